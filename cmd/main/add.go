@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/spf13/afero"
+)
+
+// runAdd creates a task in the task folder and prints the path of its file.
+func runAdd(a *app, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	do := flags.String("do", "today", "do date: YYYY-MM-DD, today, tomorrow, a weekday or +Nd/+Nw")
+	due := flags.String("due", "", "due date, in the same forms as -do")
+	high := flags.Bool("high", false, "mark the task as high priority")
+	project := flags.Bool("project", false, "mark the task as a project")
+	body := flags.String("body", "", "the text of the task, or - to read it from standard input")
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usagef("usage: cerebgo add <title> [-do date] [-due date] [-high] [-project] [-body text|-]")
+	}
+
+	task, err := newTask(a.now, strings.TrimSpace(args[0]), *do, *due, *high, *project)
+	if err != nil {
+		return err
+	}
+
+	content := *body
+	if content == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read the task text: %w", err)
+		}
+		content = string(data)
+	}
+	if content = strings.TrimRight(content, " \t\r\n"); content != "" {
+		task.Content = ptr.Some(content)
+	}
+
+	cfg, err := a.oneVault()
+	if err != nil {
+		return err
+	}
+	return withVault(cfg, a.osFs, a.wait, func(fsys afero.Fs) error {
+		path, err := tasks.AddTask(fsys, cfg, task)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, path)
+		return nil
+	})
+}
+
+// newTask returns a task created now, with its dates parsed and checked.
+func newTask(now time.Time, title, do, due string, high, project bool) (models.Task, error) {
+	created := now.Truncate(time.Second)
+	task := models.Task{
+		Title:          title,
+		Content:        ptr.None[string](),
+		IsHighPriority: high,
+		IsProject:      project,
+		DueDate:        ptr.None[string](),
+		CreatedAt:      created,
+		UpdatedAt:      created,
+	}
+
+	var err error
+	if task.DoDate, err = tasks.ParseDate(do, now); err != nil {
+		return models.Task{}, usagef("invalid -do: %v", err)
+	}
+	if !tasks.IsValidDoDate(task, now) {
+		return models.Task{}, usagef("do date %s is in the past", task.DoDate)
+	}
+
+	if due != "" {
+		dueDate, err := tasks.ParseDate(due, now)
+		if err != nil {
+			return models.Task{}, usagef("invalid -due: %v", err)
+		}
+		task.DueDate = ptr.Some(dueDate)
+		if !tasks.IsValidDueDate(task, now) {
+			return models.Task{}, usagef("due date %s is in the past", dueDate)
+		}
+		if dueDate < task.DoDate {
+			return models.Task{}, usagef("due date %s is before the do date %s", dueDate, task.DoDate)
+		}
+	}
+	return task, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/clip"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/spf13/afero"
+)
+
+// runClip converts a locally saved web page into a record in the archives directory,
+// clipped at the time of the run.
+func runClip(a *app, args []string) error {
+	flags := flag.NewFlagSet("clip", flag.ContinueOnError)
+	tags := flags.String("tags", "clip", "comma separated tags to attach to the record")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usagef("usage: cerebgo clip [-tags a,b] <file.html>")
+	}
+
+	// The saved page is usually outside the vault, so it is not read through fsys.
+	source := flags.Arg(0)
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read web page: %w", err)
+	}
+
+	fallbackTitle := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	page := clip.ParseHTML(string(data), fallbackTitle)
+	page.Title = strings.TrimSpace(page.Title)
+
+	now := a.now.Truncate(time.Second)
+	record := clip.ToRecord(page, splitTags(*tags), now)
+
+	cfg, err := a.oneVault()
+	if err != nil {
+		return err
+	}
+	return withVault(cfg, a.osFs, a.wait, func(fsys afero.Fs) error {
+		path, err := records.WriteRecordToFile(fsys, record, cfg.Paths.Archives, cfg.Archive.Collision, now)
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+
+		fmt.Fprintln(os.Stdout, path)
+		return nil
+	})
+}
+
+// splitTags splits a comma separated tag list, dropping empty entries.
+func splitTags(value string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/spf13/afero"
+)
+
+// version is the release of the binary, set with -ldflags "-X main.version=v1.2.0".
+var version = "dev"
+
+// command is a command of the command line.
+type command struct {
+	name    string
+	usage   string // the arguments, e.g. "<title>"
+	summary string
+	formats []string // output formats besides text
+	run     func(a *app, args []string) error
+}
+
+// jsonOutput is the output formats of commands that print json besides text.
+var jsonOutput = []string{"json"}
+
+// commands lists the commands in the order help shows them.
+var commands = []command{
+	{name: "process", summary: "apply the task rules to every vault (default)", formats: jsonOutput, run: runProcess},
+	{name: "plan", summary: "list the changes process would make, without making them", formats: jsonOutput, run: runPlan},
+	{name: "add", usage: "<title> [flags]", summary: "create a task and print its path, see cerebgo add -h", run: runAdd},
+	{name: "list", usage: "[view] [flags]", summary: "list the tasks of a view, see cerebgo list -h", formats: []string{"json", "markdown"}, run: runList},
+	{name: "done", usage: "<title>", summary: "complete an active task and move it to its completed folder", run: runDone},
+	{name: "config", usage: "validate | explain <file>", summary: "check the configuration, or show the settings of a file", formats: jsonOutput, run: runConfig},
+	{name: "records", usage: "dedupe [-threshold 0.8]", summary: "list near-duplicate records in the archives folder", run: readVaultCommand(runRecords)},
+	{name: "clip", usage: "[-tags a,b] <file.html>", summary: "convert a saved web page into an archive record", run: runClip},
+	{name: "migrate", usage: "[-dry-run]", summary: "rewrite old task files to the current task schema", run: runMigrate},
+	{name: "links", usage: "check", summary: "list broken and ambiguous wikilinks", run: readVaultCommand(runLinks)},
+	{name: "trash", usage: "list | restore <name>", summary: "list deleted files, or restore one", run: runTrash},
+	{name: "version", summary: "print the version of cerebgo", formats: jsonOutput, run: runVersion},
+}
+
+// findCommand returns the command with the given name.
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// vaultCommand runs a command on the only vault, or on the one chosen with -vault, while
+// holding its lock.
+func vaultCommand(fn func(cfg *config.Config, fsys afero.Fs, args []string) error) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		cfg, err := a.oneVault()
+		if err != nil {
+			return err
+		}
+		return withVault(cfg, a.osFs, a.wait, func(fsys afero.Fs) error {
+			return fn(cfg, fsys, args)
+		})
+	}
+}
+
+// readVaultCommand runs a command that only reads the vault on the only vault, or on the
+// one chosen with -vault, without taking its lock.
+func readVaultCommand(fn func(cfg *config.Config, fsys afero.Fs, args []string) error) func(a *app, args []string) error {
+	return func(a *app, args []string) error {
+		cfg, err := a.oneVault()
+		if err != nil {
+			return err
+		}
+		return readVault(cfg, a.osFs, func(fsys afero.Fs) error {
+			return fn(cfg, fsys, args)
+		})
+	}
+}
+
+// printUsage prints the commands, global flags and exit codes.
+func printUsage(global *flag.FlagSet) {
+	out := global.Output()
+	fmt.Fprintln(out, "Usage: cerebgo [flags] <command> [arguments]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-34s %s\n", strings.TrimSpace(cmd.name+" "+cmd.usage), cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	global.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Exit codes:")
+	fmt.Fprintln(out, "  0   success; with -detailed-exitcode, process and plan had nothing to do")
+	fmt.Fprintln(out, "  1   the command failed")
+	fmt.Fprintln(out, "  2   with -detailed-exitcode, process changed the vault or plan found changes")
+	fmt.Fprintln(out, "  64  the command line is invalid")
+	fmt.Fprintln(out, "  75  another run holds the vault lock")
+}
+
+// runHelp prints the usage of cerebgo, or of one command.
+func runHelp(global *flag.FlagSet, args []string) error {
+	global.SetOutput(os.Stdout)
+	switch len(args) {
+	case 0:
+		printUsage(global)
+		return nil
+	case 1:
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			return usagef("unknown command %q, see cerebgo help", args[0])
+		}
+		fmt.Fprintf(os.Stdout, "Usage: cerebgo [flags] %s\n\n%s\n", strings.TrimSpace(cmd.name+" "+cmd.usage), cmd.summary)
+		return nil
+	default:
+		return usagef("usage: cerebgo help [command]")
+	}
+}
+
+// runVersion prints the version of cerebgo and the Go release it was built with.
+func runVersion(a *app, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+
+	release := version
+	if info, ok := debug.ReadBuildInfo(); ok && release == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		release = info.Main.Version
+	}
+
+	if a.format == "json" {
+		return writeJSON(map[string]string{
+			"version":  release,
+			"go":       runtime.Version(),
+			"platform": runtime.GOOS + "/" + runtime.GOARCH,
+		})
+	}
+	fmt.Fprintf(os.Stdout, "cerebgo %s (%s %s/%s)\n", release, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
+
+// writeJSON prints a value as indented JSON.
+func writeJSON(value any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
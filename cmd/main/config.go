@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+)
+
+// runConfig handles the "config" command and its subcommands.
+func runConfig(a *app, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: cerebgo config validate | cerebgo config explain <file>")
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(a, args[1:])
+	case "explain":
+		return runConfigExplain(a, args[1:])
+	default:
+		return usagef("unknown config command %q", args[0])
+	}
+}
+
+// configProblem is a problem of the configuration, for json output.
+type configProblem struct {
+	Vault   string `json:"vault,omitempty"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// runConfigValidate lists every problem of the configuration, for each of its vaults.
+// It reports problems itself, and never changes the vault.
+func runConfigValidate(a *app, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+
+	v, err := a.config()
+	if err != nil {
+		return err
+	}
+	vaults, err := config.Vaults(v, a.osFs, a.dataPath)
+	if err != nil {
+		vaults = []config.Vault{{Err: err}}
+	}
+
+	problems := make([]configProblem, 0)
+	for _, vault := range vaults {
+		var validationErr *config.ValidationError
+		if !errors.As(vault.Err, &validationErr) {
+			if vault.Err != nil {
+				return vault.Err
+			}
+			continue
+		}
+
+		for _, fieldErr := range validationErr.Errors {
+			problems = append(problems, configProblem{Vault: vault.Name, Key: fieldErr.Key, Message: fieldErr.Message})
+		}
+	}
+
+	if a.format == "json" {
+		if err := writeJSON(problems); err != nil {
+			return err
+		}
+	} else {
+		for _, problem := range problems {
+			prefix := ""
+			if problem.Vault != "" {
+				prefix = problem.Vault + ": "
+			}
+			fmt.Fprintf(os.Stdout, "%s%s: %s\n", prefix, problem.Key, problem.Message)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems in configuration %s", len(problems), v.ConfigFileUsed())
+	}
+	if a.format == "text" && !a.quiet {
+		fmt.Fprintf(os.Stdout, "Configuration %s is valid\n", v.ConfigFileUsed())
+	}
+	return nil
+}
+
+// runConfigExplain shows the settings that apply to a file of the vault, and the
+// configuration or folder file each one comes from.
+func runConfigExplain(a *app, args []string) error {
+	if len(args) != 1 {
+		return usagef("usage: cerebgo config explain <file>")
+	}
+
+	cfg, err := a.oneVault()
+	if err != nil {
+		return err
+	}
+	settings, err := config.Explain(a.osFs, cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	for i, setting := range settings {
+		source := setting.Source
+		if rel, err := filepath.Rel(cfg.DataPath, source); err == nil && files.Within(cfg.DataPath, source) {
+			source = rel
+		}
+		if source == "" {
+			source = "default"
+		}
+		settings[i].Source = source
+	}
+
+	if a.format == "json" {
+		type explained struct {
+			Key    string `json:"key"`
+			Value  any    `json:"value"`
+			Source string `json:"source"`
+		}
+		out := make([]explained, 0, len(settings))
+		for _, setting := range settings {
+			out = append(out, explained{Key: setting.Key, Value: setting.Value, Source: setting.Source})
+		}
+		return writeJSON(out)
+	}
+
+	width := 0
+	for _, setting := range settings {
+		width = max(width, len(setting.Key))
+	}
+	for _, setting := range settings {
+		fmt.Fprintf(os.Stdout, "%-*s  %-12v  %s\n", width, setting.Key, setting.Value, setting.Source)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/spf13/afero"
+)
+
+// runDone completes an active task and moves it to the completed folder of its area.
+func runDone(a *app, args []string) error {
+	if len(args) != 1 {
+		return usagef("usage: cerebgo done <title>")
+	}
+
+	cfg, err := a.oneVault()
+	if err != nil {
+		return err
+	}
+	return withVault(cfg, a.osFs, a.wait, func(fsys afero.Fs) error {
+		path, err := tasks.CompleteTask(fsys, cfg, args[0], a.now)
+		if err != nil {
+			return err
+		}
+
+		if rel, err := filepath.Rel(cfg.DataPath, path); err == nil {
+			path = rel
+		}
+		if !a.quiet {
+			fmt.Fprintf(os.Stdout, "Completed %s\n", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/links"
+	"github.com/spf13/afero"
+)
+
+// runLinks handles the "links" command and its subcommands.
+func runLinks(cfg *config.Config, fsys afero.Fs, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: cerebgo links check")
+	}
+
+	switch args[0] {
+	case "check":
+		return runLinksCheck(cfg, fsys, args[1:])
+	default:
+		return usagef("unknown links command %q", args[0])
+	}
+}
+
+// runLinksCheck lists the broken and ambiguous wikilinks of the vault.
+func runLinksCheck(cfg *config.Config, fsys afero.Fs, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+
+	graph, err := links.BuildGraph(fsys, cfg.DataPath)
+	if err != nil {
+		return fmt.Errorf("failed to build link graph: %w", err)
+	}
+
+	problems := graph.Problems()
+	if len(problems) == 0 {
+		fmt.Fprintf(os.Stdout, "All %d links resolve\n", len(graph.Links))
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stdout, problem)
+	}
+	return fmt.Errorf("%d of %d links do not resolve", len(problems), len(graph.Links))
+}
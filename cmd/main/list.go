@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/spf13/afero"
+)
+
+// listedTask is a task printed by list with -format json, with the field names of the
+// task frontmatter.
+type listedTask struct {
+	Title          string     `json:"title"`
+	Path           string     `json:"path"`
+	DoDate         string     `json:"do_date"`
+	DueDate        string     `json:"due_date,omitempty"`
+	IsHighPriority bool       `json:"is_high_priority"`
+	IsProject      bool       `json:"is_project"`
+	Done           bool       `json:"done"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// runList prints the tasks of a view as a table, a markdown table or json.
+func runList(a *app, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	days := flags.Int("days", 7, "days ahead for upcoming, and back for completed")
+	sortKey := flags.String("sort", "", "order: priority, do_date, due_date or age (default the order of the view)")
+	high := flags.Bool("high", false, "only high priority tasks")
+	folder := flags.String("folder", "", "only the task area in this folder, relative to the task folder")
+	search := flags.String("search", "", "only tasks whose title contains this text")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cerebgo list [%s] [flags]\n", joinViews())
+		flags.PrintDefaults()
+	}
+	args, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	opts := tasks.AgendaOptions{
+		View:     tasks.ViewToday,
+		Days:     *days,
+		Sort:     tasks.SortKey(*sortKey),
+		HighOnly: *high,
+		Folder:   *folder,
+		Search:   *search,
+	}
+	switch {
+	case len(args) > 1:
+		return usagef("usage: cerebgo list [%s] [flags]", joinViews())
+	case len(args) == 1:
+		opts.View = tasks.View(args[0])
+	}
+	if !slices.Contains(tasks.Views, opts.View) {
+		return usagef("unknown view %q, use %s", opts.View, joinViews())
+	}
+	if opts.Sort != "" && !slices.Contains(tasks.SortKeys, opts.Sort) {
+		return usagef("unknown sort key %q, use priority, do_date, due_date or age", opts.Sort)
+	}
+	if opts.Days < 0 {
+		return usagef("-days must not be negative")
+	}
+
+	cfg, err := a.oneVault()
+	if err != nil {
+		return err
+	}
+	return readVault(cfg, a.osFs, func(fsys afero.Fs) error {
+		items, err := tasks.Agenda(fsys, cfg, opts, a.now)
+		if err != nil {
+			return err
+		}
+
+		listed := make([]listedTask, 0, len(items))
+		for _, item := range items {
+			listed = append(listed, toListedTask(cfg, item))
+		}
+
+		switch a.format {
+		case "json":
+			return writeJSON(listed)
+		case "markdown":
+			printMarkdownTasks(listed, opts.View)
+		default:
+			printTaskTable(listed, opts.View)
+		}
+		return nil
+	})
+}
+
+// toListedTask converts an agenda item, with its path relative to the data path.
+func toListedTask(cfg *config.Config, item tasks.AgendaItem) listedTask {
+	task := item.Task
+	listed := listedTask{
+		Title:          task.Title,
+		Path:           item.Path,
+		DoDate:         task.DoDate,
+		IsHighPriority: task.IsHighPriority,
+		IsProject:      task.IsProject,
+		Done:           task.Done,
+		CreatedAt:      task.CreatedAt,
+	}
+	if rel, err := filepath.Rel(cfg.DataPath, item.Path); err == nil {
+		listed.Path = rel
+	}
+	if task.DueDate.IsValid() {
+		listed.DueDate = task.DueDate.Value()
+	}
+	if task.CompletedAt.IsValid() {
+		completedAt := task.CompletedAt.Value()
+		listed.CompletedAt = &completedAt
+	}
+	return listed
+}
+
+// taskColumns returns the column names of a view.
+func taskColumns(view tasks.View) []string {
+	columns := []string{"Title", "Do", "Due", "Flags", "Folder"}
+	if view == tasks.ViewCompleted {
+		columns = append(columns, "Completed")
+	}
+	return columns
+}
+
+// taskCells returns the cells of a task in the columns of a view.
+func taskCells(task listedTask, view tasks.View) []string {
+	var taskFlags []string
+	if task.IsHighPriority {
+		taskFlags = append(taskFlags, "high")
+	}
+	if task.IsProject {
+		taskFlags = append(taskFlags, "project")
+	}
+
+	cells := []string{task.Title, task.DoDate, task.DueDate, strings.Join(taskFlags, ", "), filepath.Dir(task.Path)}
+	if view == tasks.ViewCompleted && task.CompletedAt != nil {
+		cells = append(cells, task.CompletedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return cells
+}
+
+// printTaskTable prints tasks as a plain table with aligned columns.
+func printTaskTable(listed []listedTask, view tasks.View) {
+	if len(listed) == 0 {
+		fmt.Fprintln(os.Stdout, "No tasks")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(taskColumns(view), "\t")))
+	for _, task := range listed {
+		fmt.Fprintln(w, strings.Join(taskCells(task, view), "\t"))
+	}
+	w.Flush()
+}
+
+// printMarkdownTasks prints tasks as a markdown table, with titles linking to the task
+// files.
+func printMarkdownTasks(listed []listedTask, view tasks.View) {
+	if len(listed) == 0 {
+		fmt.Fprintln(os.Stdout, "_No tasks_")
+		return
+	}
+
+	columns := taskColumns(view)
+	fmt.Fprintf(os.Stdout, "| %s |\n", strings.Join(columns, " | "))
+	fmt.Fprintf(os.Stdout, "|%s\n", strings.Repeat(" --- |", len(columns)))
+	for _, task := range listed {
+		cells := taskCells(task, view)
+		cells[0] = "[[" + strings.TrimSuffix(filepath.Base(task.Path), files.MarkdownExt) + "]]"
+		for i, cell := range cells {
+			cells[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		fmt.Fprintf(os.Stdout, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// joinViews lists the views for messages.
+func joinViews() string {
+	names := make([]string, 0, len(tasks.Views))
+	for _, view := range tasks.Views {
+		names = append(names, string(view))
+	}
+	return strings.Join(names, "|")
+}
@@ -1,25 +1,266 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/avivSarig/cerebgo/config"
-	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/lock"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
 )
 
 func main() {
-	// Initialize configuration
-	cfg, err := config.LoadConfig()
+	log.SetFlags(0)
+	log.SetPrefix("cerebgo: ")
+	os.Exit(run(afero.NewOsFs(), os.Args[1:]))
+}
+
+// Exit codes of the command. With -detailed-exitcode, process and plan tell a run that
+// had nothing to do from one that changed the vault, so that schedulers can act on
+// changes only.
+const (
+	exitOK      = 0  // success; with -detailed-exitcode, process and plan had nothing to do
+	exitError   = 1  // the command failed
+	exitChanged = 2  // with -detailed-exitcode, process changed the vault, or plan found changes to make
+	exitUsage   = 64 // the command line is invalid
+	exitLocked  = 75 // another run holds the vault lock; retry later
+)
+
+// exitCode returns the exit code reporting err.
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, lock.ErrLocked):
+		return exitLocked
+	default:
+		return exitError
+	}
+}
+
+// usageError is a mistake in the command line.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usagef returns a usageError, e.g. usagef("usage: cerebgo done <title>").
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// parseFlags parses the flags of a command, reporting invalid flags as a usageError.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return &usageError{msg: err.Error()}
+	}
+	return err
+}
+
+// parseInterspersed parses the flags of a command that may also follow its arguments, as
+// in cerebgo add "Buy milk" -high, and returns the arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := parseFlags(flags, args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// formats are the output formats of -format. Commands print text, and some also json or
+// markdown.
+var formats = []string{"text", "json", "markdown"}
+
+// app holds the global options of a command line, and what the command did.
+type app struct {
+	osFs       afero.Fs
+	configPath string // the configuration file or directory; empty to search for it
+	dataPath   string
+	vault      string // the vault to work on; empty for every vault
+	wait       time.Duration
+	now        time.Time
+	verbose    bool
+	quiet      bool
+	format     string // one of formats
+	detailed   bool   // exit with exitChanged when the vault changed
+	command    string // the name of the command being run
+
+	v       *viper.Viper // the loaded configuration
+	changed bool         // the command changed the vault, or found changes to make
+}
+
+// run parses the global flags and runs the command named by the first argument, or
+// process without one. It returns the exit code.
+func run(osFs afero.Fs, args []string) int {
+	a := &app{osFs: osFs}
+	global := flag.NewFlagSet("cerebgo", flag.ContinueOnError)
+	global.StringVar(&a.configPath, "config", os.Getenv("CONFIG_PATH"), "configuration file or directory")
+	global.StringVar(&a.dataPath, "data", os.Getenv("DATA_PATH"), "root folder of the vault, or of relative vault paths")
+	now := global.String("now", "", "time to run at, as YYYY-MM-DD or RFC 3339 (default the current time)")
+	global.DurationVar(&a.wait, "wait", 0, "how long to wait for another run to release the vault")
+	global.StringVar(&a.vault, "vault", "", "name of the vault to work on")
+	global.BoolVar(&a.verbose, "v", false, "list every change made")
+	global.BoolVar(&a.quiet, "q", false, "print errors only")
+	global.StringVar(&a.format, "format", "text", "output format: "+strings.Join(formats, ", "))
+	global.BoolVar(&a.detailed, "detailed-exitcode", false, "exit with 2 when process changed the vault or plan found changes, 0 when there is nothing to do")
+	global.Usage = func() { printUsage(global) }
+
+	err := parseFlags(global, args)
+	if err == nil {
+		err = a.check(*now)
+	}
+	if err == nil {
+		err = a.dispatch(global, global.Args())
+	}
+
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			log.Print(err)
+		}
+		return exitCode(err)
+	}
+	if a.changed && a.detailed {
+		return exitChanged
+	}
+	return exitOK
+}
+
+// check validates the global options, and sets the time of the run.
+func (a *app) check(now string) error {
+	if !slices.Contains(formats, a.format) {
+		return usagef("unknown format %q, use %s", a.format, strings.Join(formats, ", "))
+	}
+	if a.verbose && a.quiet {
+		return usagef("-v and -q cannot be used together")
+	}
+
+	a.now = time.Now()
+	if now == "" {
+		return nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", now, time.Local); err == nil {
+		a.now = t
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, now)
+	if err != nil {
+		return usagef("invalid -now %q, use YYYY-MM-DD or RFC 3339", now)
+	}
+	a.now = t
+	return nil
+}
+
+// dispatch runs the command named by args[0].
+func (a *app) dispatch(global *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		args = []string{"process"}
+	}
+	if args[0] == "help" {
+		return runHelp(global, args[1:])
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		return usagef("unknown command %q, see cerebgo help", args[0])
+	}
+	a.command = cmd.name
+	if a.format != "text" && !slices.Contains(cmd.formats, a.format) {
+		return usagef("%s has no %s output", cmd.name, a.format)
+	}
+	return cmd.run(a, args[1:])
+}
+
+// config returns the configuration, loading it on first use.
+func (a *app) config() (*viper.Viper, error) {
+	if a.v != nil {
+		return a.v, nil
+	}
+
+	var err error
+	if a.configPath != "" {
+		a.v, err = config.LoadConfigFrom(a.configPath)
+	} else {
+		a.v, err = config.LoadConfig()
+	}
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
+	return a.v, nil
+}
 
-	// Get current time for task processing
-	now := time.Now()
+// vaults returns the vaults of the configuration, or the one chosen with -vault.
+func (a *app) vaults() ([]config.Vault, error) {
+	v, err := a.config()
+	if err != nil {
+		return nil, err
+	}
+	vaults, err := config.Vaults(v, a.osFs, a.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	return selectVault(vaults, a.vault)
+}
 
-	// Process all tasks
-	if err := tasks.ProcessAllTasks(now, cfg); err != nil {
-		log.Fatalf("Failed to process tasks: %v", err)
+// oneVault returns the configuration of the only vault, or of the one chosen with -vault.
+func (a *app) oneVault() (*config.Config, error) {
+	vaults, err := a.vaults()
+	if err != nil {
+		return nil, err
+	}
+	if len(vaults) > 1 {
+		return nil, usagef("%s works on one vault, choose one with -vault: %s", a.command, vaultNames(vaults))
+	}
+	if vaults[0].Err != nil {
+		return nil, vaults[0].Err
 	}
+	return vaults[0].Config, nil
+}
+
+// readVault runs fn on the vault of cfg, confined to its data path, without taking the
+// vault lock. Commands that only read the vault use it, so they work while another run
+// holds the lock.
+func readVault(cfg *config.Config, osFs afero.Fs, fn func(fsys afero.Fs) error) error {
+	return fn(files.NewConfinedFs(osFs, cfg.DataPath))
+}
+
+// withVault runs fn on the vault of cfg, confined to its data path, while holding the
+// vault lock.
+func withVault(cfg *config.Config, osFs afero.Fs, wait time.Duration, fn func(fsys afero.Fs) error) (err error) {
+	fsys := files.NewConfinedFs(osFs, cfg.DataPath)
+
+	vaultLock, err := lock.Acquire(fsys, cfg.DataPath, lock.Options{
+		Wait:       wait,
+		StaleAfter: cfg.Lock.StaleAfter,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := vaultLock.Release(); releaseErr != nil && err == nil {
+			err = fmt.Errorf("failed to release vault lock: %w", releaseErr)
+		}
+	}()
+
+	return fn(fsys)
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/spf13/afero"
+)
+
+// runMigrate rewrites task files in older schema versions to the current task schema, in
+// the task folder and every task area. A dry run does not take the vault lock.
+func runMigrate(a *app, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the changes without writing files")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	run := func(cfg *config.Config, fsys afero.Fs, _ []string) error {
+		return migrateTasks(cfg, fsys, *dryRun)
+	}
+	if *dryRun {
+		return readVaultCommand(run)(a, nil)
+	}
+	return vaultCommand(run)(a, nil)
+}
+
+// migrateTasks migrates the task files of a vault and lists the files it changed.
+func migrateTasks(cfg *config.Config, fsys afero.Fs, dryRun bool) error {
+	migrated, err := tasks.MigrateAll(fsys, cfg, dryRun)
+
+	paths := make([]string, 0, len(migrated))
+	for path := range migrated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		fmt.Fprintln(os.Stdout, path)
+		for _, change := range migrated[path] {
+			fmt.Fprintf(os.Stdout, "  - %s\n", change)
+		}
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Would migrate"
+	}
+	fmt.Fprintf(os.Stdout, "%s %d task files to schema version %d\n", verb, len(paths), tasks.SchemaVersion)
+
+	return err
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/spf13/afero"
+)
+
+// runPlan lists the changes process would make to every vault, by processing the
+// vault through a filesystem that keeps every change in memory.
+func runPlan(a *app, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+	return a.eachVault(true, func(cfg *config.Config, fsys afero.Fs) ([]files.Change, error) {
+		changeFs := files.NewChangeFs(files.NewPreviewFs(fsys))
+		err := processVault(changeFs, cfg, a.now)
+		return changeFs.Changes(), err
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/trash"
+	"github.com/spf13/afero"
+)
+
+// vaultResult is what process or plan did to one vault.
+type vaultResult struct {
+	Vault   string         `json:"vault,omitempty"`
+	Changes []files.Change `json:"changes"` // paths relative to the data path
+	Error   string         `json:"error,omitempty"`
+}
+
+// runProcess runs the enabled subsystems of every vault.
+func runProcess(a *app, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+	return a.eachVault(false, func(cfg *config.Config, fsys afero.Fs) ([]files.Change, error) {
+		changeFs := files.NewChangeFs(fsys)
+		err := processVault(changeFs, cfg, a.now)
+		return changeFs.Changes(), err
+	})
+}
+
+// eachVault runs fn on every vault and reports the changes fn returns for each. A vault
+// that fails does not stop the others. plan selects how the changes are reported: as
+// made by process, or as would be made by plan. Only process holds the vault lock, as
+// plan never writes to the vault.
+func (a *app) eachVault(plan bool, fn func(cfg *config.Config, fsys afero.Fs) ([]files.Change, error)) error {
+	vaults, err := a.vaults()
+	if err != nil {
+		return err
+	}
+
+	results := make([]vaultResult, 0, len(vaults))
+	var errs []error
+	for _, vault := range vaults {
+		result := vaultResult{Vault: vault.Name, Changes: []files.Change{}}
+		err := vault.Err
+		if err == nil {
+			run := func(fsys afero.Fs) error {
+				changes, err := fn(vault.Config, fsys)
+				result.Changes = relativeChanges(vault.Config.DataPath, changes)
+				return err
+			}
+			if plan {
+				err = readVault(vault.Config, a.osFs, run)
+			} else {
+				err = withVault(vault.Config, a.osFs, a.wait, run)
+			}
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			if vault.Name != "" {
+				err = fmt.Errorf("vault %s: %w", vault.Name, err)
+			}
+			errs = append(errs, err)
+		}
+		a.changed = a.changed || len(result.Changes) > 0
+		results = append(results, result)
+	}
+
+	if err := a.printResults(vaults, results, plan); err != nil {
+		return err
+	}
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(vaults) == 1:
+		return errs[0]
+	default:
+		return fmt.Errorf("%d of %d vaults failed: %w", len(errs), len(vaults), errors.Join(errs...))
+	}
+}
+
+// printResults reports the outcome of process or plan. process lists its changes with
+// -v, and prints a line per vault when there are several; plan always lists its changes.
+func (a *app) printResults(vaults []config.Vault, results []vaultResult, plan bool) error {
+	if a.format == "json" {
+		return writeJSON(results)
+	}
+	if a.quiet {
+		return nil
+	}
+
+	single := len(vaults) == 1 && vaults[0].Name == ""
+	list := plan || a.verbose
+	failed := 0
+	for i, result := range results {
+		indent := "  "
+		switch {
+		case single:
+			indent = ""
+		case result.Error != "":
+			fmt.Fprintf(os.Stdout, "%s: failed\n", result.Vault)
+		case plan:
+			fmt.Fprintf(os.Stdout, "%s:\n", result.Vault)
+		default:
+			fmt.Fprintf(os.Stdout, "%s: processed %s, %s\n", result.Vault, subsystemNames(vaults[i].Config), changeCount(result.Changes))
+		}
+		if result.Error != "" {
+			failed++
+		}
+
+		if !list {
+			continue
+		}
+		for _, change := range result.Changes {
+			fmt.Fprintf(os.Stdout, "%s%s\n", indent, change)
+		}
+		if plan && len(result.Changes) == 0 && result.Error == "" {
+			fmt.Fprintf(os.Stdout, "%sNo changes\n", indent)
+		}
+	}
+
+	if !single && !plan {
+		fmt.Fprintf(os.Stdout, "%d of %d vaults processed\n", len(vaults)-failed, len(vaults))
+	}
+	return nil
+}
+
+// relativeChanges returns the changes with their paths relative to the data path.
+func relativeChanges(dataPath string, changes []files.Change) []files.Change {
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(dataPath, path); err == nil && files.Within(dataPath, path) {
+			return rel
+		}
+		return path
+	}
+
+	out := make([]files.Change, 0, len(changes))
+	for _, change := range changes {
+		change.Path = relative(change.Path)
+		if change.To != "" {
+			change.To = relative(change.To)
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+// changeCount describes the number of changes, e.g. "3 changes".
+func changeCount(changes []files.Change) string {
+	switch len(changes) {
+	case 0:
+		return "no changes"
+	case 1:
+		return "1 change"
+	default:
+		return fmt.Sprintf("%d changes", len(changes))
+	}
+}
+
+// processVault runs the enabled subsystems of one vault.
+func processVault(fsys afero.Fs, cfg *config.Config, now time.Time) error {
+	if cfg.Enabled(config.SubsystemTasks) {
+		if err := tasks.ProcessAllTasks(fsys, now, cfg); err != nil {
+			return fmt.Errorf("failed to process tasks: %w", err)
+		}
+	}
+	if cfg.Enabled(config.SubsystemTrash) {
+		if _, err := trash.ForVault(fsys, cfg).Purge(now, cfg.Trash.Retention); err != nil {
+			return fmt.Errorf("failed to purge trash: %w", err)
+		}
+	}
+	return nil
+}
+
+// selectVault keeps the vault with the given name, or every vault if name is empty.
+func selectVault(vaults []config.Vault, name string) ([]config.Vault, error) {
+	if name == "" {
+		return vaults, nil
+	}
+	if len(vaults) == 1 && vaults[0].Name == "" {
+		return nil, usagef("unknown vault %q, the configuration lists no vaults", name)
+	}
+	for _, vault := range vaults {
+		if strings.EqualFold(vault.Name, name) {
+			return []config.Vault{vault}, nil
+		}
+	}
+	return nil, usagef("unknown vault %q, use one of: %s", name, vaultNames(vaults))
+}
+
+// vaultNames lists the names of the vaults for messages.
+func vaultNames(vaults []config.Vault) string {
+	names := make([]string, 0, len(vaults))
+	for _, vault := range vaults {
+		names = append(names, vault.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// subsystemNames describes the enabled subsystems of a vault.
+func subsystemNames(cfg *config.Config) string {
+	if len(cfg.Subsystems) == 0 {
+		return "nothing, no subsystem enabled"
+	}
+	names := make([]string, 0, len(cfg.Subsystems))
+	for _, s := range cfg.Subsystems {
+		names = append(names, string(s))
+	}
+	return strings.Join(names, ", ")
+}
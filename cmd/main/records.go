@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/spf13/afero"
+)
+
+// runRecords handles the "records" command and its subcommands.
+func runRecords(cfg *config.Config, fsys afero.Fs, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: cerebgo records dedupe [-threshold 0.8]")
+	}
+
+	switch args[0] {
+	case "dedupe":
+		return runRecordsDedupe(cfg, fsys, args[1:])
+	default:
+		return usagef("unknown records command %q", args[0])
+	}
+}
+
+// runRecordsDedupe lists near-duplicate records in the archives directory.
+func runRecordsDedupe(cfg *config.Config, fsys afero.Fs, args []string) error {
+	flags := flag.NewFlagSet("records dedupe", flag.ContinueOnError)
+	threshold := flags.Float64("threshold", 0.8, "minimal content similarity (0..1) to report")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	dir := cfg.Paths.Archives
+	pairs, err := records.FindDuplicates(fsys, dir, *threshold)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate records: %w", err)
+	}
+
+	if len(pairs) == 0 {
+		fmt.Fprintln(os.Stdout, "No duplicate records found")
+		return nil
+	}
+
+	for _, pair := range pairs {
+		fmt.Fprintf(os.Stdout, "%3.0f%% (%s)\n  %s\n  %s\n", pair.Similarity*100, pair.Reason, pair.First, pair.Second)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/trash"
+	"github.com/spf13/afero"
+)
+
+// runTrash handles the "trash" command and its subcommands. Only restore takes the vault
+// lock.
+func runTrash(a *app, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: cerebgo trash list | cerebgo trash restore <name>")
+	}
+
+	switch args[0] {
+	case "list":
+		return readVaultCommand(runTrashList)(a, args[1:])
+	case "restore":
+		return vaultCommand(runTrashRestore)(a, args[1:])
+	default:
+		return usagef("unknown trash command %q", args[0])
+	}
+}
+
+// runTrashList lists the deleted files kept in the trash.
+func runTrashList(cfg *config.Config, fsys afero.Fs, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %q", args)
+	}
+
+	entries, err := trash.ForVault(fsys, cfg).List()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stdout, "The trash is empty")
+		return nil
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintf(os.Stdout, "%s  %s\n", entry.DeletedAt.Local().Format("2006-01-02 15:04"), entry.Name)
+	}
+	return nil
+}
+
+// runTrashRestore moves a deleted file back to its original path.
+func runTrashRestore(cfg *config.Config, fsys afero.Fs, args []string) error {
+	if len(args) != 1 {
+		return usagef("usage: cerebgo trash restore <name>")
+	}
+
+	entry, err := trash.ForVault(fsys, cfg).Restore(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Restored %s\n", entry.OriginalPath)
+	return nil
+}
@@ -4,19 +4,197 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
 
+// Config is the validated configuration of one vault. Every path is absolute, resolved
+// against DataPath; optional paths that are not configured are empty.
+type Config struct {
+	File       string      // the configuration file it was read from, if any
+	DataPath   string      // the vault root
+	Subsystems []Subsystem // the subsystems a run uses, see Enabled
+
+	Paths     Paths
+	Journals  []Journal
+	Retention Retention
+	Archive   Archive
+	Tasks     Tasks
+	Trash     Trash
+	Lock      Lock
+	Patterns  Patterns
+}
+
+// Paths holds the vault folders and files.
+type Paths struct {
+	Inbox            string
+	Tasks            string
+	CompletedTasks   string
+	Journal          string
+	CompletedJournal string
+	Lists            string
+	People           string
+	Archives         string
+}
+
+// Journal describes one journal of the vault.
+type Journal struct {
+	Name string
+}
+
+// Retention holds how long completed tasks are kept.
+type Retention struct {
+	EmptyTask            time.Duration
+	ProjectBeforeArchive time.Duration
+}
+
+// Archive holds the archive settings.
+type Archive struct {
+	Collision records.CollisionPolicy
+}
+
+// Tasks holds the task file settings.
+type Tasks struct {
+	Collision files.MovePolicy
+	Rollover  bool // move the past do dates of active tasks to the day of the run
+}
+
+// Trash holds where deleted files go and for how long.
+type Trash struct {
+	Path            string
+	Retention       time.Duration // 0 keeps deleted files forever
+	PermanentDelete bool
+}
+
+// Lock holds the vault lock settings.
+type Lock struct {
+	StaleAfter time.Duration // 0 never takes over a lock by age
+}
+
+// Patterns holds the date patterns of file names.
+type Patterns struct {
+	DateFormat string
+	FileFormat string
+}
+
+// day is the unit of retention settings.
+const day = 24 * time.Hour
+
+// Load reads, validates and resolves the configuration of the vault in dataPath.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - dataPath: the vault root, from DATA_PATH
+//
+// Returns:
+//   - *Config: the resolved configuration
+//   - error: loading errors, or a *ValidationError listing every problem
+func Load(fsys afero.Fs, dataPath string) (*Config, error) {
+	v, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return New(v, fsys, dataPath)
+}
+
+// New validates a loaded configuration, see Validate, and resolves it into a Config.
+// The folder files of the vault are checked as well, see ValidateFolders.
+//
+// Parameters:
+//   - v: the loaded configuration
+//   - fsys: filesystem holding the vault
+//   - dataPath: the vault root, from DATA_PATH
+//
+// Returns:
+//   - *Config: the resolved configuration
+//   - error: a *ValidationError listing every problem, or a folder file read error
+func New(v *viper.Viper, fsys afero.Fs, dataPath string) (*Config, error) {
+	if err := Validate(v, fsys, dataPath); err != nil {
+		return nil, err
+	}
+
+	// Validate has checked the policies already.
+	archiveCollision, _ := records.ParseCollisionPolicy(v.GetString("settings.archive.collision"))
+	taskCollision, _ := files.ParseMovePolicy(v.GetString("settings.tasks.collision"))
+
+	dataPath = filepath.Clean(dataPath)
+	path := func(key string) string {
+		if value := v.GetString(key); value != "" {
+			return filepath.Join(dataPath, value)
+		}
+		return ""
+	}
+
+	trashPath := v.GetString("settings.trash.path")
+	if trashPath == "" {
+		trashPath = ".trash"
+	}
+
+	items, _ := v.Get("journals").([]any)
+	journals := make([]Journal, 0, len(items))
+	for _, item := range items {
+		entry, _ := item.(map[string]any)
+		name, _ := entry["name"].(string)
+		journals = append(journals, Journal{Name: name})
+	}
+
+	cfg := &Config{
+		File:       v.ConfigFileUsed(),
+		DataPath:   dataPath,
+		Subsystems: Subsystems,
+		Paths: Paths{
+			Inbox:            path("paths.base.inbox"),
+			Tasks:            path("paths.base.tasks"),
+			CompletedTasks:   path("paths.subdirs.tasks.completed"),
+			Journal:          path("paths.base.journal"),
+			CompletedJournal: path("paths.subdirs.journal.completed"),
+			Lists:            path("paths.base.lists"),
+			People:           path("paths.base.people"),
+			Archives:         path("paths.base.archives"),
+		},
+		Journals: journals,
+		Retention: Retention{
+			EmptyTask:            time.Duration(v.GetInt("settings.retention.empty_task")) * day,
+			ProjectBeforeArchive: time.Duration(v.GetInt("settings.retention.project_before_archive")) * day,
+		},
+		Archive: Archive{Collision: archiveCollision},
+		Tasks:   Tasks{Collision: taskCollision, Rollover: rollover(v)},
+		Trash: Trash{
+			Path:            filepath.Join(dataPath, trashPath),
+			Retention:       time.Duration(v.GetInt("settings.trash.retention")) * day,
+			PermanentDelete: v.GetBool("settings.trash.permanent_delete"),
+		},
+		Lock: Lock{StaleAfter: time.Duration(v.GetInt("settings.lock.stale_after")) * time.Minute},
+		Patterns: Patterns{
+			DateFormat: v.GetString("settings.patterns.date_format"),
+			FileFormat: v.GetString("settings.patterns.file_format"),
+		},
+	}
+
+	if err := ValidateFolders(fsys, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
 // LoadConfig initializes and loads the application configuration using Viper.
 // It searches for a "config.yaml" file in the following locations (in order):
 //  1. Path specified by the CONFIG_PATH environment variable.
 //  2. A "config" directory near the executable's location.
 //  3. A "config" directory one level above the executable's location.
 //
+// References such as "${paths.base.tasks}" and "${env:HOME}" in values are expanded,
+// see Interpolate. A configuration with a vaults list is expanded for each vault by
+// Vaults instead, once the vault's overrides are merged in.
+//
 // Returns:
 //   - *viper.Viper: Configured Viper instance on success.
-//   - error: If the configuration file is missing or invalid.
+//   - error: If the configuration file is missing or invalid, or a reference cannot be
+//     resolved.
 func LoadConfig() (*viper.Viper, error) {
 	v := viper.New()
 
@@ -34,11 +212,50 @@ func LoadConfig() (*viper.Viper, error) {
 	v.AddConfigPath(filepath.Join(exeDir, "..", "config"))
 
 	v.SetConfigName("config")
+	return readConfig(v)
+}
+
+// LoadConfigFrom loads the application configuration from a given file, or from the
+// "config.yaml" file of a given directory, like LoadConfig does for CONFIG_PATH.
+//
+// Parameters:
+//   - path: the configuration file or its directory
+//
+// Returns:
+//   - *viper.Viper: Configured Viper instance on success.
+//   - error: If the configuration file is missing or invalid, or a reference cannot be
+//     resolved.
+func LoadConfigFrom(path string) (*viper.Viper, error) {
+	v := viper.New()
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		v.AddConfigPath(path)
+		v.SetConfigName("config")
+	} else {
+		v.SetConfigFile(path)
+	}
+	return readConfig(v)
+}
+
+// readConfig reads the YAML configuration v points to and expands its references,
+// unless it has a vaults list.
+func readConfig(v *viper.Viper) (*viper.Viper, error) {
 	v.SetConfigType("yaml")
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
+	if v.IsSet(vaultsKey) {
+		return v, nil
+	}
+
+	if err := Interpolate(v); err != nil {
+		return nil, fmt.Errorf("failed to interpolate config: %w", err)
+	}
 
 	return v, nil
 }
+
+// rollover returns the settings.tasks.rollover setting, which is on unless set to false.
+func rollover(v *viper.Viper) bool {
+	return !v.IsSet("settings.tasks.rollover") || v.GetBool("settings.tasks.rollover")
+}
@@ -1,12 +1,20 @@
 package config_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/records"
 	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
 )
 
 // Test Configuration constants.
@@ -96,3 +104,156 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+// TestLoadConfigFrom verifies that a configuration is loaded from a given file or
+// directory.
+func TestLoadConfigFrom(t *testing.T) {
+	dir := testutil.SetupConfigDir(t, validConfig)
+	invalidDir := testutil.SetupConfigDir(t, invalidConfig)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "directory", path: dir},
+		{name: "file", path: filepath.Join(dir, "config.yaml")},
+		{name: "missing file", path: filepath.Join(dir, "other.yaml"), wantErr: true},
+		{name: "invalid YAML", path: invalidDir, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.LoadConfigFrom(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfigFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				testutil.ValidateConfig(t, got)
+			}
+		})
+	}
+}
+
+// TestLoad verifies that the configuration is loaded, validated and resolved against the
+// data path in one step.
+func TestLoad(t *testing.T) {
+	dataPath := testutil.CreateTestDirectory(t)
+	for _, dir := range []string{"Tasks/Completed", "Journals", "Archive"} {
+		if err := os.MkdirAll(filepath.Join(dataPath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := testutil.CreateTestFile(t, dataPath, "Notes.md", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		yaml           string // empty for a missing configuration file
+		wantValidation bool
+		wantErr        bool
+	}{
+		{
+			name: "valid configuration",
+			yaml: schemaConfig,
+		},
+		{
+			name:    "missing configuration file",
+			wantErr: true,
+		},
+		{
+			name:    "invalid YAML",
+			yaml:    invalidConfig,
+			wantErr: true,
+		},
+		{
+			name:           "misspelled retention key",
+			yaml:           strings.Replace(schemaConfig, "empty_task:", "empty_tasks:", 1),
+			wantValidation: true,
+			wantErr:        true,
+		},
+		{
+			name:           "missing required keys",
+			yaml:           "foo: bar",
+			wantValidation: true,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if tt.yaml != "" {
+				dir = testutil.SetupConfigDir(t, tt.yaml)
+			}
+			testutil.SetConfigPath(t, dir)
+
+			cfg, err := config.Load(afero.NewOsFs(), dataPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *config.ValidationError
+			if errors.As(err, &validationErr) != tt.wantValidation {
+				t.Errorf("Load() error = %v, want a *ValidationError: %v", err, tt.wantValidation)
+			}
+			if err == nil && cfg.Paths.Tasks != filepath.Join(dataPath, "Tasks") {
+				t.Errorf("Paths.Tasks = %q, want it under %q", cfg.Paths.Tasks, dataPath)
+			}
+		})
+	}
+}
+
+// TestNew verifies that paths are resolved against the data path and settings are
+// converted to their types.
+func TestNew(t *testing.T) {
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Notes.md":              "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(schemaConfig)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := config.New(v, fsys, "/vault/")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	want := &config.Config{
+		DataPath:   "/vault",
+		Subsystems: config.Subsystems,
+		Paths: config.Paths{
+			Inbox:          "/vault/Notes.md",
+			Tasks:          "/vault/Tasks",
+			CompletedTasks: "/vault/Tasks/Completed",
+			Journal:        "/vault/Journals",
+			Archives:       "/vault/Archive",
+		},
+		Journals: []config.Journal{{Name: "Belle"}, {Name: "Pure"}},
+		Retention: config.Retention{
+			EmptyTask:            30 * 24 * time.Hour,
+			ProjectBeforeArchive: 7 * 24 * time.Hour,
+		},
+		Archive: config.Archive{Collision: records.CollisionSuffix},
+		Tasks:   config.Tasks{Collision: files.MoveKeepNewer, Rollover: true},
+		Trash: config.Trash{
+			Path:      "/vault/.trash",
+			Retention: 30 * 24 * time.Hour,
+		},
+		Lock:     config.Lock{StaleAfter: time.Hour},
+		Patterns: config.Patterns{DateFormat: "YYYY-MM-DD", FileFormat: "*-YYYY-MM-DD"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := config.New(v, fsys, ""); err == nil {
+		t.Error("New() without a data path succeeded, want an error")
+	}
+}
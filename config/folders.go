@@ -0,0 +1,302 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// FolderFile is the name of the files that override settings for a folder of the task
+// folder, and for everything below it.
+const FolderFile = ".cerebgo.yaml"
+
+// folderSetting is a setting that folder files may override.
+type folderSetting struct {
+	field
+	get func(c *Config) any
+	set func(c *Config, v *viper.Viper)
+}
+
+// folderSettings lists the settings that folder files may override.
+var folderSettings = []folderSetting{
+	{
+		field: field{key: "settings.retention.empty_task", kind: kindInt, check: nonNegative},
+		get:   func(c *Config) any { return int(c.Retention.EmptyTask / day) },
+		set: func(c *Config, v *viper.Viper) {
+			c.Retention.EmptyTask = time.Duration(v.GetInt("settings.retention.empty_task")) * day
+		},
+	},
+	{
+		field: field{key: "settings.retention.project_before_archive", kind: kindInt, check: nonNegative},
+		get:   func(c *Config) any { return int(c.Retention.ProjectBeforeArchive / day) },
+		set: func(c *Config, v *viper.Viper) {
+			c.Retention.ProjectBeforeArchive = time.Duration(v.GetInt("settings.retention.project_before_archive")) * day
+		},
+	},
+	{
+		field: field{key: "settings.archive.collision", check: collisionPolicy},
+		get:   func(c *Config) any { return string(c.Archive.Collision) },
+		set: func(c *Config, v *viper.Viper) {
+			c.Archive.Collision, _ = records.ParseCollisionPolicy(v.GetString("settings.archive.collision"))
+		},
+	},
+	{
+		field: field{key: "settings.tasks.collision", check: movePolicy},
+		get:   func(c *Config) any { return string(c.Tasks.Collision) },
+		set: func(c *Config, v *viper.Viper) {
+			c.Tasks.Collision, _ = files.ParseMovePolicy(v.GetString("settings.tasks.collision"))
+		},
+	},
+	{
+		field: field{key: "settings.tasks.rollover", kind: kindBool},
+		get:   func(c *Config) any { return c.Tasks.Rollover },
+		set: func(c *Config, v *viper.Viper) {
+			c.Tasks.Rollover = rollover(v)
+		},
+	},
+}
+
+// Setting is the value of a setting for a path, and the file it comes from.
+type Setting struct {
+	Key    string
+	Value  any
+	Source string // the configuration file, or the folder file overriding it
+}
+
+// ForPath returns the configuration that applies to a file or folder of the vault: cfg
+// with the settings of every folder file from the task folder down to the path.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - path: a file or folder, absolute or relative to the vault root
+//
+// Returns:
+//   - *Config: the configuration for path, a copy of cfg if no folder file applies
+//   - error: if a folder file cannot be read or is invalid
+func ForPath(fsys afero.Fs, cfg *Config, path string) (*Config, error) {
+	resolved, _, err := resolvePath(fsys, cfg, path)
+	return resolved, err
+}
+
+// Explain lists the settings that folder files may override, with their value for a
+// file or folder of the vault and the file each value comes from.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - path: a file or folder, absolute or relative to the vault root
+//
+// Returns:
+//   - []Setting: the settings in a fixed order
+//   - error: if a folder file cannot be read or is invalid
+func Explain(fsys afero.Fs, cfg *Config, path string) ([]Setting, error) {
+	_, settings, err := resolvePath(fsys, cfg, path)
+	return settings, err
+}
+
+// resolvePath applies the folder files that cover path to a copy of cfg.
+func resolvePath(fsys afero.Fs, cfg *Config, path string) (*Config, []Setting, error) {
+	resolved := *cfg
+	settings := make([]Setting, len(folderSettings))
+	for i, s := range folderSettings {
+		settings[i] = Setting{Key: s.key, Value: s.get(cfg), Source: cfg.File}
+	}
+
+	dirs, err := folderChain(fsys, cfg, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, dir := range dirs {
+		file := filepath.Join(dir, FolderFile)
+		v, err := readFolderFile(fsys, file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, s := range folderSettings {
+			if v.IsSet(s.key) {
+				s.set(&resolved, v)
+				settings[i] = Setting{Key: s.key, Value: s.get(&resolved), Source: file}
+			}
+		}
+	}
+	return &resolved, settings, nil
+}
+
+// folderChain returns the folders whose folder files apply to path, from the task folder
+// down to path. Paths outside the task folder get none.
+func folderChain(fsys afero.Fs, cfg *Config, path string) ([]string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataPath, path)
+	}
+	path = filepath.Clean(path)
+
+	dir := path
+	if info, err := fsys.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	root := cfg.Paths.Tasks
+	if root == "" || !files.Within(root, dir) {
+		return nil, nil
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	chain := []string{root}
+	if rel != "." {
+		current := root
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+			chain = append(chain, current)
+		}
+	}
+	return chain, nil
+}
+
+// validatedRoots returns the folders searched for folder files: the task folder, where
+// they apply, and the journal and archive folders, where they are reported as unused.
+func validatedRoots(cfg *Config) []string {
+	roots := make([]string, 0, 3)
+	for _, root := range []string{cfg.Paths.Tasks, cfg.Paths.Journal, cfg.Paths.Archives} {
+		if root != "" {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// readFolderFile reads and checks a folder file.
+func readFolderFile(fsys afero.Fs, file string) (*viper.Viper, error) {
+	data, err := afero.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read folder configuration %s: %w", file, err)
+	}
+	if errs := checkFolderSettings(v, file); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	return v, nil
+}
+
+// checkFolderSettings returns the problems of a folder file, with keys prefixed by the file.
+func checkFolderSettings(v *viper.Viper, file string) []*FieldError {
+	var errs []*FieldError
+	add := func(key, format string, args ...any) {
+		errs = append(errs, &FieldError{Key: file + ": " + key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, ok := findFolderSetting(key)
+		if !ok {
+			add(key, "cannot be set per folder, use %s", folderKeys())
+			continue
+		}
+
+		value := v.Get(key)
+		if !hasKind(value, s.kind) {
+			add(key, "must be %s, got %v", s.kind, value)
+			continue
+		}
+		if s.check == nil {
+			continue
+		}
+		if problem := s.check(&validator{}, value); problem != "" {
+			add(key, "%s", problem)
+		}
+	}
+	return errs
+}
+
+// findFolderSetting returns the folder setting of a key.
+func findFolderSetting(key string) (folderSetting, bool) {
+	for _, s := range folderSettings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return folderSetting{}, false
+}
+
+// folderKeys lists the settings folder files may override, for messages.
+func folderKeys() string {
+	keys := make([]string, 0, len(folderSettings))
+	for _, s := range folderSettings {
+		keys = append(keys, s.key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// ValidateFolders checks every folder file in the task folder, and reports folder files in
+// the journal and archive folders, which are never read.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//
+// Returns:
+//   - error: a *ValidationError listing every problem, or nil
+func ValidateFolders(fsys afero.Fs, cfg *Config) error {
+	var errs []*FieldError
+	seen := make(map[string]bool)
+	for _, root := range validatedRoots(cfg) {
+		err := afero.Walk(fsys, root, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				if path == root && errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if info.IsDir() || info.Name() != FolderFile || seen[path] {
+				return nil
+			}
+			seen[path] = true
+
+			if cfg.Paths.Tasks == "" || !files.Within(cfg.Paths.Tasks, path) {
+				errs = append(errs, &FieldError{Key: path, Message: "folder files are only read in the task folder"})
+				return nil
+			}
+			_, err = readFolderFile(fsys, path)
+			var validationErr *ValidationError
+			switch {
+			case errors.As(err, &validationErr):
+				errs = append(errs, validationErr.Errors...)
+			case err != nil:
+				errs = append(errs, &FieldError{Key: path, Message: err.Error()})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read folder configurations in %s: %w", root, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/viper"
+)
+
+// TestExplain verifies that folder files apply to their folder and everything below it,
+// the deepest one winning.
+func TestExplain(t *testing.T) {
+	vaultFiles := map[string]string{
+		"/vault/Notes.md":                           "",
+		"/vault/Tasks/Completed/.keep":              "",
+		"/vault/Tasks/.cerebgo.yaml":                "settings:\n  tasks:\n    collision: suffix\n",
+		"/vault/Tasks/Work/.cerebgo.yaml":           "settings:\n  retention:\n    empty_task: 90\n",
+		"/vault/Tasks/Work/Clients/.cerebgo.yaml":   "settings:\n  retention:\n    empty_task: 120\n    project_before_archive: 14\n",
+		"/vault/Tasks/Work/Clients/Acme/Invoice.md": "",
+		"/vault/Tasks/Archived/.cerebgo.yaml":       "settings:\n  archive:\n    collision: merge\n  tasks:\n    rollover: false\n",
+		"/vault/Journals/.keep":                     "",
+		"/vault/Archive/.keep":                      "",
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []config.Setting
+	}{
+		{
+			name: "nested folder files",
+			path: "Tasks/Work/Clients/Acme/Invoice.md",
+			want: []config.Setting{
+				{Key: "settings.retention.empty_task", Value: 120, Source: "/vault/Tasks/Work/Clients/.cerebgo.yaml"},
+				{Key: "settings.retention.project_before_archive", Value: 14, Source: "/vault/Tasks/Work/Clients/.cerebgo.yaml"},
+				{Key: "settings.archive.collision", Value: "suffix", Source: ""},
+				{Key: "settings.tasks.collision", Value: "suffix", Source: "/vault/Tasks/.cerebgo.yaml"},
+				{Key: "settings.tasks.rollover", Value: true, Source: ""},
+			},
+		},
+		{
+			name: "folder itself",
+			path: "/vault/Tasks/Work",
+			want: []config.Setting{
+				{Key: "settings.retention.empty_task", Value: 90, Source: "/vault/Tasks/Work/.cerebgo.yaml"},
+				{Key: "settings.retention.project_before_archive", Value: 7, Source: ""},
+				{Key: "settings.archive.collision", Value: "suffix", Source: ""},
+				{Key: "settings.tasks.collision", Value: "suffix", Source: "/vault/Tasks/.cerebgo.yaml"},
+				{Key: "settings.tasks.rollover", Value: true, Source: ""},
+			},
+		},
+		{
+			name: "outside the task folder",
+			path: "Notes.md",
+			want: []config.Setting{
+				{Key: "settings.retention.empty_task", Value: 30, Source: ""},
+				{Key: "settings.retention.project_before_archive", Value: 7, Source: ""},
+				{Key: "settings.archive.collision", Value: "suffix", Source: ""},
+				{Key: "settings.tasks.collision", Value: "keep_newer", Source: ""},
+				{Key: "settings.tasks.rollover", Value: true, Source: ""},
+			},
+		},
+	}
+
+	fsys := testutil.CreateMemFs(t, vaultFiles)
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(schemaConfig)); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.Explain(fsys, cfg, tt.path)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Explain() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	resolved, err := config.ForPath(fsys, cfg, "Tasks/Archived/Some Task.md")
+	if err != nil || resolved.Archive.Collision != "merge" || cfg.Archive.Collision != "suffix" {
+		t.Errorf("ForPath() archive collision = %v, %v, want merge without changing the vault configuration", resolved.Archive.Collision, err)
+	}
+	if resolved.Tasks.Rollover || !cfg.Tasks.Rollover {
+		t.Errorf("ForPath() rollover = %v, want false without changing the vault configuration", resolved.Tasks.Rollover)
+	}
+}
+
+// TestValidateFolders verifies that invalid folder files are reported when the
+// configuration is loaded.
+func TestValidateFolders(t *testing.T) {
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Notes.md":                 "",
+		"/vault/Tasks/Completed/.keep":    "",
+		"/vault/Tasks/Work/.cerebgo.yaml": "settings:\n  retention:\n    empty_task: -1\n  lock:\n    stale_after: 5\n",
+		"/vault/Journals/.cerebgo.yaml":   "settings:\n  tasks:\n    rollover: false\n",
+		"/vault/Archive/.keep":            "",
+	})
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(schemaConfig)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := config.New(v, fsys, "/vault")
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("New() error = %v, want a *ValidationError", err)
+	}
+	want := []string{
+		"/vault/Tasks/Work/.cerebgo.yaml: settings.lock.stale_after: cannot be set per folder",
+		"/vault/Tasks/Work/.cerebgo.yaml: settings.retention.empty_task: must not be negative",
+		"/vault/Journals/.cerebgo.yaml: folder files are only read in the task folder",
+	}
+	if len(validationErr.Errors) != len(want) {
+		t.Fatalf("New() found %d problems, want %d:\n%v", len(validationErr.Errors), len(want), err)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(validationErr.Errors[i].Error(), prefix) {
+			t.Errorf("problem %d = %q, want prefix %q", i, validationErr.Errors[i], prefix)
+		}
+	}
+}
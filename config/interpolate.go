@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// referencePattern matches "${...}" references and the "$${" escape.
+var referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// envPrefix starts references to environment variables, as in "${env:HOME}".
+const envPrefix = "env:"
+
+// cycleError is a chain of keys that refer back to the first one.
+type cycleError []string
+
+func (e cycleError) Error() string {
+	return "reference cycle " + strings.Join(e, " -> ")
+}
+
+// interpolator expands the references of one configuration.
+type interpolator struct {
+	v        *viper.Viper
+	resolved map[string]any
+	visiting []string
+}
+
+// Interpolate expands "${...}" references in every configuration value.
+//
+// A reference names another key, either in full ("${paths.base.tasks}") or relative to
+// the enclosing sections ("${date_format}" next to settings.patterns.date_format); the
+// nearest enclosing match wins. "${env:NAME}" is replaced by an environment variable,
+// and "$${" writes a literal "${". A value made of a single reference keeps the type of
+// the referenced value, so numbers and booleans can be shared.
+//
+// Parameters:
+//   - v: the configuration to expand in place
+//
+// Returns:
+//   - error: a *ValidationError listing every unresolved reference and reference cycle
+func Interpolate(v *viper.Viper) error {
+	in := &interpolator{v: v, resolved: make(map[string]any)}
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+
+	var errs []*FieldError
+	for _, key := range keys {
+		value, err := in.value(key)
+		if err != nil {
+			errs = append(errs, &FieldError{Key: key, Message: err.Error()})
+			continue
+		}
+		v.Set(key, value)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// expandValue expands the references in a value that is not part of v, resolving
+// them against the top-level keys of v.
+func expandValue(v *viper.Viper, value string) (any, error) {
+	in := &interpolator{v: v, resolved: make(map[string]any)}
+	return in.expandString("", value)
+}
+
+// value returns the expanded value of a key.
+func (in *interpolator) value(key string) (any, error) {
+	if value, ok := in.resolved[key]; ok {
+		return value, nil
+	}
+	if i := slices.Index(in.visiting, key); i >= 0 {
+		return nil, cycleError(append(slices.Clone(in.visiting[i:]), key))
+	}
+
+	in.visiting = append(in.visiting, key)
+	defer func() { in.visiting = in.visiting[:len(in.visiting)-1] }()
+
+	value, err := in.expand(key, in.v.Get(key))
+	if err != nil {
+		return nil, err
+	}
+	in.resolved[key] = value
+	return value, nil
+}
+
+// expand expands the references in a value, including the items of lists.
+func (in *interpolator) expand(key string, value any) (any, error) {
+	switch val := value.(type) {
+	case string:
+		return in.expandString(key, val)
+	case []any:
+		items := make([]any, len(val))
+		for i, item := range val {
+			expanded, err := in.expand(key, item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = expanded
+		}
+		return items, nil
+	case map[string]any:
+		fields := make(map[string]any, len(val))
+		for name, item := range val {
+			expanded, err := in.expand(key, item)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = expanded
+		}
+		return fields, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandString expands the references in a string value of key.
+func (in *interpolator) expandString(key, s string) (any, error) {
+	matches := referencePattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	if m := matches[0]; len(matches) == 1 && m[0] == 0 && m[1] == len(s) && m[2] >= 0 {
+		return in.reference(key, s[m[2]:m[3]])
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+		if m[2] < 0 {
+			b.WriteString("${")
+			continue
+		}
+
+		value, err := in.reference(key, s[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(&b, value)
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+// reference resolves one reference made from key.
+func (in *interpolator) reference(key, ref string) (any, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("empty reference ${}")
+	}
+
+	if name, ok := strings.CutPrefix(ref, envPrefix); ok {
+		value, set := os.LookupEnv(name)
+		if !set {
+			return nil, fmt.Errorf("environment variable %s in ${%s} is not set", name, ref)
+		}
+		return value, nil
+	}
+
+	target := strings.ToLower(ref)
+	for scope := parentKey(key); ; scope = parentKey(scope) {
+		candidate := target
+		if scope != "" {
+			candidate = scope + "." + target
+		}
+		if in.v.IsSet(candidate) {
+			if _, isSection := in.v.Get(candidate).(map[string]any); isSection {
+				return nil, fmt.Errorf("${%s} refers to the section %s, not a value", ref, candidate)
+			}
+			value, err := in.value(candidate)
+			var cycle cycleError
+			if errors.As(err, &cycle) {
+				return nil, err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("in ${%s}: %w", ref, err)
+			}
+			return value, nil
+		}
+		if scope == "" {
+			return nil, fmt.Errorf("unresolved reference ${%s}", ref)
+		}
+	}
+}
+
+// parentKey returns the section holding a dotted key, or "" at the top level.
+func parentKey(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
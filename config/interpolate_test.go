@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/spf13/viper"
+)
+
+// TestInterpolate tests expanding key and environment references.
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		want    map[string]any
+		wantErr []string // expected problems, as "key: message" prefixes
+	}{
+		{
+			name: "full and relative references",
+			yaml: `
+paths:
+  base:
+    tasks: Tasks
+  subdirs:
+    tasks:
+      completed: ${paths.base.tasks}/Completed
+settings:
+  patterns:
+    date_format: YYYY-MM-DD
+    file_format: "*-${date_format}"
+`,
+			want: map[string]any{
+				"paths.subdirs.tasks.completed": "Tasks/Completed",
+				"settings.patterns.file_format": "*-YYYY-MM-DD",
+			},
+		},
+		{
+			name: "nearest enclosing key wins",
+			yaml: `
+name: top
+section:
+  name: inner
+  value: ${name}
+other: ${name}
+`,
+			want: map[string]any{"section.value": "inner", "other": "top"},
+		},
+		{
+			name: "environment variables and escape",
+			yaml: `
+root: ${env:CEREBGO_TEST_ROOT}/vault
+literal: $${env:CEREBGO_TEST_ROOT}
+`,
+			env:  map[string]string{"CEREBGO_TEST_ROOT": "/data"},
+			want: map[string]any{"root": "/data/vault", "literal": "${env:CEREBGO_TEST_ROOT}"},
+		},
+		{
+			name: "single reference keeps its type",
+			yaml: `
+days: 30
+trash:
+  retention: ${days}
+  label: ${days} days
+`,
+			want: map[string]any{"trash.retention": 30, "trash.label": "30 days"},
+		},
+		{
+			name: "references inside lists",
+			yaml: `
+owner: Belle
+journals:
+  - name: ${owner}
+`,
+			want: map[string]any{"journals": []any{map[string]any{"name": "Belle"}}},
+		},
+		{
+			name: "reference cycle",
+			yaml: `
+a: ${b}
+b: ${a}
+`,
+			wantErr: []string{"a: reference cycle a -> b -> a", "b: reference cycle b -> a -> b"},
+		},
+		{
+			name:    "unresolved reference and unset variable",
+			yaml:    "path: ${paths.nowhere}\nhome: ${env:CEREBGO_TEST_UNSET}\n",
+			wantErr: []string{"home: environment variable CEREBGO_TEST_UNSET", "path: unresolved reference ${paths.nowhere}"},
+		},
+		{
+			name:    "reference to a section",
+			yaml:    "paths:\n  base:\n    tasks: Tasks\nvalue: ${paths.base}\n",
+			wantErr: []string{"value: ${paths.base} refers to the section paths.base"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg := viper.New()
+			cfg.SetConfigType("yaml")
+			if err := cfg.ReadConfig(strings.NewReader(tt.yaml)); err != nil {
+				t.Fatal(err)
+			}
+
+			err := config.Interpolate(cfg)
+			if len(tt.wantErr) > 0 {
+				var validationErr *config.ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Interpolate() error = %v, want a *ValidationError", err)
+				}
+				if len(validationErr.Errors) != len(tt.wantErr) {
+					t.Errorf("Interpolate() found %d problems, want %d:\n%v", len(validationErr.Errors), len(tt.wantErr), err)
+				}
+				for i, want := range tt.wantErr {
+					if i < len(validationErr.Errors) && !strings.HasPrefix(validationErr.Errors[i].Error(), want) {
+						t.Errorf("problem %d = %q, want prefix %q", i, validationErr.Errors[i], want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Interpolate() error = %v", err)
+			}
+
+			for key, want := range tt.want {
+				if got := cfg.Get(key); !reflect.DeepEqual(got, want) {
+					t.Errorf("Get(%q) = %#v, want %#v", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// FieldError describes one invalid, missing or unknown configuration key.
+type FieldError struct {
+	Key     string // dotted key, such as "settings.retention.empty_task"
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// ValidationError holds every problem found by Validate.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid configuration key " + e.Errors[0].Error()
+	}
+	lines := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		lines = append(lines, fieldErr.Error())
+	}
+	return fmt.Sprintf("%d configuration problems:\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// Unwrap returns the individual field errors.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		errs = append(errs, fieldErr)
+	}
+	return errs
+}
+
+// kind is the type of a configuration value.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindBool
+	kindList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindInt:
+		return "a whole number"
+	case kindBool:
+		return "true or false"
+	case kindList:
+		return "a list"
+	default:
+		return "a string"
+	}
+}
+
+// field describes a configuration key and how its value is checked.
+type field struct {
+	key      string
+	kind     kind
+	required bool
+	check    func(v *validator, value any) string // returns a problem, or "" if the value is fine
+}
+
+// schema lists every configuration key.
+var schema = []field{
+	{key: "paths.base.inbox", check: vaultEntry},
+	{key: "paths.base.tasks", required: true, check: vaultDir},
+	{key: "paths.base.journal", required: true, check: vaultDir},
+	{key: "paths.base.lists", check: vaultDir},
+	{key: "paths.base.people", check: vaultDir},
+	{key: "paths.base.archives", required: true, check: vaultDir},
+	{key: "paths.subdirs.tasks.completed", required: true, check: vaultDir},
+	{key: "paths.subdirs.journal.completed", check: vaultDir},
+	{key: "journals", kind: kindList, check: journalNames},
+	{key: "settings.retention.empty_task", kind: kindInt, required: true, check: nonNegative},
+	{key: "settings.retention.project_before_archive", kind: kindInt, required: true, check: nonNegative},
+	{key: "settings.archive.collision", check: collisionPolicy},
+	{key: "settings.tasks.collision", check: movePolicy},
+	{key: "settings.tasks.rollover", kind: kindBool},
+	{key: "settings.trash.path", check: vaultPath},
+	{key: "settings.trash.retention", kind: kindInt, check: nonNegative},
+	{key: "settings.trash.permanent_delete", kind: kindBool},
+	{key: "settings.lock.stale_after", kind: kindInt, check: nonNegative},
+	{key: "settings.patterns.date_format", required: true, check: datePattern(false)},
+	{key: "settings.patterns.file_format", check: datePattern(true)},
+}
+
+// validator collects the problems of one Validate call.
+type validator struct {
+	fsys     afero.Fs
+	dataPath string
+	errs     []*FieldError
+}
+
+func (v *validator) add(key, format string, args ...any) {
+	v.errs = append(v.errs, &FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a configuration against the schema: key names, value types, required
+// keys, non-negative durations, vault paths that exist under dataPath, unique journal
+// names and date patterns. Every problem is reported, and misspelled keys come with a
+// suggestion.
+//
+// Parameters:
+//   - cfg: the loaded configuration
+//   - fsys: filesystem holding the vault
+//   - dataPath: the vault root, from DATA_PATH
+//
+// Returns:
+//   - error: a *ValidationError listing every problem, or nil
+func Validate(cfg *viper.Viper, fsys afero.Fs, dataPath string) error {
+	v := &validator{fsys: fsys, dataPath: dataPath}
+	if dataPath == "" {
+		v.add("DATA_PATH", "environment variable not set")
+	}
+
+	known := make(map[string]bool, len(schema))
+	for _, f := range schema {
+		known[f.key] = true
+	}
+
+	keys := cfg.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if known[key] {
+			continue
+		}
+		if suggestion := closestKey(key); suggestion != "" {
+			v.add(key, "unknown key, did you mean %q?", suggestion)
+		} else {
+			v.add(key, "unknown key")
+		}
+	}
+
+	for _, f := range schema {
+		value := cfg.Get(f.key)
+		if value == nil {
+			if f.required {
+				v.add(f.key, "missing required key")
+			}
+			continue
+		}
+		if !hasKind(value, f.kind) {
+			v.add(f.key, "must be %s, got %v", f.kind, value)
+			continue
+		}
+		if f.check != nil {
+			if problem := f.check(v, value); problem != "" {
+				v.add(f.key, "%s", problem)
+			}
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// hasKind reports whether a decoded YAML value is of the expected kind.
+func hasKind(value any, k kind) bool {
+	switch value.(type) {
+	case string:
+		return k == kindString
+	case int, int64:
+		return k == kindInt
+	case bool:
+		return k == kindBool
+	case []any:
+		return k == kindList
+	default:
+		return false
+	}
+}
+
+// nonNegative checks that a number of days or minutes is not negative.
+func nonNegative(_ *validator, value any) string {
+	if n, _ := value.(int); n < 0 {
+		return fmt.Sprintf("must not be negative, got %d", n)
+	}
+	if n, _ := value.(int64); n < 0 {
+		return fmt.Sprintf("must not be negative, got %d", n)
+	}
+	return ""
+}
+
+// vaultPath checks that a path stays inside the vault.
+func vaultPath(v *validator, value any) string {
+	path := value.(string)
+	if strings.TrimSpace(path) == "" {
+		return "must not be empty"
+	}
+	if v.dataPath == "" {
+		return ""
+	}
+	if !files.Within(v.dataPath, filepath.Join(v.dataPath, path)) {
+		return fmt.Sprintf("%q is outside DATA_PATH", path)
+	}
+	return ""
+}
+
+// vaultEntry checks that a path exists inside the vault.
+func vaultEntry(v *validator, value any) string {
+	if problem := vaultPath(v, value); problem != "" || v.dataPath == "" {
+		return problem
+	}
+	full := filepath.Join(v.dataPath, value.(string))
+	if _, err := v.fsys.Stat(full); err != nil {
+		return pathProblem(full, err)
+	}
+	return ""
+}
+
+// vaultDir checks that a path is an existing directory inside the vault.
+func vaultDir(v *validator, value any) string {
+	if problem := vaultPath(v, value); problem != "" || v.dataPath == "" {
+		return problem
+	}
+	full := filepath.Join(v.dataPath, value.(string))
+	info, err := v.fsys.Stat(full)
+	if err != nil {
+		return pathProblem(full, err)
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%s is not a directory", full)
+	}
+	return ""
+}
+
+// pathProblem describes why a path cannot be used.
+func pathProblem(path string, err error) string {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("%s does not exist", path)
+	}
+	return err.Error()
+}
+
+// journalNames checks that every journal has a unique name.
+func journalNames(_ *validator, value any) string {
+	seen := make(map[string]bool)
+	problems := make([]string, 0)
+	for i, item := range value.([]any) {
+		entry, ok := item.(map[string]any)
+		name, _ := entry["name"].(string)
+		switch {
+		case !ok || strings.TrimSpace(name) == "":
+			problems = append(problems, fmt.Sprintf("journal %d has no name", i+1))
+		case seen[strings.ToLower(name)]:
+			problems = append(problems, fmt.Sprintf("journal name %q is used twice", name))
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	return strings.Join(problems, "; ")
+}
+
+// collisionPolicy checks a record collision policy.
+func collisionPolicy(_ *validator, value any) string {
+	if _, err := records.ParseCollisionPolicy(value.(string)); err != nil {
+		return err.Error() + ", use overwrite, suffix, merge or version"
+	}
+	return ""
+}
+
+// movePolicy checks a file move policy.
+func movePolicy(_ *validator, value any) string {
+	if _, err := files.ParseMovePolicy(value.(string)); err != nil {
+		return err.Error() + ", use error, suffix or keep_newer"
+	}
+	return ""
+}
+
+// datePattern checks a date pattern made of YYYY, MM and DD, each used once, and
+// separators. File patterns may also hold "*" wildcards.
+func datePattern(wildcards bool) func(*validator, any) string {
+	return func(_ *validator, value any) string {
+		pattern := value.(string)
+		counts := map[string]int{}
+		for rest := pattern; rest != ""; {
+			token := ""
+			for _, t := range []string{"YYYY", "MM", "DD"} {
+				if strings.HasPrefix(rest, t) {
+					token = t
+					break
+				}
+			}
+			if token != "" {
+				counts[token]++
+				rest = rest[len(token):]
+				continue
+			}
+
+			r := []rune(rest)[0]
+			switch {
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				return fmt.Sprintf("unknown token at %q in %q, use YYYY, MM and DD", rest, pattern)
+			case r == '*' && !wildcards:
+				return fmt.Sprintf("wildcard not allowed in %q", pattern)
+			}
+			rest = rest[len(string(r)):]
+		}
+
+		for _, t := range []string{"YYYY", "MM", "DD"} {
+			if counts[t] != 1 {
+				return fmt.Sprintf("%q must contain %s exactly once", pattern, t)
+			}
+		}
+		return ""
+	}
+}
+
+// closestKey suggests the schema key closest to a misspelled key, or "" if none is close.
+func closestKey(key string) string {
+	best, bestDistance := "", 4
+	for _, f := range schema {
+		if d := editDistance(key, f.key); d < bestDistance {
+			best, bestDistance = f.key, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr := make([]int, len(br)+1)
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,162 @@
+package config_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/viper"
+)
+
+// schemaConfig is a complete configuration for the vault at /vault.
+const schemaConfig = `
+paths:
+  base:
+    inbox: Notes.md
+    tasks: Tasks
+    journal: Journals
+    archives: Archive
+  subdirs:
+    tasks:
+      completed: Tasks/Completed
+journals:
+  - name: Belle
+  - name: Pure
+settings:
+  retention:
+    empty_task: 30
+    project_before_archive: 7
+  archive:
+    collision: suffix
+  tasks:
+    collision: keep_newer
+  trash:
+    path: .trash
+    retention: 30
+    permanent_delete: false
+  lock:
+    stale_after: 60
+  patterns:
+    date_format: "YYYY-MM-DD"
+    file_format: "*-YYYY-MM-DD"
+`
+
+// TestValidate verifies that every configuration problem is reported at once.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		replace  []string // pairs of old and new text applied to schemaConfig
+		dataPath string
+		want     []string // expected problems, as "key: message" prefixes
+	}{
+		{
+			name:     "valid configuration",
+			dataPath: "/vault",
+		},
+		{
+			name:     "misspelled key",
+			replace:  []string{"empty_task:", "empty_tasks:"},
+			dataPath: "/vault",
+			want: []string{
+				`settings.retention.empty_tasks: unknown key, did you mean "settings.retention.empty_task"?`,
+				"settings.retention.empty_task: missing required key",
+			},
+		},
+		{
+			name:     "wrong types and negative retention",
+			replace:  []string{"project_before_archive: 7", "project_before_archive: -1", "empty_task: 30", `empty_task: "30"`, "permanent_delete: false", "permanent_delete: sometimes"},
+			dataPath: "/vault",
+			want: []string{
+				"settings.retention.empty_task: must be a whole number",
+				"settings.retention.project_before_archive: must not be negative",
+				"settings.trash.permanent_delete: must be true or false",
+			},
+		},
+		{
+			name:     "paths outside or missing from the vault",
+			replace:  []string{"archives: Archive", "archives: ../elsewhere", "journal: Journals", "journal: Journal", "inbox: Notes.md", "inbox: Tasks/Inbox.md"},
+			dataPath: "/vault",
+			want: []string{
+				"paths.base.inbox: /vault/Tasks/Inbox.md does not exist",
+				"paths.base.journal: /vault/Journal does not exist",
+				`paths.base.archives: "../elsewhere" is outside DATA_PATH`,
+			},
+		},
+		{
+			name:     "file instead of directory",
+			replace:  []string{"tasks: Tasks\n", "tasks: Notes.md\n"},
+			dataPath: "/vault",
+			want:     []string{"paths.base.tasks: /vault/Notes.md is not a directory"},
+		},
+		{
+			name:     "duplicate journal names and bad policies",
+			replace:  []string{"name: Pure", "name: belle", "collision: suffix", "collision: sufix", "collision: keep_newer", "collision: newest"},
+			dataPath: "/vault",
+			want: []string{
+				`journals: journal name "belle" is used twice`,
+				`settings.archive.collision: unknown collision policy "sufix"`,
+				`settings.tasks.collision: unknown move policy "newest"`,
+			},
+		},
+		{
+			name:     "invalid date patterns",
+			replace:  []string{`date_format: "YYYY-MM-DD"`, `date_format: "YYYY-MM-DDTHH"`, `file_format: "*-YYYY-MM-DD"`, `file_format: "*-YYYY-MM"`},
+			dataPath: "/vault",
+			want: []string{
+				`settings.patterns.date_format: unknown token at "THH"`,
+				`settings.patterns.file_format: "*-YYYY-MM" must contain DD exactly once`,
+			},
+		},
+		{
+			name: "missing DATA_PATH",
+			want: []string{"DATA_PATH: environment variable not set"},
+		},
+	}
+
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Notes.md":              "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := strings.NewReplacer(tt.replace...).Replace(schemaConfig)
+			cfg := viper.New()
+			cfg.SetConfigType("yaml")
+			if err := cfg.ReadConfig(strings.NewReader(yaml)); err != nil {
+				t.Fatal(err)
+			}
+
+			err := config.Validate(cfg, fsys, tt.dataPath)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var validationErr *config.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+			}
+			if len(validationErr.Errors) != len(tt.want) {
+				t.Errorf("Validate() found %d problems, want %d:\n%v", len(validationErr.Errors), len(tt.want), err)
+			}
+			for _, want := range tt.want {
+				found := false
+				for _, fieldErr := range validationErr.Errors {
+					if strings.HasPrefix(fieldErr.Error(), want) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("missing problem %q in:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// Subsystem is a part of a run that can be turned off for a vault.
+type Subsystem string
+
+const (
+	// SubsystemTasks processes the active and completed tasks.
+	SubsystemTasks Subsystem = "tasks"
+	// SubsystemTrash purges the trashed files older than the trash retention.
+	SubsystemTrash Subsystem = "trash"
+)
+
+// Subsystems lists every subsystem, in the order a run uses them.
+var Subsystems = []Subsystem{SubsystemTasks, SubsystemTrash}
+
+// Enabled reports whether a subsystem runs for the vault.
+func (c *Config) Enabled(s Subsystem) bool {
+	return slices.Contains(c.Subsystems, s)
+}
+
+// Vault is one vault of a run. A vault whose configuration is invalid has Err set, so
+// that the other vaults can still be processed.
+type Vault struct {
+	Name   string // empty for the single DATA_PATH vault
+	Config *Config
+	Err    error
+}
+
+// vaultsKey lists the vaults of a run in the top-level configuration.
+const vaultsKey = "vaults"
+
+// vaultEntryKeys are the keys of an entry of the vaults list.
+var vaultEntryKeys = []string{"name", "data_path", "subsystems", "overrides"}
+
+// Vaults returns the vaults of a run.
+//
+// Without a "vaults" list, the run has one vault in dataPath. Otherwise every entry is a
+// vault with a name, a data_path, the subsystems to run (all by default) and overrides
+// that are merged over the other top-level settings. References are expanded after the
+// merge, see Interpolate, so that a derived path such as "${paths.base.tasks}/Completed"
+// follows a vault's own base path. Relative data paths are resolved against dataPath.
+// Each vault is validated on its own, see New.
+//
+// Parameters:
+//   - v: the loaded top-level configuration, as returned by LoadConfig: expanded
+//     without a vaults list, and not yet expanded with one
+//   - fsys: filesystem holding the vaults
+//   - dataPath: DATA_PATH, the vault root or the directory of relative vault paths
+//
+// Returns:
+//   - []Vault: the vaults in configuration order, each with its Config or Err
+//   - error: a *ValidationError if the vaults list itself is invalid
+func Vaults(v *viper.Viper, fsys afero.Fs, dataPath string) ([]Vault, error) {
+	if !v.IsSet(vaultsKey) {
+		cfg, err := New(v, fsys, dataPath)
+		return []Vault{{Config: cfg, Err: err}}, nil
+	}
+
+	specs, errs := vaultSpecs(v, dataPath)
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	shared := v.AllSettings()
+	delete(shared, vaultsKey)
+
+	vaults := make([]Vault, 0, len(specs))
+	for _, spec := range specs {
+		merged := viper.New()
+		if err := merged.MergeConfigMap(copySettings(shared)); err != nil {
+			return nil, err
+		}
+		if err := merged.MergeConfigMap(copySettings(spec.overrides)); err != nil {
+			return nil, err
+		}
+		if err := Interpolate(merged); err != nil {
+			vaults = append(vaults, Vault{Name: spec.name, Err: err})
+			continue
+		}
+
+		cfg, err := New(merged, fsys, spec.dataPath)
+		if err != nil {
+			vaults = append(vaults, Vault{Name: spec.name, Err: err})
+			continue
+		}
+		cfg.File = v.ConfigFileUsed()
+		cfg.Subsystems = spec.subsystems
+		vaults = append(vaults, Vault{Name: spec.name, Config: cfg})
+	}
+	return vaults, nil
+}
+
+// vaultSpec is a checked entry of the vaults list.
+type vaultSpec struct {
+	name       string
+	dataPath   string
+	subsystems []Subsystem
+	overrides  map[string]any
+}
+
+// vaultSpecs checks the entries of the vaults list of v. References in data paths are
+// expanded against the top-level settings.
+func vaultSpecs(v *viper.Viper, dataPath string) ([]vaultSpec, []*FieldError) {
+	var errs []*FieldError
+	add := func(key, format string, args ...any) {
+		errs = append(errs, &FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	items, ok := v.Get(vaultsKey).([]any)
+	if !ok || len(items) == 0 {
+		add(vaultsKey, "must be a list of vaults")
+		return nil, errs
+	}
+
+	specs := make([]vaultSpec, 0, len(items))
+	seen := make(map[string]bool)
+	for i, item := range items {
+		key := fmt.Sprintf("%s[%d]", vaultsKey, i)
+		fields, ok := item.(map[string]any)
+		if !ok {
+			add(key, "must be a vault with a name and a data_path")
+			continue
+		}
+		for name := range fields {
+			if !slices.Contains(vaultEntryKeys, name) {
+				add(key+"."+name, "unknown key, use %s", strings.Join(vaultEntryKeys, ", "))
+			}
+		}
+
+		spec := vaultSpec{subsystems: Subsystems}
+		spec.name, _ = fields["name"].(string)
+		switch {
+		case strings.TrimSpace(spec.name) == "":
+			add(key+".name", "missing vault name")
+		case seen[strings.ToLower(spec.name)]:
+			add(key+".name", "vault name %q is used twice", spec.name)
+		}
+		seen[strings.ToLower(spec.name)] = true
+
+		path, _ := fields["data_path"].(string)
+		expanded, err := expandValue(v, path)
+		path = fmt.Sprint(expanded)
+		switch {
+		case err != nil:
+			add(key+".data_path", "%v", err)
+		case strings.TrimSpace(path) == "":
+			add(key+".data_path", "missing data path")
+		case filepath.IsAbs(path):
+			spec.dataPath = filepath.Clean(path)
+		case dataPath == "":
+			add(key+".data_path", "relative path %q needs DATA_PATH", path)
+		default:
+			spec.dataPath = filepath.Join(dataPath, path)
+		}
+
+		if value, set := fields["subsystems"]; set {
+			names, ok := value.([]any)
+			if !ok {
+				add(key+".subsystems", "must be a list")
+			}
+			spec.subsystems = make([]Subsystem, 0, len(names))
+			for _, name := range names {
+				s, _ := name.(string)
+				if !slices.Contains(Subsystems, Subsystem(s)) {
+					add(key+".subsystems", "unknown subsystem %v, use tasks or trash", name)
+					continue
+				}
+				spec.subsystems = append(spec.subsystems, Subsystem(s))
+			}
+		}
+
+		if value, set := fields["overrides"]; set {
+			if spec.overrides, ok = value.(map[string]any); !ok {
+				add(key+".overrides", "must be a section of settings")
+			}
+		}
+		if _, set := spec.overrides[vaultsKey]; set {
+			add(key+".overrides."+vaultsKey, "vaults cannot be nested")
+		}
+
+		specs = append(specs, spec)
+	}
+	return specs, errs
+}
+
+// copySettings returns a deep copy of nested settings, since merging modifies them.
+func copySettings(settings map[string]any) map[string]any {
+	out := make(map[string]any, len(settings))
+	for key, value := range settings {
+		if section, ok := value.(map[string]any); ok {
+			value = copySettings(section)
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package config_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/viper"
+)
+
+// TestVaults verifies that every vault gets the shared settings with its own overrides,
+// and that an invalid vault does not hide the others.
+func TestVaults(t *testing.T) {
+	type wantVault struct {
+		name       string
+		dataPath   string
+		emptyTask  time.Duration
+		subsystems []config.Subsystem
+		wantErr    string // prefix of the vault's error
+	}
+
+	tests := []struct {
+		name     string
+		vaults   string // the vaults list appended to schemaConfig
+		dataPath string
+		want     []wantVault
+		wantErr  []string // problems of the vaults list, as "key: message" prefixes
+	}{
+		{
+			name:     "single DATA_PATH vault",
+			dataPath: "/vaults/home",
+			want:     []wantVault{{dataPath: "/vaults/home", emptyTask: 30 * 24 * time.Hour, subsystems: config.Subsystems}},
+		},
+		{
+			name: "overrides, subsystems and relative data paths",
+			vaults: `
+vaults:
+  - name: home
+    data_path: /vaults/home
+    overrides:
+      settings:
+        retention:
+          empty_task: 3
+  - name: work
+    data_path: work
+    subsystems: [tasks]
+`,
+			dataPath: "/vaults",
+			want: []wantVault{
+				{name: "home", dataPath: "/vaults/home", emptyTask: 3 * 24 * time.Hour, subsystems: config.Subsystems},
+				{name: "work", dataPath: "/vaults/work", emptyTask: 30 * 24 * time.Hour, subsystems: []config.Subsystem{config.SubsystemTasks}},
+			},
+		},
+		{
+			name: "invalid vault is isolated",
+			vaults: `
+vaults:
+  - name: home
+    data_path: /vaults/home
+  - name: shared
+    data_path: /vaults/shared
+`,
+			want: []wantVault{
+				{name: "home", dataPath: "/vaults/home", emptyTask: 30 * 24 * time.Hour, subsystems: config.Subsystems},
+				{name: "shared", wantErr: "5 configuration problems"},
+			},
+		},
+		{
+			name: "invalid vaults list",
+			vaults: `
+vaults:
+  - name: home
+    data_path: home
+    subsystems: [tasks, journals]
+  - name: Home
+    path: /vaults/work
+`,
+			wantErr: []string{
+				`vaults[0].data_path: relative path "home" needs DATA_PATH`,
+				"vaults[0].subsystems: unknown subsystem journals",
+				"vaults[1].path: unknown key",
+				`vaults[1].name: vault name "Home" is used twice`,
+				"vaults[1].data_path: missing data path",
+			},
+		},
+	}
+
+	vaultFiles := map[string]string{}
+	for _, root := range []string{"/vaults/home", "/vaults/work"} {
+		vaultFiles[root+"/Notes.md"] = ""
+		vaultFiles[root+"/Tasks/Completed/.keep"] = ""
+		vaultFiles[root+"/Journals/.keep"] = ""
+		vaultFiles[root+"/Archive/.keep"] = ""
+	}
+	fsys := testutil.CreateMemFs(t, vaultFiles)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(schemaConfig + tt.vaults)); err != nil {
+				t.Fatal(err)
+			}
+
+			vaults, err := config.Vaults(v, fsys, tt.dataPath)
+			if len(tt.wantErr) > 0 {
+				var validationErr *config.ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Vaults() error = %v, want a *ValidationError", err)
+				}
+				if len(validationErr.Errors) != len(tt.wantErr) {
+					t.Errorf("Vaults() found %d problems, want %d:\n%v", len(validationErr.Errors), len(tt.wantErr), err)
+				}
+				for _, want := range tt.wantErr {
+					found := false
+					for _, fieldErr := range validationErr.Errors {
+						found = found || strings.HasPrefix(fieldErr.Error(), want)
+					}
+					if !found {
+						t.Errorf("missing problem %q in:\n%v", want, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Vaults() error = %v", err)
+			}
+
+			if len(vaults) != len(tt.want) {
+				t.Fatalf("Vaults() returned %d vaults, want %d", len(vaults), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := vaults[i]
+				if got.Name != want.name {
+					t.Errorf("vault %d name = %q, want %q", i, got.Name, want.name)
+				}
+				if want.wantErr != "" {
+					if got.Err == nil || !strings.HasPrefix(got.Err.Error(), want.wantErr) {
+						t.Errorf("vault %s error = %v, want %q", want.name, got.Err, want.wantErr)
+					}
+					continue
+				}
+				if got.Err != nil {
+					t.Errorf("vault %s error = %v", want.name, got.Err)
+					continue
+				}
+				if got.Config.DataPath != want.dataPath {
+					t.Errorf("vault %s DataPath = %q, want %q", want.name, got.Config.DataPath, want.dataPath)
+				}
+				if got.Config.Retention.EmptyTask != want.emptyTask {
+					t.Errorf("vault %s Retention.EmptyTask = %v, want %v", want.name, got.Config.Retention.EmptyTask, want.emptyTask)
+				}
+				if !reflect.DeepEqual(got.Config.Subsystems, want.subsystems) {
+					t.Errorf("vault %s Subsystems = %v, want %v", want.name, got.Config.Subsystems, want.subsystems)
+				}
+			}
+		})
+	}
+}
+
+// TestVaults_Interpolation verifies that references are expanded after a vault's
+// overrides are merged, so that derived paths follow the vault's own base paths.
+func TestVaults_Interpolation(t *testing.T) {
+	t.Setenv("CEREBGO_TEST_VAULTS", "/vaults")
+	configText := strings.Replace(schemaConfig, "completed: Tasks/Completed", "completed: ${paths.base.tasks}/Completed", 1) + `
+vaults:
+  - name: home
+    data_path: ${env:CEREBGO_TEST_VAULTS}/home
+  - name: work
+    data_path: ${env:CEREBGO_TEST_VAULTS}/work
+    overrides:
+      paths:
+        base:
+          tasks: Projects
+`
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vaults/home/Notes.md":                 "",
+		"/vaults/home/Tasks/Completed/.keep":    "",
+		"/vaults/home/Journals/.keep":           "",
+		"/vaults/home/Archive/.keep":            "",
+		"/vaults/work/Notes.md":                 "",
+		"/vaults/work/Projects/Completed/.keep": "",
+		"/vaults/work/Journals/.keep":           "",
+		"/vaults/work/Archive/.keep":            "",
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(configText)); err != nil {
+		t.Fatal(err)
+	}
+
+	vaults, err := config.Vaults(v, fsys, "")
+	if err != nil {
+		t.Fatalf("Vaults() error = %v", err)
+	}
+
+	want := map[string]string{
+		"home": "/vaults/home/Tasks/Completed",
+		"work": "/vaults/work/Projects/Completed",
+	}
+	for _, vault := range vaults {
+		if vault.Err != nil {
+			t.Errorf("vault %s error = %v", vault.Name, vault.Err)
+			continue
+		}
+		if got := vault.Config.Paths.CompletedTasks; got != want[vault.Name] {
+			t.Errorf("vault %s CompletedTasks = %q, want %q", vault.Name, got, want[vault.Name])
+		}
+	}
+	if len(vaults) != len(want) {
+		t.Errorf("Vaults() returned %d vaults, want %d", len(vaults), len(want))
+	}
+}
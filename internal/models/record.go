@@ -7,11 +7,14 @@ import (
 )
 
 type Record struct {
-	Title      string
-	Content    ptr.Option[string]
-	Tags       []string
-	URL        ptr.Option[string]
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	ArchivedAt ptr.Option[time.Time]
+	Title       string
+	Content     ptr.Option[string]
+	Tags        []string
+	URL         ptr.Option[string]
+	Author      ptr.Option[string]
+	PublishedAt ptr.Option[time.Time]
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ArchivedAt  ptr.Option[time.Time]
+	Extra       map[string]interface{} // unknown frontmatter fields, written back unchanged
 }
@@ -17,4 +17,6 @@ type Task struct {
 	DoDate         string             // YYYY-MM-DD format, required
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	Extra          map[string]interface{} // unknown frontmatter fields, written back unchanged
+	Filename       string                 // name of the file the task was read from, empty for a new task
 }
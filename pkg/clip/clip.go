@@ -0,0 +1,206 @@
+package clip
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+)
+
+// Page holds the readable content and metadata extracted from a web page.
+type Page struct {
+	Title       string
+	URL         ptr.Option[string]
+	Author      ptr.Option[string]
+	PublishedAt ptr.Option[time.Time]
+	Content     string // markdown
+}
+
+// publishedLayouts are the date formats accepted for publish dates.
+var publishedLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"2 January 2006",
+}
+
+// Meta tags checked for each field, in order of preference.
+var (
+	titleKeys     = []string{"og:title", "twitter:title", "dc.title"}
+	urlKeys       = []string{"og:url", "twitter:url"}
+	authorKeys    = []string{"author", "article:author", "dc.creator", "twitter:creator", "parsely-author", "sailthru.author"}
+	publishedKeys = []string{"article:published_time", "datepublished", "date", "dc.date", "dc.date.issued", "pubdate", "publish-date", "parsely-pub-date", "og:published_time"}
+)
+
+// ParseHTML extracts the readable content and metadata of a saved web page.
+// Metadata is read from meta tags, the canonical link and the title element.
+// Content is taken from the <article> element when present, then <main>, then <body>,
+// and page chrome such as navigation, headers, footers and scripts is dropped.
+//
+// Parameters:
+//   - src: raw HTML of the page
+//   - fallbackTitle: title used when the page has none, e.g. the file name
+//
+// Returns:
+//   - Page: the extracted page
+func ParseHTML(src string, fallbackTitle string) Page {
+	root := parseHTML(src)
+	meta := collectMeta(root)
+
+	page := Page{
+		Title:       firstMeta(meta, titleKeys),
+		URL:         ptr.None[string](),
+		Author:      ptr.None[string](),
+		PublishedAt: ptr.None[time.Time](),
+	}
+
+	if page.Title == "" {
+		if title := root.find(byTag("title")); title != nil {
+			page.Title = strings.TrimSpace(whitespace.ReplaceAllString(title.textContent(), " "))
+		}
+	}
+	if page.Title == "" {
+		if h1 := root.find(byTag("h1")); h1 != nil {
+			page.Title = strings.TrimSpace(whitespace.ReplaceAllString(h1.textContent(), " "))
+		}
+	}
+	if page.Title == "" {
+		page.Title = fallbackTitle
+	}
+
+	if canonical := canonicalURL(root); canonical != "" {
+		page.URL = ptr.Some(canonical)
+	} else if u := firstMeta(meta, urlKeys); u != "" {
+		page.URL = ptr.Some(u)
+	}
+
+	if author := firstMeta(meta, authorKeys); author != "" {
+		page.Author = ptr.Some(author)
+	}
+
+	if published := firstMeta(meta, publishedKeys); published != "" {
+		if t, ok := parsePublished(published); ok {
+			page.PublishedAt = ptr.Some(t)
+		}
+	}
+	if !page.PublishedAt.IsValid() {
+		if el := root.find(func(n *node) bool { return n.tag == "time" && n.attrs["datetime"] != "" }); el != nil {
+			if t, ok := parsePublished(el.attrs["datetime"]); ok {
+				page.PublishedAt = ptr.Some(t)
+			}
+		}
+	}
+
+	var base *url.URL
+	if page.URL.IsValid() {
+		base, _ = url.Parse(page.URL.Value())
+	}
+	page.Content = converter{base: base}.toMarkdown(contentRoot(root))
+
+	return page
+}
+
+// ToRecord converts a page into an archive record.
+//
+// Parameters:
+//   - page: the extracted page
+//   - tags: tags to attach to the record
+//   - now: the clipping time, used for creation and archive timestamps
+//
+// Returns:
+//   - models.Record: the record to write
+func ToRecord(page Page, tags []string, now time.Time) models.Record {
+	record := models.Record{
+		Title:       page.Title,
+		Content:     ptr.None[string](),
+		Tags:        append(make([]string, 0, len(tags)), tags...),
+		URL:         page.URL,
+		Author:      page.Author,
+		PublishedAt: page.PublishedAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ArchivedAt:  ptr.Some(now),
+	}
+
+	if page.Content != "" {
+		record.Content = ptr.Some(page.Content)
+	}
+
+	return record
+}
+
+// collectMeta maps lowercase meta names, properties and itemprops to their content.
+// The first occurrence of a key wins.
+func collectMeta(root *node) map[string]string {
+	meta := make(map[string]string)
+	for _, el := range root.findAll(byTag("meta")) {
+		content := strings.TrimSpace(el.attrs["content"])
+		if content == "" {
+			continue
+		}
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			key := strings.ToLower(strings.TrimSpace(el.attrs[attr]))
+			if _, seen := meta[key]; key != "" && !seen {
+				meta[key] = content
+			}
+		}
+	}
+	return meta
+}
+
+// firstMeta returns the first non-empty meta value among the keys.
+func firstMeta(meta map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := meta[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// canonicalURL returns the href of the <link rel="canonical"> element, if any.
+func canonicalURL(root *node) string {
+	link := root.find(func(n *node) bool {
+		if n.tag != "link" {
+			return false
+		}
+		for _, rel := range strings.Fields(strings.ToLower(n.attrs["rel"])) {
+			if rel == "canonical" {
+				return true
+			}
+		}
+		return false
+	})
+	if link == nil {
+		return ""
+	}
+	return strings.TrimSpace(link.attrs["href"])
+}
+
+// contentRoot picks the element holding the main content of the page.
+func contentRoot(root *node) *node {
+	for _, tag := range []string{"article", "main", "body"} {
+		if el := root.find(byTag(tag)); el != nil {
+			return el
+		}
+	}
+	return root
+}
+
+// parsePublished parses a publish date in any of the accepted layouts.
+func parsePublished(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range publishedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,138 @@
+package clip_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/clip"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+)
+
+// TestParseHTML_Article tests metadata and content extraction from a saved article.
+func TestParseHTML_Article(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "article.html"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	page := clip.ParseHTML(string(data), "article")
+
+	results := []testutil.ValidationResult{
+		testutil.ValidateEqual("Title", page.Title, "Why Plain Text Wins"),
+		testutil.ValidateEqual("URL", page.URL.IsValid() && page.URL.Value() == "https://example.com/posts/plain-text", true),
+		testutil.ValidateEqual("Author", page.Author.IsValid() && page.Author.Value() == "Jane Doe", true),
+		testutil.ValidateEqual("PublishedAt", page.PublishedAt.IsValid() &&
+			page.PublishedAt.Value().Equal(time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC)), true),
+	}
+	testutil.ReportResults(t, results)
+
+	want := `# Why Plain Text Wins
+
+Plain text is **durable**, *portable* & easy to [search](https://example.com/search?q=grep).
+
+## Reasons
+
+- Works with ` + "`git`" + `
+- Readable everywhere
+  1. Phones
+  2. Servers
+
+> Text files outlive their apps.
+
+` + "```" + `
+grep -r "todo" .
+` + "```" + `
+
+Line one
+Line two
+
+![Diagram](https://example.com/posts/images/diagram.png)
+
+| Format | Lifetime |
+| --- | --- |
+| txt | forever |`
+
+	if page.Content != want {
+		t.Errorf("Content mismatch\ngot:\n%s\nwant:\n%s", page.Content, want)
+	}
+
+	for _, chrome := range []string{"Home", "Copyright", "not content", "color: red"} {
+		if strings.Contains(page.Content, chrome) {
+			t.Errorf("Content should not contain page chrome %q", chrome)
+		}
+	}
+}
+
+// TestParseHTML_Fallbacks tests metadata fallbacks for minimal pages.
+func TestParseHTML_Fallbacks(t *testing.T) {
+	tests := []struct {
+		name          string
+		html          string
+		wantTitle     string
+		wantURL       string
+		wantPublished string
+		wantContent   string
+	}{
+		{
+			name:        "title element when no meta title",
+			html:        `<html><head><title> Plain   Title </title></head><body><p>Hi</p></body></html>`,
+			wantTitle:   "Plain Title",
+			wantContent: "Hi",
+		},
+		{
+			name:          "first heading and time element",
+			html:          `<body><main><h1>Heading</h1><time datetime="2023-12-24">Dec 24</time></main></body>`,
+			wantTitle:     "Heading",
+			wantPublished: "2023-12-24",
+			wantContent:   "# Heading\n\nDec 24",
+		},
+		{
+			name:        "fallback title and og url",
+			html:        `<meta property="og:url" content="https://example.org/a"><p>Unclosed <b>bold`,
+			wantTitle:   "saved-page",
+			wantURL:     "https://example.org/a",
+			wantContent: "Unclosed **bold**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := clip.ParseHTML(tt.html, "saved-page")
+
+			if page.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", page.Title, tt.wantTitle)
+			}
+			if tt.wantURL != "" && (!page.URL.IsValid() || page.URL.Value() != tt.wantURL) {
+				t.Errorf("URL = %v, want %q", page.URL, tt.wantURL)
+			}
+			if tt.wantPublished != "" &&
+				(!page.PublishedAt.IsValid() || page.PublishedAt.Value().Format("2006-01-02") != tt.wantPublished) {
+				t.Errorf("PublishedAt = %v, want %q", page.PublishedAt, tt.wantPublished)
+			}
+			if page.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", page.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+// TestToRecord tests conversion of a page into an archive record.
+func TestToRecord(t *testing.T) {
+	now := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+	page := clip.ParseHTML(`<title>Note</title><p>Body</p>`, "fallback")
+
+	record := clip.ToRecord(page, []string{"clip", "reading"}, now)
+
+	results := []testutil.ValidationResult{
+		testutil.ValidateEqual("Title", record.Title, "Note"),
+		testutil.ValidateEqual("Tags", strings.Join(record.Tags, ","), "clip,reading"),
+		testutil.ValidateEqual("CreatedAt", record.CreatedAt, now),
+		testutil.ValidateEqual("ArchivedAt", record.ArchivedAt.IsValid() && record.ArchivedAt.Value().Equal(now), true),
+		testutil.ValidateOptional("Content", record.Content, ptr.Some("Body"), testutil.StringComparer),
+	}
+	testutil.ReportResults(t, results)
+}
@@ -0,0 +1,271 @@
+package clip
+
+import (
+	"html"
+	"strings"
+)
+
+// node is an element or text node of a parsed HTML document.
+// The parser is deliberately forgiving: it only needs enough structure
+// to extract metadata and readable content from saved web pages.
+type node struct {
+	tag      string // lowercase element name, empty for text nodes
+	attrs    map[string]string
+	text     string // unescaped text, only for text nodes
+	children []*node
+	parent   *node
+}
+
+// voidElements never have children or end tags.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text that must not be parsed as markup.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// autoClosed lists elements that are implicitly closed when a sibling of
+// the same kind starts, e.g. "<li>one<li>two".
+var autoClosed = map[string]bool{
+	"li": true, "p": true, "td": true, "th": true, "tr": true, "option": true,
+}
+
+// parseHTML builds a node tree from an HTML document.
+//
+// Parameters:
+//   - src: raw HTML text
+//
+// Returns:
+//   - *node: synthetic root node holding the top-level nodes
+func parseHTML(src string) *node {
+	root := &node{tag: "#root"}
+	current := root
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			end := strings.IndexByte(src[i:], '<')
+			if end == -1 {
+				end = len(src) - i
+			}
+			appendText(current, src[i:i+end])
+			i += end
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(src[i:], "<!--"):
+			end := strings.Index(src[i+4:], "-->")
+			if end == -1 {
+				return root
+			}
+			i += 4 + end + 3
+
+		case strings.HasPrefix(src[i:], "<!") || strings.HasPrefix(src[i:], "<?"):
+			end := strings.IndexByte(src[i:], '>')
+			if end == -1 {
+				return root
+			}
+			i += end + 1
+
+		case strings.HasPrefix(src[i:], "</"):
+			end := strings.IndexByte(src[i:], '>')
+			if end == -1 {
+				return root
+			}
+			name := strings.ToLower(strings.TrimSpace(src[i+2 : i+end]))
+			current = closeElement(current, name)
+			i += end + 1
+
+		default:
+			tag, attrs, selfClosing, length := parseStartTag(src[i:])
+			if length == 0 {
+				appendText(current, "<")
+				i++
+				continue
+			}
+			i += length
+
+			if autoClosed[tag] && current.tag == tag {
+				current = current.parent
+			}
+
+			el := &node{tag: tag, attrs: attrs, parent: current}
+			current.children = append(current.children, el)
+
+			if rawTextElements[tag] {
+				closing := "</" + tag
+				end := strings.Index(strings.ToLower(src[i:]), closing)
+				if end == -1 {
+					end = len(src) - i
+				}
+				appendText(el, src[i:i+end])
+				i += end
+				if gt := strings.IndexByte(src[i:], '>'); gt != -1 {
+					i += gt + 1
+				}
+				continue
+			}
+
+			if !selfClosing && !voidElements[tag] {
+				current = el
+			}
+		}
+	}
+
+	return root
+}
+
+// parseStartTag parses a start tag at the beginning of src.
+// Returns a zero length if src does not start with a valid tag.
+func parseStartTag(src string) (tag string, attrs map[string]string, selfClosing bool, length int) {
+	i := 1
+	start := i
+	for i < len(src) && isNameChar(src[i]) {
+		i++
+	}
+	if i == start {
+		return "", nil, false, 0
+	}
+	tag = strings.ToLower(src[start:i])
+	attrs = make(map[string]string)
+
+	for i < len(src) {
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i >= len(src) {
+			return "", nil, false, 0
+		}
+		switch {
+		case src[i] == '>':
+			return tag, attrs, selfClosing, i + 1
+		case src[i] == '/':
+			selfClosing = true
+			i++
+			continue
+		}
+
+		nameStart := i
+		for i < len(src) && !isSpace(src[i]) && src[i] != '=' && src[i] != '>' && src[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(src[nameStart:i])
+
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i >= len(src) || src[i] != '=' {
+			attrs[name] = ""
+			continue
+		}
+		i++
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+
+		var value string
+		if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+			quote := src[i]
+			end := strings.IndexByte(src[i+1:], quote)
+			if end == -1 {
+				return "", nil, false, 0
+			}
+			value = src[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			valueStart := i
+			for i < len(src) && !isSpace(src[i]) && src[i] != '>' {
+				i++
+			}
+			value = src[valueStart:i]
+		}
+		attrs[name] = html.UnescapeString(value)
+		selfClosing = false
+	}
+
+	return "", nil, false, 0
+}
+
+// closeElement returns the parent of the nearest open element with the given name.
+// Stray end tags without a matching open element are ignored.
+func closeElement(current *node, name string) *node {
+	for n := current; n != nil && n.tag != "#root"; n = n.parent {
+		if n.tag == name {
+			return n.parent
+		}
+	}
+	return current
+}
+
+// appendText adds unescaped text to a node, merging adjacent text nodes.
+func appendText(parent *node, raw string) {
+	if raw == "" {
+		return
+	}
+	text := html.UnescapeString(raw)
+	if n := len(parent.children); n > 0 && parent.children[n-1].tag == "" {
+		parent.children[n-1].text += text
+		return
+	}
+	parent.children = append(parent.children, &node{text: text, parent: parent})
+}
+
+// find returns the first element in document order matching the predicate.
+func (n *node) find(match func(*node) bool) *node {
+	for _, child := range n.children {
+		if child.tag == "" {
+			continue
+		}
+		if match(child) {
+			return child
+		}
+		if found := child.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every element in document order matching the predicate.
+func (n *node) findAll(match func(*node) bool) []*node {
+	var result []*node
+	for _, child := range n.children {
+		if child.tag == "" {
+			continue
+		}
+		if match(child) {
+			result = append(result, child)
+		}
+		result = append(result, child.findAll(match)...)
+	}
+	return result
+}
+
+// textContent returns the concatenated text of a node and its descendants.
+func (n *node) textContent() string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.textContent())
+	}
+	return b.String()
+}
+
+// byTag returns a predicate matching elements with the given name.
+func byTag(tag string) func(*node) bool {
+	return func(n *node) bool { return n.tag == tag }
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,298 @@
+package clip
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// skippedElements hold page chrome or non-content markup.
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "iframe": true, "svg": true, "canvas": true, "select": true,
+	"textarea": true, "input": true, "title": true,
+}
+
+// blockElements start a new markdown block.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "body": true, "dd": true,
+	"details": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "hr": true, "html": true, "li": true, "main": true, "ol": true,
+	"p": true, "pre": true, "section": true, "summary": true, "table": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"tr": true, "ul": true, "#root": true,
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// converter renders a node tree as markdown.
+type converter struct {
+	base *url.URL // used to resolve relative links, may be nil
+}
+
+// toMarkdown converts the content of a node into markdown text.
+func (c converter) toMarkdown(n *node) string {
+	md := strings.Join(c.blocks(n), "\n\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(md, "\n\n"))
+}
+
+// blocks renders the children of a block container. Consecutive inline
+// children are grouped into a single paragraph.
+func (c converter) blocks(n *node) []string {
+	var result []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := strings.TrimSpace(collapseSpaces(inline.String())); text != "" {
+			result = append(result, text)
+		}
+		inline.Reset()
+	}
+
+	for _, child := range n.children {
+		if child.tag != "" && skippedElements[child.tag] {
+			continue
+		}
+		if child.tag == "" || !blockElements[child.tag] {
+			inline.WriteString(c.inline(child))
+			continue
+		}
+		flush()
+		if block := c.block(child); block != "" {
+			result = append(result, block)
+		}
+	}
+	flush()
+
+	return result
+}
+
+// block renders a single block element.
+func (c converter) block(n *node) string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.tag[1] - '0')
+		text := c.inlineChildren(n)
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+
+	case "p", "dt", "summary", "figcaption":
+		return c.inlineChildren(n)
+
+	case "hr":
+		return "---"
+
+	case "pre":
+		code := strings.Trim(n.textContent(), "\n")
+		return "```\n" + code + "\n```"
+
+	case "blockquote":
+		inner := strings.Join(c.blocks(n), "\n\n")
+		return prefixLines(inner, "> ")
+
+	case "ul", "ol":
+		return c.list(n)
+
+	case "table":
+		return c.table(n)
+
+	default:
+		return strings.Join(c.blocks(n), "\n\n")
+	}
+}
+
+// list renders an ordered or unordered list, indenting nested content.
+func (c converter) list(n *node) string {
+	var items []string
+	index := 1
+	for _, item := range n.findAll(byTag("li")) {
+		if nearestList(item) != n {
+			continue
+		}
+
+		marker := "- "
+		if n.tag == "ol" {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		content := strings.Join(c.blocks(item), "\n")
+		if content == "" {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// nearestList returns the closest list element containing a node.
+func nearestList(n *node) *node {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.tag == "ul" || p.tag == "ol" {
+			return p
+		}
+	}
+	return nil
+}
+
+// table renders a table as a markdown pipe table, using the first row as header.
+func (c converter) table(n *node) string {
+	var rows [][]string
+	for _, tr := range n.findAll(byTag("tr")) {
+		var cells []string
+		for _, cell := range tr.children {
+			if cell.tag == "td" || cell.tag == "th" {
+				cells = append(cells, strings.ReplaceAll(c.inlineChildren(cell), "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// inlineChildren renders the children of a node as a single line of inline markdown.
+func (c converter) inlineChildren(n *node) string {
+	var b strings.Builder
+	for _, child := range n.children {
+		if child.tag != "" && skippedElements[child.tag] {
+			continue
+		}
+		if child.tag != "" && blockElements[child.tag] {
+			b.WriteString(" " + c.inlineChildren(child) + " ")
+			continue
+		}
+		b.WriteString(c.inline(child))
+	}
+	return strings.TrimSpace(collapseSpaces(b.String()))
+}
+
+// inline renders an inline node.
+func (c converter) inline(n *node) string {
+	if n.tag == "" {
+		return whitespace.ReplaceAllString(n.text, " ")
+	}
+	if skippedElements[n.tag] {
+		return ""
+	}
+
+	switch n.tag {
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return wrap(c.inlineRaw(n), "**")
+	case "em", "i":
+		return wrap(c.inlineRaw(n), "*")
+	case "del", "s", "strike":
+		return wrap(c.inlineRaw(n), "~~")
+	case "code", "kbd", "samp":
+		return wrap(n.textContent(), "`")
+	case "a":
+		text := c.inlineChildren(n)
+		href := c.resolve(n.attrs["href"])
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case "img":
+		src := c.resolve(n.attrs["src"])
+		if src == "" {
+			return ""
+		}
+		return "![" + n.attrs["alt"] + "](" + src + ")"
+	default:
+		return c.inlineRaw(n)
+	}
+}
+
+// inlineRaw renders the children of an inline node without trimming.
+func (c converter) inlineRaw(n *node) string {
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+// resolve makes a link absolute against the page URL, when known.
+func (c converter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || c.base == nil {
+		return ref
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return c.base.ResolveReference(parsed).String()
+}
+
+// wrap surrounds non-empty text with a markdown marker, keeping surrounding
+// whitespace outside of the marker.
+func wrap(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// collapseSpaces collapses runs of spaces while keeping explicit line breaks.
+func collapseSpaces(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prefixLines adds a prefix to every line of a text.
+func prefixLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,125 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// writeTemp writes data to the temporary file of WriteFileAtomic.
+// Tests replace it to simulate a write that fails halfway.
+var writeTemp = func(f afero.File, data []byte) error {
+	_, err := f.Write(data)
+	return err
+}
+
+// maxSymlinks bounds the number of symbolic links followed by WriteFileAtomic.
+const maxSymlinks = 40
+
+// WriteFileAtomic writes data to a file so that the file always holds either its old
+// or its new content, even if the process is killed mid-write. The data is written to
+// a temporary file in the same directory, synced to disk and renamed over the target.
+// An existing file keeps its mode, and a symbolic link is followed so the link itself
+// is preserved.
+//
+// Parameters:
+//   - fsys: filesystem to write to
+//   - path: the file to write
+//   - data: the new content
+//   - perm: the mode of a new file
+//
+// Returns:
+//   - error: if the temporary file cannot be written, synced or renamed; the target is
+//     left untouched and the temporary file is removed
+func WriteFileAtomic(fsys afero.Fs, path string, data []byte, perm fs.FileMode) error {
+	path, err := resolveSymlinks(fsys, path)
+	if err != nil {
+		return err
+	}
+
+	info, err := fsys.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := afero.TempFile(fsys, dir, "."+name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	renamed := false
+	defer func() {
+		// Closing twice is harmless; the temporary file is kept only once renamed.
+		tmp.Close()
+		if !renamed {
+			fsys.Remove(tmp.Name())
+		}
+	}()
+
+	if err := writeTemp(tmp, data); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := fsys.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := fsys.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	renamed = true
+
+	syncDir(fsys, dir)
+	return nil
+}
+
+// resolveSymlinks follows symbolic links at path, on filesystems that support them.
+func resolveSymlinks(fsys afero.Fs, path string) (string, error) {
+	lstater, canLstat := fsys.(afero.Lstater)
+	reader, canRead := fsys.(afero.LinkReader)
+	if !canLstat || !canRead {
+		return path, nil
+	}
+
+	for i := 0; i < maxSymlinks; i++ {
+		info, _, err := lstater.LstatIfPossible(path)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		target, err := reader.ReadlinkIfPossible(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read link %s: %w", path, err)
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("too many links at %s", path)
+}
+
+// syncDir flushes a directory entry to disk so a completed rename survives a crash.
+// It is best effort, as some platforms cannot sync directories.
+func syncDir(fsys afero.Fs, dir string) {
+	d, err := fsys.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
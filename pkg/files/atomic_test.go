@@ -0,0 +1,153 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestWriteFileAtomic verifies that files are replaced with their mode, links are kept
+// and no temporary files are left behind.
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, dir string) string
+		wantMode os.FileMode
+	}{
+		{
+			name: "new file",
+			setup: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "new.md")
+			},
+			wantMode: 0640,
+		},
+		{
+			name: "existing file keeps its mode",
+			setup: func(t *testing.T, dir string) string {
+				path := filepath.Join(dir, "task.md")
+				if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, 0600); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			wantMode: 0600,
+		},
+		{
+			name: "symbolic link is followed",
+			setup: func(t *testing.T, dir string) string {
+				if err := os.WriteFile(filepath.Join(dir, "target.md"), []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				link := filepath.Join(dir, "link.md")
+				if err := os.Symlink("target.md", link); err != nil {
+					t.Skipf("symlinks not supported: %v", err)
+				}
+				return link
+			},
+			wantMode: 0644,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			path := tt.setup(t, dir)
+
+			if err := files.WriteFileAtomic(afero.NewOsFs(), path, []byte("new content"), 0640); err != nil {
+				t.Fatalf("WriteFileAtomic() error = %v", err)
+			}
+
+			testutil.AssertFileContent(t, path, "new content")
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tt.wantMode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), tt.wantMode)
+			}
+			if lstat, _ := os.Lstat(path); tt.name == "symbolic link is followed" && lstat.Mode()&os.ModeSymlink == 0 {
+				t.Error("symbolic link was replaced by a file")
+			}
+			assertNoTempFiles(t, dir)
+		})
+	}
+}
+
+// TestWriteFileAtomic_PartialWrite verifies that a write failing halfway never replaces
+// the original file.
+func TestWriteFileAtomic_PartialWrite(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	if err := afero.WriteFile(fsys, "/vault/task.md", []byte("good content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errKilled := errors.New("killed")
+	restore := files.SetWriteTemp(func(f afero.File, data []byte) error {
+		if _, err := f.Write(data[:len(data)/2]); err != nil {
+			return err
+		}
+		return errKilled
+	})
+	defer restore()
+
+	err := files.WriteFileAtomic(fsys, "/vault/task.md", []byte("replacement content"), 0644)
+	if !errors.Is(err, errKilled) {
+		t.Fatalf("WriteFileAtomic() error = %v, want %v", err, errKilled)
+	}
+
+	got, _ := afero.ReadFile(fsys, "/vault/task.md")
+	if string(got) != "good content" {
+		t.Errorf("content = %q, want the original content", got)
+	}
+	entries, _ := afero.ReadDir(fsys, "/vault")
+	if len(entries) != 1 {
+		t.Errorf("temporary file left behind: %d entries in /vault", len(entries))
+	}
+}
+
+// TestWriteFileAtomic_ReadOnly verifies that a read-only filesystem is never written.
+func TestWriteFileAtomic_ReadOnly(t *testing.T) {
+	base := afero.NewMemMapFs()
+	if err := afero.WriteFile(base, "/vault/task.md", []byte("good content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := files.WriteFileAtomic(afero.NewReadOnlyFs(base), "/vault/task.md", []byte("new"), 0644); err == nil {
+		t.Error("WriteFileAtomic() on a read-only filesystem expected error")
+	}
+
+	overlay := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(base), afero.NewMemMapFs())
+	if err := files.WriteFileAtomic(overlay, "/vault/task.md", []byte("preview"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() on an overlay error = %v", err)
+	}
+	if got, _ := afero.ReadFile(overlay, "/vault/task.md"); string(got) != "preview" {
+		t.Errorf("overlay content = %q, want %q", got, "preview")
+	}
+	if got, _ := afero.ReadFile(base, "/vault/task.md"); string(got) != "good content" {
+		t.Errorf("base content = %q, want the original content", got)
+	}
+}
+
+// assertNoTempFiles fails if WriteFileAtomic left a temporary file in a directory.
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left in %s", entry.Name(), dir)
+		}
+	}
+}
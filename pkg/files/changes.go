@@ -0,0 +1,234 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ChangeOp is the kind of a change made through a ChangeFs.
+type ChangeOp string
+
+const (
+	ChangeWrite  ChangeOp = "write"
+	ChangeMove   ChangeOp = "move"
+	ChangeDelete ChangeOp = "delete"
+	ChangeMkdir  ChangeOp = "mkdir"
+)
+
+// Change is a file or folder changed through a ChangeFs.
+type Change struct {
+	Op   ChangeOp `json:"op"`
+	Path string   `json:"path"`
+	To   string   `json:"to,omitempty"` // the destination of a move
+}
+
+// String describes the change for listings, e.g. "move Tasks/a.md -> Tasks/Completed/a.md".
+func (c Change) String() string {
+	if c.Op == ChangeMove {
+		return string(c.Op) + " " + c.Path + " -> " + c.To
+	}
+	return string(c.Op) + " " + c.Path
+}
+
+// ChangeFs is a filesystem that records the files and folders changed through it, so
+// that a run can report what it did. A file created and then renamed into place, as
+// WriteFileAtomic does, is recorded as a single write of its final path, and a file
+// created and removed again is not recorded at all. Changes of mode and times are not
+// recorded.
+type ChangeFs struct {
+	fs afero.Fs
+
+	mu      sync.Mutex
+	changes []Change
+	created map[string]bool // files that did not exist before being written through c
+}
+
+// NewChangeFs returns a filesystem recording the changes made to fsys.
+//
+// Parameters:
+//   - fsys: the underlying filesystem
+//
+// Returns:
+//   - *ChangeFs: the recording filesystem
+func NewChangeFs(fsys afero.Fs) *ChangeFs {
+	return &ChangeFs{fs: fsys, created: make(map[string]bool)}
+}
+
+// Changes returns the changes recorded so far, in the order they were made.
+func (c *ChangeFs) Changes() []Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Change(nil), c.changes...)
+}
+
+// exists reports whether path exists on the underlying filesystem.
+func (c *ChangeFs) exists(path string) bool {
+	_, err := c.fs.Stat(path)
+	return err == nil
+}
+
+// recordWrite records a write of path once. isNew marks a file created by the write.
+func (c *ChangeFs) recordWrite(path string, isNew bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path = filepath.Clean(path)
+	if isNew {
+		c.created[path] = true
+	}
+	for _, earlier := range c.changes {
+		if earlier.Op == ChangeWrite && earlier.Path == path {
+			return
+		}
+	}
+	c.changes = append(c.changes, Change{Op: ChangeWrite, Path: path})
+}
+
+// forget drops the write of a file created through c, reporting whether there was one.
+func (c *ChangeFs) forget(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path = filepath.Clean(path)
+	if !c.created[path] {
+		return false
+	}
+	delete(c.created, path)
+	for i, earlier := range c.changes {
+		if earlier.Op == ChangeWrite && earlier.Path == path {
+			c.changes = append(c.changes[:i], c.changes[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// record adds a change.
+func (c *ChangeFs) record(op ChangeOp, path, to string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	change := Change{Op: op, Path: filepath.Clean(path)}
+	if to != "" {
+		change.To = filepath.Clean(to)
+	}
+	c.changes = append(c.changes, change)
+}
+
+func (c *ChangeFs) Create(name string) (afero.File, error) {
+	isNew := !c.exists(name)
+	f, err := c.fs.Create(name)
+	if err == nil {
+		c.recordWrite(name, isNew)
+	}
+	return f, err
+}
+
+func (c *ChangeFs) Mkdir(name string, perm os.FileMode) error {
+	isNew := !c.exists(name)
+	err := c.fs.Mkdir(name, perm)
+	if err == nil && isNew {
+		c.record(ChangeMkdir, name, "")
+	}
+	return err
+}
+
+func (c *ChangeFs) MkdirAll(path string, perm os.FileMode) error {
+	isNew := !c.exists(path)
+	err := c.fs.MkdirAll(path, perm)
+	if err == nil && isNew {
+		c.record(ChangeMkdir, path, "")
+	}
+	return err
+}
+
+func (c *ChangeFs) Open(name string) (afero.File, error) {
+	return c.fs.Open(name)
+}
+
+func (c *ChangeFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		return c.fs.OpenFile(name, flag, perm)
+	}
+	isNew := !c.exists(name)
+	f, err := c.fs.OpenFile(name, flag, perm)
+	if err == nil {
+		c.recordWrite(name, isNew)
+	}
+	return f, err
+}
+
+func (c *ChangeFs) Remove(name string) error {
+	if err := c.fs.Remove(name); err != nil {
+		return err
+	}
+	if !c.forget(name) {
+		c.record(ChangeDelete, name, "")
+	}
+	return nil
+}
+
+func (c *ChangeFs) RemoveAll(path string) error {
+	existed := c.exists(path)
+	if err := c.fs.RemoveAll(path); err != nil {
+		return err
+	}
+	if !c.forget(path) && existed {
+		c.record(ChangeDelete, path, "")
+	}
+	return nil
+}
+
+func (c *ChangeFs) Rename(oldname, newname string) error {
+	isNew := !c.exists(newname)
+	if err := c.fs.Rename(oldname, newname); err != nil {
+		return err
+	}
+	if c.forget(oldname) {
+		c.recordWrite(newname, isNew)
+		return nil
+	}
+	c.record(ChangeMove, oldname, newname)
+	return nil
+}
+
+func (c *ChangeFs) Stat(name string) (os.FileInfo, error) {
+	return c.fs.Stat(name)
+}
+
+func (c *ChangeFs) Name() string {
+	return "ChangeFs"
+}
+
+func (c *ChangeFs) Chmod(name string, mode os.FileMode) error {
+	return c.fs.Chmod(name, mode)
+}
+
+func (c *ChangeFs) Chown(name string, uid, gid int) error {
+	return c.fs.Chown(name, uid, gid)
+}
+
+func (c *ChangeFs) Chtimes(name string, atime, mtime time.Time) error {
+	return c.fs.Chtimes(name, atime, mtime)
+}
+
+// LstatIfPossible implements afero.Lstater, falling back to Stat.
+func (c *ChangeFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if lstater, ok := c.fs.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	info, err := c.fs.Stat(name)
+	return info, false, err
+}
+
+// ReadlinkIfPossible implements afero.LinkReader.
+func (c *ChangeFs) ReadlinkIfPossible(name string) (string, error) {
+	if reader, ok := c.fs.(afero.LinkReader); ok {
+		return reader.ReadlinkIfPossible(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
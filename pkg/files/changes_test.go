@@ -0,0 +1,89 @@
+package files_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestChangeFs tests that changes are recorded once, with temporary files folded away.
+func TestChangeFs(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(fsys afero.Fs) error
+		want []files.Change
+	}{
+		{
+			name: "reads are not changes",
+			op: func(fsys afero.Fs) error {
+				_, err := afero.ReadFile(fsys, "/vault/Tasks/Buy milk.md")
+				return err
+			},
+		},
+		{
+			name: "atomic write of an existing file",
+			op: func(fsys afero.Fs) error {
+				return files.WriteFileAtomic(fsys, "/vault/Tasks/Buy milk.md", []byte("oat milk"), 0644)
+			},
+			want: []files.Change{{Op: files.ChangeWrite, Path: "/vault/Tasks/Buy milk.md"}},
+		},
+		{
+			name: "repeated writes",
+			op: func(fsys afero.Fs) error {
+				if err := afero.WriteFile(fsys, "/vault/Tasks/New.md", []byte("a"), 0644); err != nil {
+					return err
+				}
+				return afero.WriteFile(fsys, "/vault/Tasks/New.md", []byte("b"), 0644)
+			},
+			want: []files.Change{{Op: files.ChangeWrite, Path: "/vault/Tasks/New.md"}},
+		},
+		{
+			name: "created and removed",
+			op: func(fsys afero.Fs) error {
+				if err := afero.WriteFile(fsys, "/vault/Tasks/Scratch.md", []byte("a"), 0644); err != nil {
+					return err
+				}
+				return fsys.Remove("/vault/Tasks/Scratch.md")
+			},
+		},
+		{
+			name: "move, delete and mkdir",
+			op: func(fsys afero.Fs) error {
+				if err := fsys.MkdirAll("/vault/Tasks/Completed", 0755); err != nil {
+					return err
+				}
+				if err := fsys.MkdirAll("/vault/Tasks", 0755); err != nil {
+					return err
+				}
+				if err := fsys.Rename("/vault/Tasks/Buy milk.md", "/vault/Tasks/Completed/Buy milk.md"); err != nil {
+					return err
+				}
+				return fsys.Remove("/vault/Tasks/Old.md")
+			},
+			want: []files.Change{
+				{Op: files.ChangeMkdir, Path: "/vault/Tasks/Completed"},
+				{Op: files.ChangeMove, Path: "/vault/Tasks/Buy milk.md", To: "/vault/Tasks/Completed/Buy milk.md"},
+				{Op: files.ChangeDelete, Path: "/vault/Tasks/Old.md"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := files.NewChangeFs(testutil.CreateMemFs(t, map[string]string{
+				"/vault/Tasks/Buy milk.md": "milk",
+				"/vault/Tasks/Old.md":      "old",
+			}))
+
+			if err := tt.op(fsys); err != nil {
+				t.Fatalf("operation failed: %v", err)
+			}
+			if got := fsys.Changes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ErrOutsideRoot is returned by a ConfinedFs for paths outside its root directory.
+var ErrOutsideRoot = errors.New("path is outside the vault")
+
+// ConfinedFs is a filesystem that rejects every path outside a root directory, so that
+// a crafted title or link can never read or write files beyond the vault. Paths keep
+// their usual form; relative paths are resolved against the working directory.
+//
+// The check is lexical: a symbolic link inside the root that points outside it is
+// rejected when WriteFileAtomic follows it, but not when it is opened directly.
+type ConfinedFs struct {
+	fs   afero.Fs
+	root string
+}
+
+// NewConfinedFs returns a filesystem confined to root.
+//
+// Parameters:
+//   - fsys: the underlying filesystem
+//   - root: the directory all paths must stay in
+//
+// Returns:
+//   - *ConfinedFs: the confined filesystem
+func NewConfinedFs(fsys afero.Fs, root string) *ConfinedFs {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &ConfinedFs{fs: fsys, root: filepath.Clean(root)}
+}
+
+// Within reports whether path is the root directory or inside it.
+//
+// Parameters:
+//   - root: the containing directory
+//   - path: the path to check
+//
+// Returns:
+//   - bool: true if path does not leave root
+func Within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// check returns an error if name is outside the root.
+func (c *ConfinedFs) check(op, name string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if !Within(c.root, abs) {
+		return &os.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+	}
+	return nil
+}
+
+func (c *ConfinedFs) Create(name string) (afero.File, error) {
+	if err := c.check("create", name); err != nil {
+		return nil, err
+	}
+	return c.fs.Create(name)
+}
+
+func (c *ConfinedFs) Mkdir(name string, perm os.FileMode) error {
+	if err := c.check("mkdir", name); err != nil {
+		return err
+	}
+	return c.fs.Mkdir(name, perm)
+}
+
+func (c *ConfinedFs) MkdirAll(path string, perm os.FileMode) error {
+	if err := c.check("mkdir", path); err != nil {
+		return err
+	}
+	return c.fs.MkdirAll(path, perm)
+}
+
+func (c *ConfinedFs) Open(name string) (afero.File, error) {
+	if err := c.check("open", name); err != nil {
+		return nil, err
+	}
+	return c.fs.Open(name)
+}
+
+func (c *ConfinedFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := c.check("open", name); err != nil {
+		return nil, err
+	}
+	return c.fs.OpenFile(name, flag, perm)
+}
+
+func (c *ConfinedFs) Remove(name string) error {
+	if err := c.check("remove", name); err != nil {
+		return err
+	}
+	return c.fs.Remove(name)
+}
+
+func (c *ConfinedFs) RemoveAll(path string) error {
+	if err := c.check("remove", path); err != nil {
+		return err
+	}
+	return c.fs.RemoveAll(path)
+}
+
+func (c *ConfinedFs) Rename(oldname, newname string) error {
+	if err := c.check("rename", oldname); err != nil {
+		return err
+	}
+	if err := c.check("rename", newname); err != nil {
+		return err
+	}
+	return c.fs.Rename(oldname, newname)
+}
+
+func (c *ConfinedFs) Stat(name string) (os.FileInfo, error) {
+	if err := c.check("stat", name); err != nil {
+		return nil, err
+	}
+	return c.fs.Stat(name)
+}
+
+func (c *ConfinedFs) Name() string {
+	return "ConfinedFs"
+}
+
+func (c *ConfinedFs) Chmod(name string, mode os.FileMode) error {
+	if err := c.check("chmod", name); err != nil {
+		return err
+	}
+	return c.fs.Chmod(name, mode)
+}
+
+func (c *ConfinedFs) Chown(name string, uid, gid int) error {
+	if err := c.check("chown", name); err != nil {
+		return err
+	}
+	return c.fs.Chown(name, uid, gid)
+}
+
+func (c *ConfinedFs) Chtimes(name string, atime, mtime time.Time) error {
+	if err := c.check("chtimes", name); err != nil {
+		return err
+	}
+	return c.fs.Chtimes(name, atime, mtime)
+}
+
+// LstatIfPossible implements afero.Lstater, falling back to Stat.
+func (c *ConfinedFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if err := c.check("lstat", name); err != nil {
+		return nil, false, err
+	}
+	if lstater, ok := c.fs.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	info, err := c.fs.Stat(name)
+	return info, false, err
+}
+
+// ReadlinkIfPossible implements afero.LinkReader.
+func (c *ConfinedFs) ReadlinkIfPossible(name string) (string, error) {
+	if err := c.check("readlink", name); err != nil {
+		return "", err
+	}
+	if reader, ok := c.fs.(afero.LinkReader); ok {
+		return reader.ReadlinkIfPossible(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
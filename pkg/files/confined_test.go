@@ -0,0 +1,74 @@
+package files_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestConfinedFs tests that paths outside the vault are rejected.
+func TestConfinedFs(t *testing.T) {
+	base := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Tasks/Buy milk.md": "milk",
+		"/secrets/passwd":          "secret",
+	})
+	fsys := files.NewConfinedFs(base, "/vault")
+
+	tests := []struct {
+		name    string
+		op      func() error
+		allowed bool
+	}{
+		{
+			name:    "read inside the vault",
+			op:      func() error { _, err := afero.ReadFile(fsys, "/vault/Tasks/Buy milk.md"); return err },
+			allowed: true,
+		},
+		{
+			name:    "write inside the vault",
+			op:      func() error { return afero.WriteFile(fsys, "/vault/Tasks/New.md", []byte("new"), 0644) },
+			allowed: true,
+		},
+		{
+			name: "read outside the vault",
+			op:   func() error { _, err := afero.ReadFile(fsys, "/secrets/passwd"); return err },
+		},
+		{
+			name: "traversal out of the vault",
+			op:   func() error { _, err := fsys.Stat("/vault/Tasks/../../secrets/passwd"); return err },
+		},
+		{
+			name: "sibling with the vault as prefix",
+			op:   func() error { return fsys.MkdirAll("/vault-other", 0755) },
+		},
+		{
+			name: "move out of the vault",
+			op:   func() error { return fsys.Rename("/vault/Tasks/Buy milk.md", "/secrets/milk.md") },
+		},
+		{
+			name: "atomic write out of the vault",
+			op:   func() error { return files.WriteFileAtomic(fsys, "/secrets/passwd", []byte("x"), 0644) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.op()
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("error = %v, want none", err)
+				}
+				return
+			}
+			if !errors.Is(err, files.ErrOutsideRoot) {
+				t.Errorf("error = %v, want %v", err, files.ErrOutsideRoot)
+			}
+		})
+	}
+
+	testutil.AssertFsFileContent(t, base, "/secrets/passwd", "secret")
+	testutil.AssertFsFileContent(t, base, "/vault/Tasks/Buy milk.md", "milk")
+}
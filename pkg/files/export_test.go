@@ -0,0 +1,11 @@
+package files
+
+import "github.com/spf13/afero"
+
+// SetWriteTemp replaces the function writing the temporary file of WriteFileAtomic,
+// returning a function that restores it.
+func SetWriteTemp(write func(f afero.File, data []byte) error) (restore func()) {
+	previous := writeTemp
+	writeTemp = write
+	return func() { writeTemp = previous }
+}
@@ -0,0 +1,105 @@
+package files
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MarkdownExt is the extension of note files.
+const MarkdownExt = ".md"
+
+// unsafeNameChars are the characters escaped in file names: path separators, characters
+// reserved on Windows or by Obsidian, and the escape character itself.
+const unsafeNameChars = `/\:*?"<>|%`
+
+// TitleToName maps a title to a file name stem that is safe on every platform and cannot
+// leave its directory. The title is normalized to Unicode NFC, and unsafe characters,
+// control characters, a leading dot and trailing dots or spaces are escaped as %XX.
+// The mapping is reversed by NameToTitle.
+//
+// Parameters:
+//   - title: the title to map
+//
+// Returns:
+//   - string: the file name stem, without extension
+func TitleToName(title string) string {
+	title = norm.NFC.String(title)
+
+	var b strings.Builder
+	last := len(title) - len(strings.TrimRight(title, ". "))
+	for i, r := range title {
+		switch {
+		case r < 0x20, r == 0x7f, strings.ContainsRune(unsafeNameChars, r),
+			i == 0 && r == '.',
+			i >= len(title)-last:
+			fmt.Fprintf(&b, "%%%02X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NameToTitle reverses TitleToName. Sequences that are not valid %XX escapes are kept
+// as they are, so hand-written names containing "%" still map to a title.
+//
+// Parameters:
+//   - name: the file name stem, without extension
+//
+// Returns:
+//   - string: the title, normalized to Unicode NFC
+func NameToTitle(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '%' && i+2 < len(name) && isHex(name[i+1]) && isHex(name[i+2]) {
+			b.WriteByte(unhex(name[i+1])<<4 | unhex(name[i+2]))
+			i += 2
+			continue
+		}
+		b.WriteByte(name[i])
+	}
+	return norm.NFC.String(b.String())
+}
+
+// TitleToFilename returns the markdown file name of a title.
+//
+// Parameters:
+//   - title: the title of the note
+//
+// Returns:
+//   - string: the file name, with the markdown extension
+func TitleToFilename(title string) string {
+	return TitleToName(title) + MarkdownExt
+}
+
+// FilenameToTitle returns the title of a file name, see NameToTitle.
+//
+// Parameters:
+//   - filename: the file name or path
+//
+// Returns:
+//   - string: the title
+func FilenameToTitle(filename string) string {
+	base := filepath.Base(filename)
+	return NameToTitle(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// unhex returns the value of a hexadecimal digit.
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
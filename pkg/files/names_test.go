@@ -0,0 +1,108 @@
+package files_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TestTitleToName tests that unsafe titles map to names that stay in their directory
+// and map back to the same title.
+func TestTitleToName(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "plain title", title: "Buy milk", want: "Buy milk"},
+		{name: "path separators", title: "Read a/b or c\\d", want: "Read a%2Fb or c%5Cd"},
+		{name: "reserved characters", title: `Why? 10:30 <now> "x" *|*`, want: `Why%3F 10%3A30 %3Cnow%3E %22x%22 %2A%7C%2A`},
+		{name: "escape character", title: "100% done", want: "100%25 done"},
+		{name: "leading dot", title: ".hidden", want: "%2Ehidden"},
+		{name: "parent directory", title: "..", want: "%2E%2E"},
+		{name: "traversal", title: "../../etc/passwd", want: "%2E.%2F..%2Fetc%2Fpasswd"},
+		{name: "trailing dots and spaces", title: "Wait... ", want: "Wait%2E%2E%2E%20"},
+		{name: "control characters", title: "a\tb\nc", want: "a%09b%0Ac"},
+		{name: "unicode is normalized", title: norm.NFD.String("Café"), want: "Café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := files.TitleToName(tt.title)
+			if got != tt.want {
+				t.Errorf("TitleToName(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+
+			path := files.FilePath{Dir: "/vault/Tasks", Name: files.TitleToFilename(tt.title)}
+			if filepath.Dir(path.FullPath()) != "/vault/Tasks" {
+				t.Errorf("%s leaves its directory", path.FullPath())
+			}
+
+			if back := files.NameToTitle(got); back != norm.NFC.String(tt.title) {
+				t.Errorf("NameToTitle(%q) = %q, want %q", got, back, tt.title)
+			}
+		})
+	}
+}
+
+// TestFilenameToTitle tests titles read from file names, including hand-written ones.
+func TestFilenameToTitle(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "/vault/Tasks/Buy milk.md", want: "Buy milk"},
+		{filename: "Read a%2Fb.md", want: "Read a/b"},
+		{filename: "50% off.md", want: "50% off"},
+		{filename: "ends with %.md", want: "ends with %"},
+		{filename: norm.NFD.String("Café.md"), want: "Café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := files.FilenameToTitle(tt.filename); got != tt.want {
+				t.Errorf("FilenameToTitle(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestResolve tests that NFD file names written on macOS are found from NFC titles, and
+// that escaped names find files named with the unescaped title.
+func TestResolve(t *testing.T) {
+	nfd := norm.NFD.String("Café.md")
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/" + nfd:       "decomposed",
+		"/vault/Buy milk.md":  "milk",
+		"/vault/50% off.md":   "sale",
+		"/vault/What now?.md": "question",
+	})
+
+	tests := []struct {
+		name       string
+		file       string
+		wantName   string
+		wantExists bool
+	}{
+		{name: "exact name", file: "Buy milk.md", wantName: "Buy milk.md", wantExists: true},
+		{name: "normalized name", file: "Café.md", wantName: nfd, wantExists: true},
+		{name: "unescaped percent sign", file: "50%25 off.md", wantName: "50% off.md", wantExists: true},
+		{name: "unescaped reserved character", file: "What now%3F.md", wantName: "What now?.md", wantExists: true},
+		{name: "missing file", file: "Other.md", wantName: "Other.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists, err := files.Resolve(fsys, files.FilePath{Dir: "/vault", Name: tt.file})
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got.Name != tt.wantName || exists != tt.wantExists {
+				t.Errorf("Resolve() = %q, %v, want %q, %v", got.Name, exists, tt.wantName, tt.wantExists)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package files
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// PreviewFs is a filesystem that keeps every change in memory and never writes to the
+// filesystem below it, so that a run can be previewed on a real vault. Reads see the
+// underlying files until they are changed.
+//
+// It is built on afero's copy-on-write filesystem, which refuses to rename or remove
+// files it did not write. PreviewFs allows both: a renamed file is copied into memory,
+// and removed files are hidden from every later read.
+type PreviewFs struct {
+	base  afero.Fs
+	layer afero.Fs
+	cow   afero.Fs
+
+	mu      sync.Mutex
+	removed map[string]bool // underlying paths removed through the preview
+}
+
+// NewPreviewFs returns a filesystem previewing changes to fsys in memory.
+//
+// Parameters:
+//   - fsys: the underlying filesystem, only ever read from
+//
+// Returns:
+//   - *PreviewFs: the preview filesystem
+func NewPreviewFs(fsys afero.Fs) *PreviewFs {
+	base := afero.NewReadOnlyFs(fsys)
+	layer := afero.NewMemMapFs()
+	return &PreviewFs{
+		base:    base,
+		layer:   layer,
+		cow:     afero.NewCopyOnWriteFs(base, layer),
+		removed: make(map[string]bool),
+	}
+}
+
+// hidden reports whether name, or a folder holding it, was removed through p.
+func (p *PreviewFs) hidden(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for path := filepath.Clean(name); ; path = filepath.Dir(path) {
+		if p.removed[path] {
+			return true
+		}
+		if parent := filepath.Dir(path); parent == path {
+			return false
+		}
+	}
+}
+
+// hide removes name from the in-memory layer and hides the underlying file, if any.
+func (p *PreviewFs) hide(name string) error {
+	if err := p.layer.RemoveAll(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := p.base.Stat(name); err == nil {
+		p.mu.Lock()
+		p.removed[filepath.Clean(name)] = true
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// unhide makes name writable again after a removal. The underlying file stays hidden
+// behind the new one, as do the files of an underlying folder.
+func (p *PreviewFs) unhide(name string) bool {
+	name = filepath.Clean(name)
+	p.mu.Lock()
+	wasRemoved := p.removed[name]
+	delete(p.removed, name)
+	p.mu.Unlock()
+	if !wasRemoved {
+		return false
+	}
+
+	names, err := afero.ReadDir(p.base, name)
+	if err != nil {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, info := range names {
+		p.removed[filepath.Join(name, info.Name())] = true
+	}
+	return true
+}
+
+func notExist(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+func (p *PreviewFs) Create(name string) (afero.File, error) {
+	return p.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0666)
+}
+
+func (p *PreviewFs) Mkdir(name string, perm os.FileMode) error {
+	if _, err := p.Stat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	p.unhide(name)
+	return p.layer.MkdirAll(name, perm)
+}
+
+func (p *PreviewFs) MkdirAll(path string, perm os.FileMode) error {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		p.unhide(dir)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	if info, err := p.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	return p.layer.MkdirAll(path, perm)
+}
+
+func (p *PreviewFs) Open(name string) (afero.File, error) {
+	return p.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (p *PreviewFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		if p.hidden(name) {
+			return nil, notExist("open", name)
+		}
+		// Open, unlike OpenFile, merges folders present in both layers.
+		f, err := p.cow.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		return &previewFile{File: f, fs: p}, nil
+	}
+
+	if p.hidden(filepath.Dir(name)) {
+		return nil, notExist("open", name)
+	}
+	if p.unhide(name) {
+		// The underlying file was removed: start from an empty file rather than its content.
+		if flag&os.O_CREATE == 0 {
+			return nil, notExist("open", name)
+		}
+		if err := p.layer.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return nil, err
+		}
+		return p.layer.OpenFile(name, flag|os.O_TRUNC, perm)
+	}
+	return p.cow.OpenFile(name, flag, perm)
+}
+
+func (p *PreviewFs) Remove(name string) error {
+	info, err := p.Stat(name)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if info.IsDir() {
+		entries, err := afero.ReadDir(p, name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	return p.hide(name)
+}
+
+func (p *PreviewFs) RemoveAll(path string) error {
+	if _, err := p.Stat(path); err != nil {
+		return nil
+	}
+	return p.hide(path)
+}
+
+// Rename copies oldname into memory under newname and removes oldname. Files keep
+// their mode and modification time.
+func (p *PreviewFs) Rename(oldname, newname string) error {
+	if _, err := p.Stat(oldname); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	if _, err := p.Stat(filepath.Dir(newname)); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+
+	err := afero.Walk(p, oldname, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(oldname, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(newname, rel)
+
+		if info.IsDir() {
+			if err := p.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		} else if err := p.copyFile(path, target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return p.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return p.RemoveAll(oldname)
+}
+
+// copyFile copies the content of a file within the preview.
+func (p *PreviewFs) copyFile(src, dst string, perm os.FileMode) error {
+	in, err := p.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := p.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (p *PreviewFs) Stat(name string) (os.FileInfo, error) {
+	if p.hidden(name) {
+		return nil, notExist("stat", name)
+	}
+	return p.cow.Stat(name)
+}
+
+func (p *PreviewFs) Name() string {
+	return "PreviewFs"
+}
+
+func (p *PreviewFs) Chmod(name string, mode os.FileMode) error {
+	if p.hidden(name) {
+		return notExist("chmod", name)
+	}
+	return p.cow.Chmod(name, mode)
+}
+
+func (p *PreviewFs) Chown(name string, uid, gid int) error {
+	if p.hidden(name) {
+		return notExist("chown", name)
+	}
+	return p.cow.Chown(name, uid, gid)
+}
+
+func (p *PreviewFs) Chtimes(name string, atime, mtime time.Time) error {
+	if p.hidden(name) {
+		return notExist("chtimes", name)
+	}
+	return p.cow.Chtimes(name, atime, mtime)
+}
+
+// LstatIfPossible implements afero.Lstater.
+func (p *PreviewFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if p.hidden(name) {
+		return nil, false, notExist("lstat", name)
+	}
+	return p.cow.(afero.Lstater).LstatIfPossible(name)
+}
+
+// previewFile is a file opened for reading through a PreviewFs. Folder listings leave
+// out the files removed through the preview.
+type previewFile struct {
+	afero.File
+	fs *PreviewFs
+}
+
+func (f *previewFile) Readdir(count int) ([]os.FileInfo, error) {
+	for {
+		infos, err := f.File.Readdir(count)
+		visible := infos[:0]
+		for _, info := range infos {
+			if !f.fs.hidden(filepath.Join(f.Name(), info.Name())) {
+				visible = append(visible, info)
+			}
+		}
+		if len(visible) > 0 || len(infos) == 0 || count <= 0 || err != nil {
+			if count <= 0 {
+				sort.Slice(visible, func(i, j int) bool { return visible[i].Name() < visible[j].Name() })
+			}
+			return visible, err
+		}
+	}
+}
+
+func (f *previewFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
@@ -0,0 +1,141 @@
+package files_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestPreviewFs tests that changes are visible through the preview and never reach the
+// underlying filesystem.
+func TestPreviewFs(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(fsys afero.Fs) error
+		want map[string]string // files visible through the preview, with their content
+	}{
+		{
+			name: "reads see the underlying files",
+			op:   func(fsys afero.Fs) error { return nil },
+			want: map[string]string{"/vault/Tasks/Buy milk.md": "milk", "/vault/Tasks/Old.md": "old"},
+		},
+		{
+			name: "write",
+			op: func(fsys afero.Fs) error {
+				return files.WriteFileAtomic(fsys, "/vault/Tasks/Buy milk.md", []byte("oat milk"), 0644)
+			},
+			want: map[string]string{"/vault/Tasks/Buy milk.md": "oat milk", "/vault/Tasks/Old.md": "old"},
+		},
+		{
+			name: "move into a new folder",
+			op: func(fsys afero.Fs) error {
+				if err := fsys.MkdirAll("/vault/Tasks/Completed", 0755); err != nil {
+					return err
+				}
+				return fsys.Rename("/vault/Tasks/Buy milk.md", "/vault/Tasks/Completed/Buy milk.md")
+			},
+			want: map[string]string{"/vault/Tasks/Completed/Buy milk.md": "milk", "/vault/Tasks/Old.md": "old"},
+		},
+		{
+			name: "remove",
+			op: func(fsys afero.Fs) error {
+				return fsys.Remove("/vault/Tasks/Old.md")
+			},
+			want: map[string]string{"/vault/Tasks/Buy milk.md": "milk"},
+		},
+		{
+			name: "removed file written again starts empty",
+			op: func(fsys afero.Fs) error {
+				if err := fsys.Remove("/vault/Tasks/Old.md"); err != nil {
+					return err
+				}
+				f, err := fsys.OpenFile("/vault/Tasks/Old.md", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+				if err != nil {
+					return err
+				}
+				if _, err := f.WriteString("new"); err != nil {
+					return err
+				}
+				return f.Close()
+			},
+			want: map[string]string{"/vault/Tasks/Buy milk.md": "milk", "/vault/Tasks/Old.md": "new"},
+		},
+		{
+			name: "remove a folder",
+			op: func(fsys afero.Fs) error {
+				return fsys.RemoveAll("/vault/Tasks")
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Tasks/Buy milk.md": "milk",
+				"/vault/Tasks/Old.md":      "old",
+			})
+			preview := files.NewPreviewFs(base)
+
+			if err := tt.op(preview); err != nil {
+				t.Fatalf("operation failed: %v", err)
+			}
+			if got := readTree(t, preview); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preview = %v, want %v", got, tt.want)
+			}
+			want := map[string]string{"/vault/Tasks/Buy milk.md": "milk", "/vault/Tasks/Old.md": "old"}
+			if got := readTree(t, base); !reflect.DeepEqual(got, want) {
+				t.Errorf("underlying filesystem changed to %v", got)
+			}
+		})
+	}
+}
+
+// TestPreviewFs_RenameKeepsTimes tests that a moved file keeps its modification time,
+// which MoveKeepNewer relies on.
+func TestPreviewFs_RenameKeepsTimes(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := testutil.CreateMemFs(t, map[string]string{"/vault/a.md": "a"})
+	if err := base.Chtimes("/vault/a.md", modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	preview := files.NewPreviewFs(base)
+	if err := preview.Rename("/vault/a.md", "/vault/b.md"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+
+	info, err := preview.Stat("/vault/b.md")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("ModTime() = %v, want %v", info.ModTime(), modTime)
+	}
+	if _, err := preview.Stat("/vault/a.md"); !os.IsNotExist(err) {
+		t.Errorf("Stat() of the moved file error = %v, want not exist", err)
+	}
+}
+
+// readTree returns the content of every file under /vault.
+func readTree(t *testing.T, fsys afero.Fs) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	err := afero.Walk(fsys, "/vault", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := afero.ReadFile(fsys, path)
+		tree[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", fsys.Name(), err)
+	}
+	return tree
+}
@@ -1,9 +1,16 @@
 package files
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
 	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/afero"
+	"golang.org/x/text/unicode/norm"
 )
 
 // FilePath represents a file path.
@@ -23,57 +30,241 @@ func (f FilePath) FullPath() string {
 	return filepath.Join(f.Dir, f.Name)
 }
 
-// FileExists checks if a file exists at the specified path.
+// FileExists checks if a file exists at the specified path. Names are compared in
+// Unicode NFC form, see Resolve.
 //
 // Parameters:
+//   - fsys: filesystem to check.
 //   - path: path to the file to check.
 //
 // Returns:
 //   - bool: true if the file exists, false otherwise.
-func FileExists(path FilePath) (bool, error) {
-	entries, err := os.ReadDir(path.Dir)
+func FileExists(fsys afero.Fs, path FilePath) (bool, error) {
+	_, exists, err := Resolve(fsys, path)
+	return exists, err
+}
+
+// Resolve finds the directory entry of a file. An exact name match wins; otherwise names
+// are compared in Unicode NFC form, so a file written with an NFD name, as macOS does,
+// matches its NFC title. A name escaped by TitleToName also matches a file still holding
+// the unescaped title, as files named before titles were escaped do.
+//
+// Parameters:
+//   - fsys: filesystem to check.
+//   - path: path to the file to find.
+//
+// Returns:
+//   - FilePath: the path of the existing file, or path unchanged if there is none.
+//   - bool: true if the file exists.
+//   - error: if the directory cannot be read.
+func Resolve(fsys afero.Fs, path FilePath) (FilePath, bool, error) {
+	entries, err := afero.ReadDir(fsys, path.Dir)
 	if err != nil {
-		return false, fmt.Errorf("failed to read directory: %w", err)
+		return path, false, fmt.Errorf("failed to read directory: %w", err)
 	}
 
+	normalized := norm.NFC.String(path.Name)
+	ext := filepath.Ext(path.Name)
+	unescaped := NameToTitle(strings.TrimSuffix(path.Name, ext)) + ext
+	found, fallback := FilePath{}, FilePath{}
 	for _, entry := range entries {
-		if entry.Name() == path.Name {
-			return true, nil
+		name := norm.NFC.String(entry.Name())
+		switch {
+		case entry.Name() == path.Name:
+			return path, true, nil
+		case found.Name == "" && name == normalized:
+			found = FilePath{Dir: path.Dir, Name: entry.Name()}
+		case fallback.Name == "" && name == unescaped:
+			fallback = FilePath{Dir: path.Dir, Name: entry.Name()}
 		}
 	}
-	return false, nil
+	switch {
+	case found.Name != "":
+		return found, true, nil
+	case fallback.Name != "":
+		return fallback, true, nil
+	}
+	return path, false, nil
+}
+
+// ErrSourceOlder is returned by MoveFile under MoveKeepNewer when the source is older
+// than the destination. The source is left in place, so that the caller can delete it
+// the way it deletes other files, such as through the vault trash.
+var ErrSourceOlder = errors.New("source is older than the destination")
+
+// MovePolicy defines what MoveFile does when the destination file already exists.
+type MovePolicy string
+
+const (
+	// MoveError refuses to move over an existing file.
+	MoveError MovePolicy = "error"
+	// MoveSuffix moves the file next to the existing one with a numeric suffix.
+	MoveSuffix MovePolicy = "suffix"
+	// MoveKeepNewer keeps whichever file was modified last: a newer source replaces the
+	// destination, and an older source is left in place for the caller to delete, see
+	// ErrSourceOlder.
+	MoveKeepNewer MovePolicy = "keep_newer"
+)
+
+// ParseMovePolicy converts a configuration value into a MovePolicy.
+// An empty value defaults to MoveError.
+//
+// Parameters:
+//   - value: policy name as written in the configuration
+//
+// Returns:
+//   - MovePolicy: the parsed policy
+//   - error: if the value is not a known policy
+func ParseMovePolicy(value string) (MovePolicy, error) {
+	switch policy := MovePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return MoveError, nil
+	case MoveError, MoveSuffix, MoveKeepNewer:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown move policy %q", value)
+	}
 }
 
-// MoveFile moves a file from source to destination path. Uses os.Rename which is atomic on POSIX systems
+// MoveFile moves a file from source to destination path. Uses Rename which is atomic on POSIX
+// systems, and falls back to copying, syncing and deleting the source when the paths are on
+// different devices. An existing destination is handled according to policy.
 //
 // Parameters:
+//   - fsys: filesystem holding both paths
 //   - src: source file path
 //   - dest: destination file path
+//   - policy: how to handle an existing destination file
 //
-// Returns error if:
-//   - source file doesn't exist
-//   - directory read fails
-//   - rename operation fails
-func MoveFile(src, dest FilePath) error {
-	exists, err := FileExists(src)
+// Returns:
+//   - FilePath: where the file ended up, which differs from dest with MoveSuffix
+//   - error: if the source doesn't exist, a directory read fails, the destination
+//     exists under MoveError, or the move fails; ErrSourceOlder, with the destination
+//     path, if an older source was not moved under MoveKeepNewer
+func MoveFile(fsys afero.Fs, src, dest FilePath, policy MovePolicy) (FilePath, error) {
+	src, exists, err := Resolve(fsys, src)
 	if err != nil {
-		return err
+		return FilePath{}, err
 	}
 	if !exists {
-		return fmt.Errorf("file %s not found in %s", src.Name, src.Dir)
+		return FilePath{}, fmt.Errorf("file %s not found in %s", src.Name, src.Dir)
+	}
+
+	dest, exists, err = Resolve(fsys, dest)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return FilePath{}, err
 	}
 
-	return os.Rename(src.FullPath(), dest.FullPath())
+	switch {
+	case !exists:
+	case src.FullPath() == dest.FullPath():
+		return dest, nil
+	default:
+		switch policy {
+		case MoveError:
+			return FilePath{}, fmt.Errorf("cannot move %s: %s %w", src.Name, dest.FullPath(), fs.ErrExist)
+		case MoveSuffix:
+			dest, err = NextAvailableName(fsys, dest)
+			if err != nil {
+				return FilePath{}, err
+			}
+		case MoveKeepNewer:
+			srcInfo, err := fsys.Stat(src.FullPath())
+			if err != nil {
+				return FilePath{}, fmt.Errorf("failed to stat %s: %w", src.FullPath(), err)
+			}
+			destInfo, err := fsys.Stat(dest.FullPath())
+			if err != nil {
+				return FilePath{}, fmt.Errorf("failed to stat %s: %w", dest.FullPath(), err)
+			}
+			if srcInfo.ModTime().Before(destInfo.ModTime()) {
+				return dest, fmt.Errorf("cannot move %s: %w", src.FullPath(), ErrSourceOlder)
+			}
+		default:
+			return FilePath{}, fmt.Errorf("unknown move policy %q", policy)
+		}
+	}
+
+	err = fsys.Rename(src.FullPath(), dest.FullPath())
+	if errors.Is(err, syscall.EXDEV) {
+		err = moveAcrossDevices(fsys, src.FullPath(), dest.FullPath())
+	}
+	if err != nil {
+		return FilePath{}, err
+	}
+	return dest, nil
+}
+
+// moveAcrossDevices moves a file that cannot be renamed because the destination is on
+// another device. The file is copied next to the destination, synced and renamed into
+// place, so the destination is never left half written, and only then is the source removed.
+func moveAcrossDevices(fsys afero.Fs, src, dest string) error {
+	in, err := fsys.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+
+	dir, name := filepath.Split(dest)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := afero.TempFile(fsys, dir, "."+name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	renamed := false
+	defer func() {
+		// Closing again after Chtimes would reset the modification time on some filesystems.
+		if !renamed {
+			tmp.Close()
+			fsys.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := fsys.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	// Keep the modification time, which MoveKeepNewer relies on.
+	if err := fsys.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set file times: %w", err)
+	}
+	if err := fsys.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	renamed = true
+	syncDir(fsys, dir)
+
+	if err := fsys.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove %s after copying it: %w", src, err)
+	}
+	return nil
 }
 
 // DeleteFile removes a file at the specified path.
 //
 // Parameters:
+//   - fsys: filesystem holding the file.
 //   - src: path to the file to delete.
 //
 // Returns error if file doesn't exist or deletion fails.
-func DeleteFile(src FilePath) error {
-	exists, err := FileExists(src)
+func DeleteFile(fsys afero.Fs, src FilePath) error {
+	src, exists, err := Resolve(fsys, src)
 	if err != nil {
 		return err
 	}
@@ -81,5 +272,36 @@ func DeleteFile(src FilePath) error {
 		return fmt.Errorf("file %s not found in %s", src.Name, src.Dir)
 	}
 
-	return os.Remove(src.FullPath())
+	return fsys.Remove(src.FullPath())
+}
+
+// NextAvailableName returns a file path in the same directory that does not collide with an
+// existing file. The original name is returned unchanged when it is free, otherwise a numeric
+// suffix is added before the extension ("note.md" -> "note (2).md").
+//
+// Parameters:
+//   - fsys: filesystem to check.
+//   - path: desired file path.
+//
+// Returns:
+//   - FilePath: the first free file path.
+//   - error: if the directory cannot be read.
+func NextAvailableName(fsys afero.Fs, path FilePath) (FilePath, error) {
+	ext := filepath.Ext(path.Name)
+	stem := strings.TrimSuffix(path.Name, ext)
+
+	candidate := path
+	for i := 2; ; i++ {
+		exists, err := FileExists(fsys, candidate)
+		if err != nil {
+			return FilePath{}, err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = FilePath{
+			Dir:  path.Dir,
+			Name: fmt.Sprintf("%s (%d)%s", stem, i, ext),
+		}
+	}
 }
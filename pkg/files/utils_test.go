@@ -1,12 +1,16 @@
 package files_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/avivSarig/cerebgo/pkg/files"
 	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
 )
 
 // TestFilePath_FullPath tests the FullPath method of FilePath struct.
@@ -98,7 +102,7 @@ func TestFileExists(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := files.FileExists(tt.path)
+			got, err := files.FileExists(afero.NewOsFs(), tt.path)
 
 			// Check error expectation
 			if (err != nil) != tt.wantErr {
@@ -163,7 +167,7 @@ func TestMoveFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := files.MoveFile(tt.src, tt.dest)
+			_, err := files.MoveFile(afero.NewOsFs(), tt.src, tt.dest, files.MoveError)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("MoveFile() error = %v, wantErr %v", err, tt.wantErr)
@@ -182,6 +186,177 @@ func TestMoveFile(t *testing.T) {
 	}
 }
 
+// TestMoveFile_Collisions tests each policy for a destination that already exists.
+func TestMoveFile_Collisions(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		policy      files.MovePolicy
+		srcTime     time.Time
+		wantErr     bool
+		wantOlder   bool
+		wantDest    string
+		wantContent map[string]string
+	}{
+		{
+			name:        "error keeps both files in place",
+			policy:      files.MoveError,
+			srcTime:     newer,
+			wantErr:     true,
+			wantContent: map[string]string{"/active/Task.md": "active", "/done/Task.md": "done"},
+		},
+		{
+			name:        "suffix keeps both files",
+			policy:      files.MoveSuffix,
+			srcTime:     newer,
+			wantDest:    "/done/Task (2).md",
+			wantContent: map[string]string{"/done/Task.md": "done", "/done/Task (2).md": "active"},
+		},
+		{
+			name:        "keep newer replaces an older destination",
+			policy:      files.MoveKeepNewer,
+			srcTime:     newer,
+			wantDest:    "/done/Task.md",
+			wantContent: map[string]string{"/done/Task.md": "active"},
+		},
+		{
+			name:        "keep newer leaves an older source to the caller",
+			policy:      files.MoveKeepNewer,
+			srcTime:     older.Add(-time.Hour),
+			wantOlder:   true,
+			wantContent: map[string]string{"/active/Task.md": "active", "/done/Task.md": "done"},
+		},
+		{
+			name:    "unknown policy",
+			policy:  files.MovePolicy("replace"),
+			srcTime: newer,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/active/Task.md": "active",
+				"/done/Task.md":   "done",
+			})
+			if err := fsys.Chtimes("/active/Task.md", tt.srcTime, tt.srcTime); err != nil {
+				t.Fatal(err)
+			}
+			if err := fsys.Chtimes("/done/Task.md", older, older); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := files.MoveFile(fsys,
+				files.FilePath{Dir: "/active", Name: "Task.md"},
+				files.FilePath{Dir: "/done", Name: "Task.md"},
+				tt.policy,
+			)
+			if errors.Is(err, files.ErrSourceOlder) != tt.wantOlder {
+				t.Fatalf("MoveFile() error = %v, want ErrSourceOlder %v", err, tt.wantOlder)
+			}
+			if tt.wantOlder {
+				if got.FullPath() != "/done/Task.md" {
+					t.Errorf("MoveFile() = %s, want /done/Task.md", got.FullPath())
+				}
+			} else if (err != nil) != tt.wantErr {
+				t.Fatalf("MoveFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.wantOlder && got.FullPath() != tt.wantDest {
+				t.Errorf("MoveFile() = %s, want %s", got.FullPath(), tt.wantDest)
+			}
+			if !tt.wantErr && !tt.wantOlder {
+				if exists, _ := afero.Exists(fsys, "/active/Task.md"); exists {
+					t.Error("source file still exists")
+				}
+			}
+			for path, content := range tt.wantContent {
+				testutil.AssertFsFileContent(t, fsys, path, content)
+			}
+		})
+	}
+}
+
+// crossDeviceFs fails renames between directories like a rename across mount points.
+type crossDeviceFs struct {
+	afero.Fs
+}
+
+func (f crossDeviceFs) Rename(oldname, newname string) error {
+	if filepath.Dir(oldname) != filepath.Dir(newname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+	}
+	return f.Fs.Rename(oldname, newname)
+}
+
+// TestMoveFile_CrossDevice tests that a file is copied and removed when it cannot be renamed.
+func TestMoveFile_CrossDevice(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := crossDeviceFs{testutil.CreateMemFs(t, map[string]string{
+		"/active/Task.md": "task content",
+		"/done/.keep":     "",
+	})}
+	if err := fsys.Chmod("/active/Task.md", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Chtimes("/active/Task.md", modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := files.MoveFile(fsys,
+		files.FilePath{Dir: "/active", Name: "Task.md"},
+		files.FilePath{Dir: "/done", Name: "Task.md"},
+		files.MoveError,
+	)
+	if err != nil {
+		t.Fatalf("MoveFile() error = %v", err)
+	}
+
+	testutil.AssertFsFileContent(t, fsys, "/done/Task.md", "task content")
+	if exists, _ := afero.Exists(fsys, "/active/Task.md"); exists {
+		t.Error("source file still exists")
+	}
+	info, err := fsys.Stat("/done/Task.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 || !info.ModTime().Equal(modTime) {
+		t.Errorf("mode = %v, modified = %v, want 0600 and %v", info.Mode().Perm(), info.ModTime(), modTime)
+	}
+	entries, _ := afero.ReadDir(fsys, "/done")
+	if len(entries) != 2 {
+		t.Errorf("temporary file left behind: %d entries in /done", len(entries))
+	}
+}
+
+// TestParseMovePolicy tests parsing of configured move policies.
+func TestParseMovePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    files.MovePolicy
+		wantErr bool
+	}{
+		{value: "", want: files.MoveError},
+		{value: "suffix", want: files.MoveSuffix},
+		{value: " Keep_Newer ", want: files.MoveKeepNewer},
+		{value: "overwrite", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := files.ParseMovePolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMovePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMovePolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestDeleteFile tests the DeleteFile function.
 // It verifies file deletion operations work correctly in various scenarios.
 func TestDeleteFile(t *testing.T) {
@@ -218,7 +393,7 @@ func TestDeleteFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := files.DeleteFile(tt.path)
+			err := files.DeleteFile(afero.NewOsFs(), tt.path)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DeleteFile() error = %v, wantErr %v", err, tt.wantErr)
@@ -232,3 +407,57 @@ func TestDeleteFile(t *testing.T) {
 		})
 	}
 }
+
+// TestNextAvailableName tests the NextAvailableName function.
+// It verifies that a numeric suffix is added until a free name is found.
+func TestNextAvailableName(t *testing.T) {
+	testDir := testutil.CreateTestDirectory(t)
+	for _, name := range []string{"taken.md", "taken (2).md", "single.md"} {
+		if err := testutil.CreateTestFile(t, testDir, name, "content"); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    files.FilePath
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "free name is unchanged",
+			path: files.FilePath{Dir: testDir, Name: "free.md"},
+			want: "free.md",
+		},
+		{
+			name: "first collision gets suffix 2",
+			path: files.FilePath{Dir: testDir, Name: "single.md"},
+			want: "single (2).md",
+		},
+		{
+			name: "skips taken suffixes",
+			path: files.FilePath{Dir: testDir, Name: "taken.md"},
+			want: "taken (3).md",
+		},
+		{
+			name:    "invalid directory",
+			path:    files.FilePath{Dir: "/nonexistent/dir", Name: "file.md"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := files.NextAvailableName(afero.NewOsFs(), tt.path)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NextAvailableName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && (got.Name != tt.want || got.Dir != tt.path.Dir) {
+				t.Errorf("NextAvailableName() = %v, want %v", got, files.FilePath{Dir: tt.path.Dir, Name: tt.want})
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package links
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/spf13/afero"
+)
+
+// Note is a file of the vault that links can point to.
+type Note struct {
+	Path     string   // slash separated path relative to the vault root
+	Aliases  []string // frontmatter aliases, markdown notes only
+	Headings []string // heading texts, markdown notes only
+	BlockIDs []string // block ids without the "^", markdown notes only
+}
+
+// Link is a wikilink or embed found in a markdown note.
+type Link struct {
+	mdparser.WikiLink
+	Source string // path of the note containing the link, relative to the vault root
+	Raw    string // the link as written, such as "[[Note#Heading|alias]]"
+}
+
+// Status is the outcome of resolving a link.
+type Status int
+
+const (
+	// Resolved links point to exactly one note, and to an existing heading or block.
+	Resolved Status = iota
+	// Broken links match no note.
+	Broken
+	// Ambiguous links match several notes.
+	Ambiguous
+	// MissingHeading links point to a note without the linked heading.
+	MissingHeading
+	// MissingBlock links point to a note without the linked block id.
+	MissingBlock
+)
+
+// String returns a short description of the status.
+func (s Status) String() string {
+	switch s {
+	case Resolved:
+		return "resolved"
+	case Broken:
+		return "broken link"
+	case Ambiguous:
+		return "ambiguous link"
+	case MissingHeading:
+		return "missing heading"
+	case MissingBlock:
+		return "missing block"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Resolution is the result of resolving a link.
+type Resolution struct {
+	Status  Status
+	Targets []string // paths of the matching notes, sorted
+}
+
+// Problem is a link that does not resolve to exactly one note, heading or block.
+type Problem struct {
+	Link
+	Resolution
+}
+
+// String formats a problem as "path:line: status [[link]]", followed by the
+// candidates of an ambiguous link.
+func (p Problem) String() string {
+	msg := fmt.Sprintf("%s:%d: %s %s", p.Source, p.Span.Line, p.Status, p.Raw)
+	if p.Status == Ambiguous {
+		msg += " matches " + strings.Join(p.Targets, ", ")
+	}
+	return msg
+}
+
+// Graph holds the notes of a vault and the links between them.
+type Graph struct {
+	Notes map[string]*Note // notes by path
+	Links []Link           // links in the order of their source paths and positions
+
+	byName  map[string][]string // lower case name without .md extension -> paths
+	byAlias map[string][]string // lower case alias -> paths
+}
+
+var blockIDLine = regexp.MustCompile(`(?:^|\s)\^([A-Za-z0-9-]+)[ \t]*$`)
+
+// BuildGraph scans a vault and builds its link graph. Hidden files and folders,
+// such as .obsidian and .trash, are skipped. Every file can be linked to, and links
+// are read from markdown files, including links written in their frontmatter.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - root: the vault directory
+//
+// Returns:
+//   - *Graph: the notes and links of the vault
+//   - error: if the vault cannot be read
+func BuildGraph(fsys afero.Fs, root string) (*Graph, error) {
+	g := &Graph{
+		Notes:   make(map[string]*Note),
+		byName:  make(map[string][]string),
+		byAlias: make(map[string][]string),
+	}
+
+	err := afero.Walk(fsys, root, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !isMarkdown(rel) {
+			g.add(&Note{Path: rel})
+			return nil
+		}
+
+		data, err := afero.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read note %s: %w", p, err)
+		}
+		g.addMarkdown(rel, data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan vault %s: %w", root, err)
+	}
+
+	for _, paths := range g.byName {
+		sort.Strings(paths)
+	}
+	for _, paths := range g.byAlias {
+		sort.Strings(paths)
+	}
+	return g, nil
+}
+
+// addMarkdown indexes a markdown note and collects its links. Notes with invalid
+// frontmatter are still indexed, without aliases.
+func (g *Graph) addMarkdown(rel string, data []byte) {
+	note := &Note{Path: rel}
+
+	if doc, err := mdparser.Parse(bytes.NewReader(data), rel); err == nil {
+		var meta struct {
+			Aliases []string `fm:"aliases"`
+			Alias   []string `fm:"alias"`
+		}
+		// Invalid items are left out, the rest of the aliases are still used.
+		_ = mdparser.Decode(doc.Frontmatter, &meta)
+		note.Aliases = append(meta.Aliases, meta.Alias...)
+
+		body := doc.Body()
+		for _, heading := range body.Headings() {
+			note.Headings = append(note.Headings, heading.Text)
+		}
+		for _, line := range strings.Split(doc.Content, "\n") {
+			if m := blockIDLine.FindStringSubmatch(line); m != nil {
+				note.BlockIDs = append(note.BlockIDs, m[1])
+			}
+		}
+	}
+	g.add(note)
+
+	// The whole file is scanned so that link lines match the file, and links in
+	// frontmatter properties are found too.
+	source := mdparser.ParseBody(string(data))
+	for _, link := range source.WikiLinks {
+		g.Links = append(g.Links, Link{WikiLink: link, Source: rel, Raw: source.Text(link.Span)})
+	}
+}
+
+// add indexes a note by name and aliases.
+func (g *Graph) add(note *Note) {
+	g.Notes[note.Path] = note
+
+	name := strings.ToLower(path.Base(trimMarkdown(note.Path)))
+	g.byName[name] = append(g.byName[name], note.Path)
+	for _, alias := range note.Aliases {
+		key := strings.ToLower(strings.TrimSpace(alias))
+		if key != "" {
+			g.byAlias[key] = append(g.byAlias[key], note.Path)
+		}
+	}
+}
+
+// Resolve resolves a link the way Obsidian does. A target matching the full path of a
+// note from the vault root wins. Otherwise the target is matched case-insensitively
+// against note names, or against the end of note paths when it contains a "/", and
+// aliases are used when no note name matches. An empty target refers to the source note.
+//
+// Parameters:
+//   - source: path of the note containing the link, relative to the vault root
+//   - link: the link to resolve
+//
+// Returns:
+//   - Resolution: the status and the matching notes
+func (g *Graph) Resolve(source string, link mdparser.WikiLink) Resolution {
+	var targets []string
+	if link.Target == "" {
+		if _, ok := g.Notes[source]; ok {
+			targets = []string{source}
+		}
+	} else {
+		targets = g.match(link.Target)
+	}
+
+	switch len(targets) {
+	case 0:
+		return Resolution{Status: Broken}
+	case 1:
+	default:
+		return Resolution{Status: Ambiguous, Targets: targets}
+	}
+
+	note := g.Notes[targets[0]]
+	// Nested headings are written as "Note#Parent#Child", only the last one is checked.
+	heading := link.Heading[strings.LastIndex(link.Heading, "#")+1:]
+	if heading != "" && !containsFold(note.Headings, normalizeHeading(heading), normalizeHeading) {
+		return Resolution{Status: MissingHeading, Targets: targets}
+	}
+	if link.BlockID != "" && !containsFold(note.BlockIDs, link.BlockID, strings.TrimSpace) {
+		return Resolution{Status: MissingBlock, Targets: targets}
+	}
+	return Resolution{Status: Resolved, Targets: targets}
+}
+
+// match returns the paths of the notes a link target refers to.
+func (g *Graph) match(target string) []string {
+	key := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(target), "/"))
+	key = trimMarkdown(key)
+
+	candidates := g.byName[path.Base(key)]
+	for _, candidate := range candidates {
+		if strings.ToLower(trimMarkdown(candidate)) == key {
+			return []string{candidate}
+		}
+	}
+
+	if !strings.Contains(key, "/") {
+		if len(candidates) > 0 {
+			return candidates
+		}
+		return g.byAlias[key]
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasSuffix(strings.ToLower(trimMarkdown(candidate)), "/"+key) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// Problems resolves every link of the graph and returns those that do not resolve.
+//
+// Returns:
+//   - []Problem: broken, ambiguous and partially broken links, by source path and line
+func (g *Graph) Problems() []Problem {
+	var problems []Problem
+	for _, link := range g.Links {
+		if res := g.Resolve(link.Source, link.WikiLink); res.Status != Resolved {
+			problems = append(problems, Problem{Link: link, Resolution: res})
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Source != problems[j].Source {
+			return problems[i].Source < problems[j].Source
+		}
+		return problems[i].Span.Start < problems[j].Span.Start
+	})
+	return problems
+}
+
+// Backlinks returns the links that resolve to a note.
+//
+// Parameters:
+//   - notePath: path of the note, relative to the vault root
+//
+// Returns:
+//   - []Link: links pointing to the note, including links to its headings and blocks
+func (g *Graph) Backlinks(notePath string) []Link {
+	var backlinks []Link
+	for _, link := range g.Links {
+		res := g.Resolve(link.Source, link.WikiLink)
+		if res.Status != Broken && res.Status != Ambiguous && res.Targets[0] == notePath {
+			backlinks = append(backlinks, link)
+		}
+	}
+	return backlinks
+}
+
+// LinkTarget returns the shortest link target that resolves to a note without ambiguity:
+// its name when that is unique in the vault, otherwise the shortest unique path suffix.
+//
+// Parameters:
+//   - notePath: path of the note, relative to the vault root
+//
+// Returns:
+//   - string: the link target, without the .md extension of markdown notes
+func (g *Graph) LinkTarget(notePath string) string {
+	full := trimMarkdown(notePath)
+	parts := strings.Split(full, "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		candidate := strings.Join(parts[i:], "/")
+		if matches := g.match(candidate); len(matches) == 1 && matches[0] == notePath {
+			return candidate
+		}
+	}
+	return full
+}
+
+// isMarkdown reports whether a path is a markdown note.
+func isMarkdown(p string) bool {
+	return strings.EqualFold(path.Ext(p), ".md")
+}
+
+// trimMarkdown removes a .md extension, keeping the extension of other files.
+func trimMarkdown(p string) string {
+	if isMarkdown(p) {
+		return p[:len(p)-len(path.Ext(p))]
+	}
+	return p
+}
+
+// normalizeHeading compares headings the way Obsidian does, ignoring case,
+// surrounding spaces and repeated inner spaces.
+func normalizeHeading(heading string) string {
+	return strings.Join(strings.Fields(heading), " ")
+}
+
+// containsFold reports whether a list holds a value, comparing normalized values
+// case-insensitively.
+func containsFold(list []string, value string, normalize func(string) string) bool {
+	for _, item := range list {
+		if strings.EqualFold(normalize(item), value) {
+			return true
+		}
+	}
+	return false
+}
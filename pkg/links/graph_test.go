@@ -0,0 +1,184 @@
+package links_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/links"
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// createVault builds an in-memory vault at /vault with duplicate names, aliases and attachments.
+func createVault(t *testing.T) afero.Fs {
+	t.Helper()
+
+	files := map[string]string{
+		"Home.md":                     "---\nrelated: \"[[Plan]]\"\n---\n\n# Home\n\nSee [[plan#Goals]], [[Roadmap|the roadmap]] and ![[diagram.png]].\n[[Meeting]] [[Missing]] [[Plan#Nope]] [[Plan#^abc123]]\n`[[Not a link]]`\n",
+		"Projects/Plan.md":            "---\naliases: [Roadmap, Q1 plan]\n---\n\n## Goals\n\nShip it. ^abc123\n",
+		"Projects/Meeting.md":         "[[#Agenda]] [[Archive/Meeting]] [[Projects/Meeting]] [[/Projects/Meeting]]\n\n# Agenda\n",
+		"Archive/Meeting.md":          "",
+		"Archive/Projects/Meeting.md": "",
+		"Attachments/diagram.png":     "png",
+		".obsidian/workspace.md":      "[[Missing]]",
+	}
+	vault := make(map[string]string, len(files))
+	for name, content := range files {
+		vault["/vault/"+name] = content
+	}
+	return testutil.CreateMemFs(t, vault)
+}
+
+func TestGraph_Resolve(t *testing.T) {
+	graph, err := links.BuildGraph(createVault(t), "/vault")
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		link   mdparser.WikiLink
+		want   links.Resolution
+	}{
+		{
+			name: "name is case-insensitive",
+			link: mdparser.WikiLink{Target: "plan"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "extension is optional",
+			link: mdparser.WikiLink{Target: "Plan.md"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "alias",
+			link: mdparser.WikiLink{Target: "q1 PLAN"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "attachment",
+			link: mdparser.WikiLink{Target: "diagram.png", Embed: true},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Attachments/diagram.png"}},
+		},
+		{
+			name: "duplicate name is ambiguous",
+			link: mdparser.WikiLink{Target: "Meeting"},
+			want: links.Resolution{Status: links.Ambiguous, Targets: []string{"Archive/Meeting.md", "Archive/Projects/Meeting.md", "Projects/Meeting.md"}},
+		},
+		{
+			name: "path suffix",
+			link: mdparser.WikiLink{Target: "archive/meeting"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Archive/Meeting.md"}},
+		},
+		{
+			name: "full path from the root",
+			link: mdparser.WikiLink{Target: "Home"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Home.md"}},
+		},
+		{
+			name: "full path wins over longer paths",
+			link: mdparser.WikiLink{Target: "Projects/Meeting"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Meeting.md"}},
+		},
+		{
+			name: "nested heading",
+			link: mdparser.WikiLink{Target: "Plan", Heading: "Top#goals"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "missing heading",
+			link: mdparser.WikiLink{Target: "Plan", Heading: "Nope"},
+			want: links.Resolution{Status: links.MissingHeading, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "block id",
+			link: mdparser.WikiLink{Target: "Plan", BlockID: "abc123"},
+			want: links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name: "missing block id",
+			link: mdparser.WikiLink{Target: "Plan", BlockID: "zzz"},
+			want: links.Resolution{Status: links.MissingBlock, Targets: []string{"Projects/Plan.md"}},
+		},
+		{
+			name:   "same note heading",
+			source: "Projects/Meeting.md",
+			link:   mdparser.WikiLink{Heading: "Agenda"},
+			want:   links.Resolution{Status: links.Resolved, Targets: []string{"Projects/Meeting.md"}},
+		},
+		{
+			name: "hidden folders are skipped",
+			link: mdparser.WikiLink{Target: "workspace"},
+			want: links.Resolution{Status: links.Broken},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source == "" {
+				source = "Home.md"
+			}
+			got := graph.Resolve(source, tt.link)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_Problems(t *testing.T) {
+	graph, err := links.BuildGraph(createVault(t), "/vault")
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	var got []string
+	for _, problem := range graph.Problems() {
+		got = append(got, problem.String())
+	}
+
+	want := []string{
+		"Home.md:8: ambiguous link [[Meeting]] matches Archive/Meeting.md, Archive/Projects/Meeting.md, Projects/Meeting.md",
+		"Home.md:8: broken link [[Missing]]",
+		"Home.md:8: missing heading [[Plan#Nope]]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Problems() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestGraph_LinkTargetAndBacklinks(t *testing.T) {
+	graph, err := links.BuildGraph(createVault(t), "/vault")
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	for notePath, want := range map[string]string{
+		"Projects/Plan.md":        "Plan",
+		"Projects/Meeting.md":     "Projects/Meeting",
+		"Home.md":                 "Home",
+		"Attachments/diagram.png": "diagram.png",
+	} {
+		if got := graph.LinkTarget(notePath); got != want {
+			t.Errorf("LinkTarget(%q) = %q, want %q", notePath, got, want)
+		}
+	}
+
+	var sources []string
+	for _, link := range graph.Backlinks("Projects/Plan.md") {
+		sources = append(sources, link.Source+" "+link.Raw)
+	}
+	want := []string{
+		"Home.md [[Plan]]",
+		"Home.md [[plan#Goals]]",
+		"Home.md [[Roadmap|the roadmap]]",
+		"Home.md [[Plan#Nope]]",
+		"Home.md [[Plan#^abc123]]",
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("Backlinks() = %q, want %q", sources, want)
+	}
+}
@@ -0,0 +1,9 @@
+package lock
+
+// SetProcess replaces the description of the current process and the process liveness
+// check, returning a function that restores them.
+func SetProcess(self func() Info, isAlive func(pid int) bool) (restore func()) {
+	previousCurrent, previousAlive := current, alive
+	current, alive = self, isAlive
+	return func() { current, alive = previousCurrent, previousAlive }
+}
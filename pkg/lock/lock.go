@@ -0,0 +1,248 @@
+// Package lock provides the advisory lock that keeps two runs from processing the same
+// vault at the same time.
+//
+// The lock is a file in the vault root holding the PID, host and start time of the run
+// that owns it. A lock left behind by a run that died is detected as stale and taken
+// over: on the same host as soon as its process no longer exists, and in any case once
+// it is older than Options.StaleAfter.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// FileName is the name of the lock file in the vault root.
+const FileName = ".cerebgo.lock"
+
+// DefaultPollInterval is how often a waiting run checks whether the lock was released.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// unreadableGrace is how long an unreadable lock file is left alone, since its owner
+// may still be writing it.
+const unreadableGrace = time.Minute
+
+// ErrLocked is returned when another run holds the vault lock.
+var ErrLocked = errors.New("vault is locked by another run")
+
+// Info describes the run holding a lock.
+type Info struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// String describes the run for error messages.
+func (i Info) String() string {
+	return fmt.Sprintf("pid %d on %s since %s", i.PID, i.Host, i.StartedAt.Format(time.RFC3339))
+}
+
+// sameRun reports whether two descriptions are of the same run.
+func (i Info) sameRun(other Info) bool {
+	return i.PID == other.PID && i.Host == other.Host && i.StartedAt.Equal(other.StartedAt)
+}
+
+// Options configures Acquire.
+type Options struct {
+	Wait         time.Duration // how long to wait for another run to finish, 0 fails at once
+	StaleAfter   time.Duration // age after which any lock is stale, 0 never
+	PollInterval time.Duration // how often to check while waiting, DefaultPollInterval if 0
+}
+
+// Lock is a held vault lock.
+type Lock struct {
+	fsys afero.Fs
+	path string
+	info Info
+}
+
+// current returns the Info of this process. Tests replace it.
+var current = func() Info {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return Info{PID: os.Getpid(), Host: host, StartedAt: time.Now().UTC().Truncate(time.Second)}
+}
+
+// alive reports whether a process exists on this host. Tests replace it.
+var alive = processAlive
+
+// Acquire takes the lock of the vault in dir, waiting up to opts.Wait for another run
+// to release it. Stale locks are removed.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - dir: the vault root
+//   - opts: waiting and stale lock settings
+//
+// Returns:
+//   - *Lock: the held lock, to be released with Release
+//   - error: wrapping ErrLocked if another run holds the lock, or file errors
+func Acquire(fsys afero.Fs, dir string, opts Options) (*Lock, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	self := current()
+	path := filepath.Join(dir, FileName)
+	deadline := time.Now().Add(opts.Wait)
+
+	for {
+		err := create(fsys, path, self)
+		if err == nil {
+			return &Lock{fsys: fsys, path: path, info: self}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, err := read(fsys, path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Released in the meantime.
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		if isStale(holder, self, opts.StaleAfter) {
+			if err := removeIfUnchanged(fsys, path, holder); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w (%s)", ErrLocked, holder.Info)
+		}
+		time.Sleep(min(opts.PollInterval, time.Until(deadline)))
+	}
+}
+
+// Release removes the lock, unless another run has taken it over in the meantime.
+//
+// Returns:
+//   - error: if the lock file cannot be read or removed
+func (l *Lock) Release() error {
+	holder, err := read(l.fsys, l.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case !holder.valid || !holder.sameRun(l.info):
+		return nil
+	}
+
+	if err := l.fsys.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// Read returns the run holding the lock of the vault in dir.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - dir: the vault root
+//
+// Returns:
+//   - Info: the run holding the lock
+//   - error: wrapping fs.ErrNotExist if the vault is not locked
+func Read(fsys afero.Fs, dir string) (Info, error) {
+	holder, err := read(fsys, filepath.Join(dir, FileName))
+	if err != nil {
+		return Info{}, err
+	}
+	if !holder.valid {
+		return Info{}, fmt.Errorf("unreadable lock file %s", filepath.Join(dir, FileName))
+	}
+	return holder.Info, nil
+}
+
+// holder is the content of an existing lock file.
+type holder struct {
+	Info
+	data    []byte
+	modTime time.Time
+	valid   bool
+}
+
+// create writes a new lock file, failing with fs.ErrExist if there is one.
+func create(fsys afero.Fs, path string, info Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	f, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		fsys.Remove(path)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		fsys.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// read reads an existing lock file. A file that cannot be decoded is returned as invalid.
+func read(fsys afero.Fs, path string) (holder, error) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return holder{}, err
+	}
+	data, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		return holder{}, err
+	}
+
+	h := holder{data: data, modTime: info.ModTime()}
+	h.valid = json.Unmarshal(data, &h.Info) == nil && h.PID > 0
+	return h, nil
+}
+
+// isStale reports whether the run holding a lock is gone.
+func isStale(h holder, self Info, staleAfter time.Duration) bool {
+	if !h.valid {
+		return time.Since(h.modTime) > unreadableGrace
+	}
+	if h.Host == self.Host && h.PID != self.PID && !alive(h.PID) {
+		return true
+	}
+	return staleAfter > 0 && time.Since(h.StartedAt) > staleAfter
+}
+
+// removeIfUnchanged removes a stale lock file, unless another run replaced it since it
+// was read.
+func removeIfUnchanged(fsys afero.Fs, path string, stale holder) error {
+	data, err := afero.ReadFile(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lock file: %w", err)
+	}
+	if string(data) != string(stale.data) {
+		return nil
+	}
+
+	if err := fsys.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale lock file: %w", err)
+	}
+	return nil
+}
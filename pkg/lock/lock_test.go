@@ -0,0 +1,180 @@
+package lock_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/lock"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// setProcess makes the test run as the given process, with the listed PIDs alive.
+func setProcess(t *testing.T, self lock.Info, alivePIDs ...int) {
+	t.Helper()
+	restore := lock.SetProcess(
+		func() lock.Info { return self },
+		func(pid int) bool {
+			for _, p := range alivePIDs {
+				if p == pid {
+					return true
+				}
+			}
+			return false
+		},
+	)
+	t.Cleanup(restore)
+}
+
+// lockFile returns the content of a lock file held by info.
+func lockFile(t *testing.T, info lock.Info) string {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestAcquire tests taking the lock over existing lock files.
+func TestAcquire(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	self := lock.Info{PID: 100, Host: "laptop", StartedAt: now}
+
+	tests := []struct {
+		name       string
+		existing   *lock.Info
+		raw        string
+		alive      []int
+		staleAfter time.Duration
+		wantLocked bool
+	}{
+		{
+			name: "unlocked vault",
+		},
+		{
+			name:       "running process on this host",
+			existing:   &lock.Info{PID: 200, Host: "laptop", StartedAt: now.Add(-time.Minute)},
+			alive:      []int{200},
+			wantLocked: true,
+		},
+		{
+			name:     "dead process on this host is stale",
+			existing: &lock.Info{PID: 200, Host: "laptop", StartedAt: now.Add(-time.Minute)},
+		},
+		{
+			name:       "recent run on another host",
+			existing:   &lock.Info{PID: 200, Host: "runner", StartedAt: now.Add(-time.Minute)},
+			staleAfter: time.Hour,
+			wantLocked: true,
+		},
+		{
+			name:       "old run on another host is stale",
+			existing:   &lock.Info{PID: 200, Host: "runner", StartedAt: now.Add(-2 * time.Hour)},
+			staleAfter: time.Hour,
+		},
+		{
+			name:       "old run is kept without stale age",
+			existing:   &lock.Info{PID: 200, Host: "runner", StartedAt: now.Add(-48 * time.Hour)},
+			wantLocked: true,
+		},
+		{
+			name:       "unreadable lock being written",
+			raw:        "{",
+			wantLocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProcess(t, self, tt.alive...)
+
+			files := map[string]string{"/vault/Tasks/.keep": ""}
+			if tt.existing != nil {
+				files["/vault/"+lock.FileName] = lockFile(t, *tt.existing)
+			}
+			if tt.raw != "" {
+				files["/vault/"+lock.FileName] = tt.raw
+			}
+			fsys := testutil.CreateMemFs(t, files)
+
+			held, err := lock.Acquire(fsys, "/vault", lock.Options{StaleAfter: tt.staleAfter})
+			if tt.wantLocked {
+				if !errors.Is(err, lock.ErrLocked) {
+					t.Fatalf("Acquire() error = %v, want %v", err, lock.ErrLocked)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+
+			info, err := lock.Read(fsys, "/vault")
+			if err != nil || info.PID != self.PID || info.Host != self.Host || !info.StartedAt.Equal(self.StartedAt) {
+				t.Errorf("Read() = %+v, %v, want %+v", info, err, self)
+			}
+
+			if err := held.Release(); err != nil {
+				t.Fatalf("Release() error = %v", err)
+			}
+			if _, err := lock.Read(fsys, "/vault"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Read() after Release() error = %v, want not exist", err)
+			}
+		})
+	}
+}
+
+// TestAcquire_Wait tests waiting for another run to release the lock.
+func TestAcquire_Wait(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	self := lock.Info{PID: 100, Host: "laptop", StartedAt: now}
+	other := lock.Info{PID: 200, Host: "laptop", StartedAt: now}
+	setProcess(t, self, other.PID)
+
+	fsys := testutil.CreateMemFs(t, map[string]string{"/vault/" + lock.FileName: lockFile(t, other)})
+	opts := lock.Options{Wait: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond}
+
+	if _, err := lock.Acquire(fsys, "/vault", opts); !errors.Is(err, lock.ErrLocked) {
+		t.Fatalf("Acquire() error = %v, want %v after the wait", err, lock.ErrLocked)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fsys.Remove("/vault/" + lock.FileName)
+	}()
+	opts.Wait = time.Second
+	held, err := lock.Acquire(fsys, "/vault", opts)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v, want the released lock", err)
+	}
+	if err := held.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+}
+
+// TestRelease_TakenOver tests that releasing never removes another run's lock.
+func TestRelease_TakenOver(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	setProcess(t, lock.Info{PID: 100, Host: "laptop", StartedAt: now})
+
+	fsys := afero.NewMemMapFs()
+	held, err := lock.Acquire(fsys, "/vault", lock.Options{})
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	other := lock.Info{PID: 200, Host: "runner", StartedAt: now}
+	if err := afero.WriteFile(fsys, "/vault/"+lock.FileName, []byte(lockFile(t, other)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if info, err := lock.Read(fsys, "/vault"); err != nil || info.PID != other.PID {
+		t.Errorf("Read() = %+v, %v, want the lock of pid %d", info, err, other.PID)
+	}
+}
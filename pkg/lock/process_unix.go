@@ -0,0 +1,15 @@
+//go:build unix
+
+package lock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process exists, by sending it the null signal.
+// A process owned by another user still exists even though it cannot be signalled.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,578 @@
+package mdparser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Span locates a node in the source of a Body.
+type Span struct {
+	Start  int // byte offset of the first byte
+	End    int // byte offset after the last byte
+	Line   int // 1-based line of Start
+	Column int // 1-based byte column of Start
+}
+
+// BlockKind identifies the type of a block.
+type BlockKind int
+
+const (
+	// BlockParagraph is a run of consecutive text lines.
+	BlockParagraph BlockKind = iota
+	// BlockHeading is an ATX heading such as "## Log".
+	BlockHeading
+	// BlockListItem is a single bullet or numbered list line, possibly a checklist item.
+	BlockListItem
+	// BlockCode is a fenced code block, including its fences.
+	BlockCode
+	// BlockThematicBreak is a "---", "***" or "___" line.
+	BlockThematicBreak
+)
+
+// Block is a block-level element of a markdown body.
+// Fields that do not apply to a block's kind are left empty.
+type Block struct {
+	Kind     BlockKind
+	Span     Span   // the whole block, without its final line ending
+	Text     string // heading text, list item text, paragraph text or code content
+	Level    int    // heading level, 1 to 6
+	Indent   int    // list item indentation, in columns
+	Marker   string // list marker such as "-", "*" or "1."
+	Task     bool   // whether the list item has a checkbox
+	Checked  bool   // whether the checkbox is ticked
+	Checkbox Span   // the character between the checkbox brackets
+	Language string // fenced code info string
+}
+
+// WikiLink is an Obsidian style [[link]], or an ![[embed]].
+type WikiLink struct {
+	Target  string // note name or path, empty for links within the same note
+	Heading string // heading after "#", if any
+	BlockID string // block id after "#^", if any
+	Alias   string // display text after "|", if any
+	Embed   bool
+	Span    Span
+}
+
+// Tag is an inline #tag. Name excludes the leading "#".
+type Tag struct {
+	Name string
+	Span Span
+}
+
+// URL is a bare or linked http(s) URL.
+type URL struct {
+	Value string
+	Span  Span
+}
+
+// InlineField is a Dataview inline field, written on its own line as "key:: value"
+// or inside text as "[key:: value]" or "(key:: value)".
+type InlineField struct {
+	Key       string
+	Value     string
+	Span      Span // the whole field
+	ValueSpan Span // the value, empty when the field has no value
+	Bracketed bool // whether the field is written inside brackets or parentheses
+}
+
+// Section is a heading and everything up to the next heading of the same or a higher level.
+type Section struct {
+	Heading Block
+	Span    Span // from the heading to the end of the section
+	Content Span // the section text without the heading line and surrounding blank lines
+}
+
+// Body is a parsed markdown body. Positions refer to Source, and edits return a new
+// Body whose Source differs from the original only in the edited bytes.
+type Body struct {
+	Source    string
+	Blocks    []Block
+	WikiLinks []WikiLink
+	Tags      []Tag
+	URLs      []URL
+	Fields    []InlineField
+
+	lineStarts []int
+}
+
+var (
+	headingLine   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	listItemLine  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	checkboxStart = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+	thematicLine  = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceLine     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	wikiLinkRe    = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+?)\]\]`)
+	tagRe         = regexp.MustCompile(`(?:^|[\s(,])#([\p{L}\p{N}_/-]+)`)
+	urlRe         = regexp.MustCompile("https?://[^\\s<>()\\[\\]\"'`]+")
+	lineFieldRe   = regexp.MustCompile(`^[ \t]*(?:[-*+][ \t]+(?:\[[ xX]\][ \t]+)?)?([\p{L}\p{N}_][\p{L}\p{N}_ /-]*?)::[ \t]*(.*?)[ \t]*$`)
+	inlineFieldRe = regexp.MustCompile(`[\[(]([\p{L}\p{N}_][\p{L}\p{N}_ /-]*?)::[ \t]*([^\[\]()\n]*?)[ \t]*[\])]`)
+)
+
+// sourceLine is a line of the source, with offsets of its text and of the next line.
+type sourceLine struct {
+	start int
+	end   int // end of the text, excluding the line ending
+	next  int // start of the next line
+	text  string
+}
+
+// ParseBody parses the body of a markdown document into blocks and inline elements.
+// Wikilinks, tags and URLs inside code blocks and inline code are ignored.
+//
+// Parameters:
+//   - src: markdown text without frontmatter
+//
+// Returns:
+//   - *Body: the parsed body
+func ParseBody(src string) *Body {
+	lines := splitSourceLines(src)
+	body := &Body{Source: src, lineStarts: make([]int, 0, len(lines))}
+	for _, l := range lines {
+		body.lineStarts = append(body.lineStarts, l.start)
+	}
+
+	inParagraph := false
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		if m := fenceLine.FindStringSubmatch(l.text); m != nil {
+			last := len(lines) - 1
+			for j := i + 1; j < len(lines); j++ {
+				if closesFence(lines[j].text, m[1]) {
+					last = j
+					break
+				}
+			}
+			contentEnd := lines[last].start
+			if last == len(lines)-1 && !closesFence(lines[last].text, m[1]) {
+				contentEnd = len(src)
+			}
+			text := ""
+			if l.next < contentEnd {
+				text = strings.TrimSuffix(src[l.next:contentEnd], "\n")
+			}
+			body.Blocks = append(body.Blocks, Block{
+				Kind:     BlockCode,
+				Span:     body.span(l.start, lines[last].end),
+				Text:     text,
+				Language: m[2],
+			})
+			i = last
+			inParagraph = false
+			continue
+		}
+
+		body.scanInline(l)
+
+		switch {
+		case strings.TrimSpace(l.text) == "":
+			inParagraph = false
+
+		case thematicLine.MatchString(l.text):
+			body.Blocks = append(body.Blocks, Block{Kind: BlockThematicBreak, Span: body.span(l.start, l.end)})
+			inParagraph = false
+
+		case headingLine.MatchString(l.text):
+			m := headingLine.FindStringSubmatch(l.text)
+			body.Blocks = append(body.Blocks, Block{
+				Kind:  BlockHeading,
+				Span:  body.span(l.start, l.end),
+				Text:  strings.TrimSpace(m[2]),
+				Level: len(m[1]),
+			})
+			inParagraph = false
+
+		case listItemLine.MatchString(l.text):
+			body.Blocks = append(body.Blocks, body.listItem(l))
+			inParagraph = false
+
+		case inParagraph:
+			last := &body.Blocks[len(body.Blocks)-1]
+			last.Span.End = l.end
+			last.Text += "\n" + strings.TrimSpace(l.text)
+
+		default:
+			body.Blocks = append(body.Blocks, Block{
+				Kind: BlockParagraph,
+				Span: body.span(l.start, l.end),
+				Text: strings.TrimSpace(l.text),
+			})
+			inParagraph = true
+		}
+	}
+
+	return body
+}
+
+// Body parses the content of the document.
+func (d MarkdownDocument) Body() *Body {
+	return ParseBody(d.Content)
+}
+
+// Headings returns the heading blocks in document order.
+func (b *Body) Headings() []Block {
+	return b.blocksOf(func(block Block) bool { return block.Kind == BlockHeading })
+}
+
+// ListItems returns all list item blocks in document order.
+func (b *Body) ListItems() []Block {
+	return b.blocksOf(func(block Block) bool { return block.Kind == BlockListItem })
+}
+
+// Checklist returns the list items that have a checkbox.
+func (b *Body) Checklist() []Block {
+	return b.blocksOf(func(block Block) bool { return block.Kind == BlockListItem && block.Task })
+}
+
+// Embeds returns the ![[embed]] wikilinks.
+func (b *Body) Embeds() []WikiLink {
+	embeds := make([]WikiLink, 0)
+	for _, link := range b.WikiLinks {
+		if link.Embed {
+			embeds = append(embeds, link)
+		}
+	}
+	return embeds
+}
+
+// Sections returns a section for every heading, in document order.
+// Sections nest: a section includes the sections of its lower level headings.
+func (b *Body) Sections() []Section {
+	headings := b.Headings()
+	sections := make([]Section, 0, len(headings))
+
+	for i, heading := range headings {
+		end := len(b.Source)
+		for _, next := range headings[i+1:] {
+			if next.Level <= heading.Level {
+				end = b.lineStart(next.Span.Line)
+				break
+			}
+		}
+
+		contentStart := b.lineEnd(heading.Span.Line)
+		contentEnd := end
+		for contentStart < contentEnd && isBlankLineAt(b.Source, contentStart) {
+			contentStart = nextLineStart(b.Source, contentStart)
+		}
+		for contentEnd > contentStart && isBlankLineBefore(b.Source, contentEnd) {
+			contentEnd = previousLineStart(b.Source, contentEnd)
+		}
+		if contentEnd <= contentStart {
+			contentStart = b.lineEnd(heading.Span.Line)
+			contentEnd = contentStart
+		}
+
+		sections = append(sections, Section{
+			Heading: heading,
+			Span:    b.span(heading.Span.Start, heading.Span.Start+len(strings.TrimRight(b.Source[heading.Span.Start:end], "\r\n"))),
+			Content: b.span(contentStart, contentEnd),
+		})
+	}
+
+	return sections
+}
+
+// Section finds the first section whose heading text matches, ignoring case.
+//
+// Parameters:
+//   - heading: heading text without the leading "#" characters
+//
+// Returns:
+//   - Section: the matching section
+//   - bool: false if no heading matches
+func (b *Body) Section(heading string) (Section, bool) {
+	for _, section := range b.Sections() {
+		if strings.EqualFold(section.Heading.Text, strings.TrimSpace(heading)) {
+			return section, true
+		}
+	}
+	return Section{}, false
+}
+
+// Field finds the first inline field with a key, ignoring case.
+//
+// Parameters:
+//   - key: field key
+//
+// Returns:
+//   - InlineField: the matching field
+//   - bool: false if no field has the key
+func (b *Body) Field(key string) (InlineField, bool) {
+	for _, field := range b.Fields {
+		if strings.EqualFold(field.Key, key) {
+			return field, true
+		}
+	}
+	return InlineField{}, false
+}
+
+// Text returns the source text covered by a span.
+func (b *Body) Text(span Span) string {
+	return b.Source[span.Start:span.End]
+}
+
+// blocksOf returns the blocks matching a predicate.
+func (b *Body) blocksOf(match func(Block) bool) []Block {
+	result := make([]Block, 0)
+	for _, block := range b.Blocks {
+		if match(block) {
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+// listItem builds a list item block from a list line.
+func (b *Body) listItem(l sourceLine) Block {
+	m := listItemLine.FindStringSubmatchIndex(l.text)
+	indent := strings.Count(l.text[:m[3]], " ") + 4*strings.Count(l.text[:m[3]], "\t")
+
+	item := Block{
+		Kind:   BlockListItem,
+		Span:   b.span(l.start, l.end),
+		Indent: indent,
+		Marker: l.text[m[4]:m[5]],
+	}
+	if m[6] == -1 {
+		return item
+	}
+
+	text := l.text[m[6]:m[7]]
+	if cb := checkboxStart.FindStringSubmatchIndex(text); cb != nil {
+		item.Task = true
+		item.Checked = text[cb[2]:cb[3]] != " "
+		item.Checkbox = b.span(l.start+m[6]+cb[2], l.start+m[6]+cb[3])
+		text = text[cb[1]:]
+	}
+	item.Text = strings.TrimSpace(text)
+	return item
+}
+
+// scanInline collects wikilinks, URLs and tags from a source line, skipping inline code.
+// Tags and URLs inside wikilinks, and tags inside URLs, are ignored.
+func (b *Body) scanInline(l sourceLine) {
+	text := maskInlineCode(l.text)
+	taken := make([][2]int, 0)
+
+	for _, m := range wikiLinkRe.FindAllStringSubmatchIndex(text, -1) {
+		b.WikiLinks = append(b.WikiLinks, parseWikiLink(
+			text[m[4]:m[5]],
+			m[3] > m[2],
+			b.span(l.start+m[0], l.start+m[1]),
+		))
+		taken = append(taken, [2]int{m[0], m[1]})
+	}
+
+	for _, m := range urlRe.FindAllStringIndex(text, -1) {
+		value := strings.TrimRight(text[m[0]:m[1]], ".,;:!?*_~")
+		if overlaps(taken, m[0], m[0]+len(value)) {
+			continue
+		}
+		b.URLs = append(b.URLs, URL{Value: value, Span: b.span(l.start+m[0], l.start+m[0]+len(value))})
+		taken = append(taken, [2]int{m[0], m[0] + len(value)})
+	}
+
+	if m := lineFieldRe.FindStringSubmatchIndex(text); m != nil && !overlaps(taken, m[2], m[3]) {
+		b.Fields = append(b.Fields, InlineField{
+			Key:       strings.TrimSpace(text[m[2]:m[3]]),
+			Value:     text[m[4]:m[5]],
+			Span:      b.span(l.start+m[2], l.start+m[5]),
+			ValueSpan: b.span(l.start+m[4], l.start+m[5]),
+		})
+	} else {
+		for _, m := range inlineFieldRe.FindAllStringSubmatchIndex(text, -1) {
+			if overlaps(taken, m[0], m[1]) {
+				continue
+			}
+			b.Fields = append(b.Fields, InlineField{
+				Key:       strings.TrimSpace(text[m[2]:m[3]]),
+				Value:     text[m[4]:m[5]],
+				Span:      b.span(l.start+m[0], l.start+m[1]),
+				ValueSpan: b.span(l.start+m[4], l.start+m[5]),
+				Bracketed: true,
+			})
+		}
+	}
+
+	for _, m := range tagRe.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2]-1, m[3]
+		name := strings.TrimRight(text[m[2]:m[3]], "/-")
+		if !hasNonDigit(name) || overlaps(taken, start, end) {
+			continue
+		}
+		b.Tags = append(b.Tags, Tag{Name: name, Span: b.span(l.start+start, l.start+start+1+len(name))})
+	}
+}
+
+// parseWikiLink splits the inside of a wikilink into its parts.
+func parseWikiLink(inner string, embed bool, span Span) WikiLink {
+	link := WikiLink{Embed: embed, Span: span}
+
+	target, alias, hasAlias := strings.Cut(inner, "|")
+	if hasAlias {
+		link.Alias = strings.TrimSpace(alias)
+	}
+
+	target, subpath, _ := strings.Cut(target, "#")
+	link.Target = strings.TrimSpace(target)
+	if strings.HasPrefix(subpath, "^") {
+		link.BlockID = strings.TrimSpace(subpath[1:])
+	} else {
+		link.Heading = strings.TrimSpace(subpath)
+	}
+
+	return link
+}
+
+// span builds a Span for a byte range of the source.
+func (b *Body) span(start, end int) Span {
+	line := sort.Search(len(b.lineStarts), func(i int) bool { return b.lineStarts[i] > start })
+	column := start + 1
+	if line > 0 {
+		column = start - b.lineStarts[line-1] + 1
+	}
+	return Span{Start: start, End: end, Line: line, Column: column}
+}
+
+// lineStart returns the offset of a 1-based line.
+func (b *Body) lineStart(line int) int {
+	if line-1 >= len(b.lineStarts) {
+		return len(b.Source)
+	}
+	return b.lineStarts[line-1]
+}
+
+// lineEnd returns the offset of the line after a 1-based line, or the end of the source.
+func (b *Body) lineEnd(line int) int {
+	return b.lineStart(line + 1)
+}
+
+// splitSourceLines splits source text into lines. CRLF line endings are excluded from the text.
+func splitSourceLines(src string) []sourceLine {
+	lines := make([]sourceLine, 0, strings.Count(src, "\n")+1)
+	for start := 0; start < len(src); {
+		next := strings.IndexByte(src[start:], '\n')
+		if next == -1 {
+			next = len(src)
+		} else {
+			next += start + 1
+		}
+		end := len(strings.TrimRight(src[:next], "\r\n"))
+		if end < start {
+			end = start
+		}
+		lines = append(lines, sourceLine{start: start, end: end, next: next, text: src[start:end]})
+		start = next
+	}
+	return lines
+}
+
+// closesFence reports whether a line closes a code fence opened with the given fence.
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 || len(trimmed) < len(fence) {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// maskInlineCode replaces inline code spans with spaces, keeping offsets intact.
+func maskInlineCode(text string) string {
+	if !strings.Contains(text, "`") {
+		return text
+	}
+
+	masked := []byte(text)
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for run < len(text) && text[run] == '`' {
+			run++
+		}
+		fence := text[i:run]
+
+		closing := -1
+		for j := run; j < len(text); {
+			k := strings.Index(text[j:], fence)
+			if k == -1 {
+				break
+			}
+			k += j
+			after := k + len(fence)
+			if after == len(text) || text[after] != '`' {
+				if k == 0 || text[k-1] != '`' {
+					closing = k
+					break
+				}
+			}
+			j = after
+			for j < len(text) && text[j] == '`' {
+				j++
+			}
+		}
+		if closing == -1 {
+			i = run
+			continue
+		}
+
+		for j := i; j < closing+len(fence); j++ {
+			masked[j] = ' '
+		}
+		i = closing + len(fence)
+	}
+	return string(masked)
+}
+
+// overlaps reports whether a range intersects any of the taken ranges.
+func overlaps(taken [][2]int, start, end int) bool {
+	for _, r := range taken {
+		if start < r[1] && r[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNonDigit reports whether a tag name contains a non-digit, as required for tags.
+func hasNonDigit(name string) bool {
+	return strings.TrimLeft(name, "0123456789") != ""
+}
+
+// isBlankLineAt reports whether the line starting at offset is blank.
+func isBlankLineAt(src string, offset int) bool {
+	end := strings.IndexByte(src[offset:], '\n')
+	if end == -1 {
+		end = len(src) - offset
+	}
+	return strings.TrimSpace(src[offset:offset+end]) == ""
+}
+
+// isBlankLineBefore reports whether the line ending right before offset is blank.
+func isBlankLineBefore(src string, offset int) bool {
+	start := previousLineStart(src, offset)
+	return strings.TrimSpace(src[start:offset]) == ""
+}
+
+// nextLineStart returns the offset of the line after the one containing offset.
+func nextLineStart(src string, offset int) int {
+	next := strings.IndexByte(src[offset:], '\n')
+	if next == -1 {
+		return len(src)
+	}
+	return offset + next + 1
+}
+
+// previousLineStart returns the start of the line that ends right before offset.
+func previousLineStart(src string, offset int) int {
+	end := offset
+	if end > 0 && src[end-1] == '\n' {
+		end--
+	}
+	return strings.LastIndexByte(src[:end], '\n') + 1
+}
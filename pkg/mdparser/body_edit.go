@@ -0,0 +1,123 @@
+package mdparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReplaceSection replaces the content of a section, keeping its heading and the
+// blank lines around the content. Bytes outside the section content are unchanged.
+//
+// Parameters:
+//   - heading: heading text of the section, matched ignoring case
+//   - content: new section content
+//
+// Returns:
+//   - *Body: the edited body
+//   - error: if no section has the heading
+func (b *Body) ReplaceSection(heading, content string) (*Body, error) {
+	section, ok := b.Section(heading)
+	if !ok {
+		return nil, fmt.Errorf("section %q not found", heading)
+	}
+
+	old := b.Text(section.Content)
+	if content != "" && strings.HasSuffix(old, "\n") && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if old == "" && content != "" {
+		content = b.separateInsert(section.Content.Start, content)
+	}
+
+	return b.splice(section.Content.Start, section.Content.End, content), nil
+}
+
+// AppendToSection adds text at the end of a section's content.
+//
+// Parameters:
+//   - heading: heading text of the section, matched ignoring case
+//   - text: text to append, typically one or more lines
+//
+// Returns:
+//   - *Body: the edited body
+//   - error: if no section has the heading
+func (b *Body) AppendToSection(heading, text string) (*Body, error) {
+	section, ok := b.Section(heading)
+	if !ok {
+		return nil, fmt.Errorf("section %q not found", heading)
+	}
+
+	old := b.Text(section.Content)
+	if old == "" {
+		return b.ReplaceSection(heading, text)
+	}
+
+	insert := text
+	if !strings.HasSuffix(old, "\n") {
+		insert = "\n" + insert
+	} else if !strings.HasSuffix(insert, "\n") {
+		insert += "\n"
+	}
+
+	return b.splice(section.Content.End, section.Content.End, insert), nil
+}
+
+// ToggleCheckbox ticks or unticks the checklist item on a line.
+//
+// Parameters:
+//   - line: 1-based line number of the checklist item
+//
+// Returns:
+//   - *Body: the edited body
+//   - error: if the line holds no checklist item
+func (b *Body) ToggleCheckbox(line int) (*Body, error) {
+	for _, item := range b.Checklist() {
+		if item.Span.Line != line {
+			continue
+		}
+		mark := "x"
+		if item.Checked {
+			mark = " "
+		}
+		return b.splice(item.Checkbox.Start, item.Checkbox.End, mark), nil
+	}
+	return nil, fmt.Errorf("no checklist item on line %d", line)
+}
+
+// SetField changes the value of an inline field.
+//
+// Parameters:
+//   - key: field key, matched ignoring case
+//   - value: new value text
+//
+// Returns:
+//   - *Body: the edited body
+//   - error: if no field has the key
+func (b *Body) SetField(key, value string) (*Body, error) {
+	field, ok := b.Field(key)
+	if !ok {
+		return nil, fmt.Errorf("inline field %q not found", key)
+	}
+
+	start := field.ValueSpan.Start
+	if field.Value == "" && value != "" && b.Source[start-1] == ':' {
+		value = " " + value
+	}
+	return b.splice(start, field.ValueSpan.End, value), nil
+}
+
+// splice replaces a byte range of the source and parses the result.
+func (b *Body) splice(start, end int, text string) *Body {
+	return ParseBody(b.Source[:start] + text + b.Source[end:])
+}
+
+// separateInsert puts text inserted into an empty section on its own lines.
+func (b *Body) separateInsert(at int, text string) string {
+	if at > 0 && b.Source[at-1] != '\n' {
+		text = "\n" + text
+	}
+	if at < len(b.Source) && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
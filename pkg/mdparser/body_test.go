@@ -0,0 +1,246 @@
+package mdparser_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+)
+
+const sampleBody = "# Project\n" +
+	"\n" +
+	"Intro with #work tag and [[Other Note#Plan|the plan]].\n" +
+	"\n" +
+	"## Checklist\n" +
+	"\n" +
+	"- [ ] Write draft\n" +
+	"  - [x] Outline #writing\n" +
+	"1. Plain item\n" +
+	"\n" +
+	"## Log\n" +
+	"\n" +
+	"See https://example.com/page?id=1#frag, and ![[diagram.png]].\n" +
+	"Not a tag: `#code` or issue #123.\n" +
+	"\n" +
+	"```go\n" +
+	"// [[Ignored]] #ignored\n" +
+	"```\n" +
+	"\n" +
+	"### Details\n" +
+	"Deep text\n"
+
+func TestParseBody_Blocks(t *testing.T) {
+	body := mdparser.ParseBody(sampleBody)
+
+	type blockSummary struct {
+		Kind    mdparser.BlockKind
+		Line    int
+		Text    string
+		Level   int
+		Task    bool
+		Checked bool
+	}
+
+	got := make([]blockSummary, 0, len(body.Blocks))
+	for _, b := range body.Blocks {
+		got = append(got, blockSummary{b.Kind, b.Span.Line, b.Text, b.Level, b.Task, b.Checked})
+	}
+
+	want := []blockSummary{
+		{Kind: mdparser.BlockHeading, Line: 1, Text: "Project", Level: 1},
+		{Kind: mdparser.BlockParagraph, Line: 3, Text: "Intro with #work tag and [[Other Note#Plan|the plan]]."},
+		{Kind: mdparser.BlockHeading, Line: 5, Text: "Checklist", Level: 2},
+		{Kind: mdparser.BlockListItem, Line: 7, Text: "Write draft", Task: true},
+		{Kind: mdparser.BlockListItem, Line: 8, Text: "Outline #writing", Task: true, Checked: true},
+		{Kind: mdparser.BlockListItem, Line: 9, Text: "Plain item"},
+		{Kind: mdparser.BlockHeading, Line: 11, Text: "Log", Level: 2},
+		{Kind: mdparser.BlockParagraph, Line: 13, Text: "See https://example.com/page?id=1#frag, and ![[diagram.png]].\nNot a tag: `#code` or issue #123."},
+		{Kind: mdparser.BlockCode, Line: 16, Text: "// [[Ignored]] #ignored"},
+		{Kind: mdparser.BlockHeading, Line: 20, Text: "Details", Level: 3},
+		{Kind: mdparser.BlockParagraph, Line: 21, Text: "Deep text"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if code := body.Blocks[8]; code.Language != "go" {
+		t.Errorf("code language = %q, want %q", code.Language, "go")
+	}
+	if item := body.Blocks[4]; item.Indent != 2 || item.Marker != "-" {
+		t.Errorf("nested item indent = %d, marker = %q", item.Indent, item.Marker)
+	}
+}
+
+func TestParseBody_Inline(t *testing.T) {
+	body := mdparser.ParseBody(sampleBody)
+
+	wantLinks := []mdparser.WikiLink{
+		{Target: "Other Note", Heading: "Plan", Alias: "the plan"},
+		{Target: "diagram.png", Embed: true},
+	}
+	if len(body.WikiLinks) != len(wantLinks) {
+		t.Fatalf("wikilinks = %+v, want %d", body.WikiLinks, len(wantLinks))
+	}
+	for i, want := range wantLinks {
+		got := body.WikiLinks[i]
+		got.Span = mdparser.Span{}
+		if got != want {
+			t.Errorf("wikilink %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if embeds := body.Embeds(); len(embeds) != 1 || embeds[0].Target != "diagram.png" {
+		t.Errorf("Embeds() = %+v", embeds)
+	}
+
+	tags := make([]string, 0)
+	for _, tag := range body.Tags {
+		tags = append(tags, tag.Name)
+	}
+	if want := []string{"work", "writing"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	if len(body.URLs) != 1 || body.URLs[0].Value != "https://example.com/page?id=1#frag" {
+		t.Errorf("urls = %+v", body.URLs)
+	}
+}
+
+func TestParseBody_Positions(t *testing.T) {
+	body := mdparser.ParseBody(sampleBody)
+
+	tag := body.Tags[0]
+	if tag.Span.Line != 3 || tag.Span.Column != 12 || body.Text(tag.Span) != "#work" {
+		t.Errorf("tag span = %+v (%q)", tag.Span, body.Text(tag.Span))
+	}
+
+	link := body.WikiLinks[0]
+	if body.Text(link.Span) != "[[Other Note#Plan|the plan]]" {
+		t.Errorf("link text = %q", body.Text(link.Span))
+	}
+
+	item := body.Checklist()[0]
+	if body.Text(item.Checkbox) != " " || body.Text(item.Span) != "- [ ] Write draft" {
+		t.Errorf("checkbox = %q, item = %q", body.Text(item.Checkbox), body.Text(item.Span))
+	}
+}
+
+func TestBody_Sections(t *testing.T) {
+	body := mdparser.ParseBody(sampleBody)
+
+	tests := []struct {
+		heading     string
+		wantFound   bool
+		wantContent string
+	}{
+		{
+			heading:     "checklist",
+			wantFound:   true,
+			wantContent: "- [ ] Write draft\n  - [x] Outline #writing\n1. Plain item\n",
+		},
+		{
+			heading:     "Details",
+			wantFound:   true,
+			wantContent: "Deep text\n",
+		},
+		{
+			heading:   "Missing",
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			section, found := body.Section(tt.heading)
+			if found != tt.wantFound {
+				t.Fatalf("Section() found = %v, want %v", found, tt.wantFound)
+			}
+			if found && body.Text(section.Content) != tt.wantContent {
+				t.Errorf("content = %q, want %q", body.Text(section.Content), tt.wantContent)
+			}
+		})
+	}
+
+	logSection, _ := body.Section("Log")
+	if got := body.Text(logSection.Span); got[len(got)-len("Deep text"):] != "Deep text" {
+		t.Errorf("Log section should include its subsections, got %q", got)
+	}
+}
+
+func TestBody_Edits(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		edit func(b *mdparser.Body) (*mdparser.Body, error)
+		want string
+	}{
+		{
+			name: "replace section keeps surrounding bytes",
+			src:  "# A\r\nkeep\r\n\n## Log\n\n- old\n\n## Next\ntail",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.ReplaceSection("Log", "- new") },
+			want: "# A\r\nkeep\r\n\n## Log\n\n- new\n\n## Next\ntail",
+		},
+		{
+			name: "replace section with identical content is byte-for-byte",
+			src:  "## Log\n\n- old\n\n## Next\n",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.ReplaceSection("Log", "- old\n") },
+			want: "## Log\n\n- old\n\n## Next\n",
+		},
+		{
+			name: "fill empty section",
+			src:  "## Log\n\n## Next\n",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.ReplaceSection("Log", "- first") },
+			want: "## Log\n- first\n\n## Next\n",
+		},
+		{
+			name: "append to section at end of file without newline",
+			src:  "## Log\n- one",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.AppendToSection("Log", "- two") },
+			want: "## Log\n- one\n- two",
+		},
+		{
+			name: "append to section before next heading",
+			src:  "## Log\n- one\n\n## Next\n",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.AppendToSection("log", "- two") },
+			want: "## Log\n- one\n- two\n\n## Next\n",
+		},
+		{
+			name: "tick checkbox",
+			src:  "- [ ] a\n- [ ] b\n",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.ToggleCheckbox(2) },
+			want: "- [ ] a\n- [x] b\n",
+		},
+		{
+			name: "untick checkbox",
+			src:  "  * [X] done",
+			edit: func(b *mdparser.Body) (*mdparser.Body, error) { return b.ToggleCheckbox(1) },
+			want: "  * [ ] done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.edit(mdparser.ParseBody(tt.src))
+			if err != nil {
+				t.Fatalf("edit error = %v", err)
+			}
+			if got.Source != tt.want {
+				t.Errorf("Source = %q, want %q", got.Source, tt.want)
+			}
+		})
+	}
+}
+
+func TestBody_EditErrors(t *testing.T) {
+	body := mdparser.ParseBody("## Log\n- plain item\n")
+
+	if _, err := body.ReplaceSection("Missing", "x"); err == nil {
+		t.Error("ReplaceSection() expected error for missing section")
+	}
+	if _, err := body.AppendToSection("Missing", "x"); err == nil {
+		t.Error("AppendToSection() expected error for missing section")
+	}
+	if _, err := body.ToggleCheckbox(2); err == nil {
+		t.Error("ToggleCheckbox() expected error for a line without a checkbox")
+	}
+}
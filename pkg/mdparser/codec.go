@@ -0,0 +1,229 @@
+package mdparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Frontmatter format names.
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// FrontmatterCodec reads and writes one frontmatter format. A frontmatter block starts
+// with the opening marker as the first non-blank line of a file, and ends with one of
+// the closing markers.
+type FrontmatterCodec struct {
+	Name  string   // format name, such as "yaml"
+	Open  string   // opening marker line
+	Close []string // closing marker lines, the first one is used when writing
+	// Enclosed is true when the markers are part of the encoded data, as with JSON braces.
+	Enclosed  bool
+	Unmarshal func(data []byte) (map[string]interface{}, error)
+	Marshal   func(fm Frontmatter) ([]byte, error)
+}
+
+// errNotMapping is returned by codecs when the frontmatter is not a set of keys and values.
+var errNotMapping = errors.New("frontmatter must be a mapping of keys to values")
+
+// frontmatterCodecs are the known formats, checked in order when reading a file.
+var frontmatterCodecs = []FrontmatterCodec{
+	{
+		Name:      FormatYAML,
+		Open:      "---",
+		Close:     []string{"---", "..."},
+		Unmarshal: unmarshalYAML,
+		Marshal:   func(fm Frontmatter) ([]byte, error) { return yaml.Marshal(map[string]interface{}(fm)) },
+	},
+	{
+		Name:      FormatTOML,
+		Open:      "+++",
+		Close:     []string{"+++"},
+		Unmarshal: unmarshalTOML,
+		Marshal:   func(fm Frontmatter) ([]byte, error) { return toml.Marshal(map[string]interface{}(fm)) },
+	},
+	{
+		Name:      FormatJSON,
+		Open:      "{",
+		Close:     []string{"}"},
+		Enclosed:  true,
+		Unmarshal: unmarshalJSON,
+		Marshal:   marshalJSON,
+	},
+}
+
+// RegisterFrontmatterCodec adds a frontmatter format, or replaces the one with the same name.
+// Codecs must be registered before documents are parsed.
+//
+// Parameters:
+//   - codec: the codec to register
+//
+// Returns:
+//   - error: if the codec is missing a name, an opening marker or its functions
+func RegisterFrontmatterCodec(codec FrontmatterCodec) error {
+	if codec.Name == "" || codec.Open == "" || len(codec.Close) == 0 || codec.Unmarshal == nil || codec.Marshal == nil {
+		return fmt.Errorf("incomplete frontmatter codec %q", codec.Name)
+	}
+
+	for i, existing := range frontmatterCodecs {
+		if existing.Name == codec.Name {
+			frontmatterCodecs[i] = codec
+			return nil
+		}
+	}
+	frontmatterCodecs = append(frontmatterCodecs, codec)
+	return nil
+}
+
+// codecByOpen returns the codec whose opening marker matches a line.
+func codecByOpen(line []byte) (FrontmatterCodec, bool) {
+	for _, codec := range frontmatterCodecs {
+		if isMarker(line, codec.Open) {
+			return codec, true
+		}
+	}
+	return FrontmatterCodec{}, false
+}
+
+// closes reports whether a line is one of the codec's closing markers.
+func (c FrontmatterCodec) closes(line []byte) bool {
+	for _, marker := range c.Close {
+		if isMarker(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalYAML decodes a YAML mapping. An empty document decodes to an empty map.
+func unmarshalYAML(data []byte) (map[string]interface{}, error) {
+	fm := make(map[string]interface{})
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		return fm, nil
+	}
+	if len(node.Content) != 1 || node.Content[0].Kind != yaml.MappingNode {
+		return nil, errNotMapping
+	}
+	keepTimestampText(&node)
+	if err := node.Decode(&fm); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// keepTimestampText makes timestamps decode as the text written in the file, so that
+// "2024-01-01" stays a date and "2024-01-01T10:00:00Z" keeps its time.
+func keepTimestampText(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		keepTimestampText(child)
+	}
+}
+
+// unmarshalTOML decodes a TOML document.
+func unmarshalTOML(data []byte) (map[string]interface{}, error) {
+	fm := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &fm); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// unmarshalJSON decodes a JSON object. Whole numbers decode to int, like YAML.
+func unmarshalJSON(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	fm, ok := normalizeJSON(v).(map[string]interface{})
+	if !ok {
+		return nil, errNotMapping
+	}
+	return fm, nil
+}
+
+// normalizeJSON converts json.Number values into int or float64.
+func normalizeJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(val.String()); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeJSON(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeJSON(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// marshalJSON encodes frontmatter as an indented JSON object, ending with a newline.
+func marshalJSON(fm Frontmatter) ([]byte, error) {
+	data, err := json.MarshalIndent(map[string]interface{}(fm), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// normalizeFrontmatter converts decoded date values to strings, as YAML timestamps are
+// kept as their text. Times become RFC3339 strings, and TOML local dates and times use
+// their TOML text.
+func normalizeFrontmatter(fm map[string]interface{}) {
+	for k, v := range fm {
+		switch val := v.(type) {
+		case time.Time:
+			fm[k] = val.Format(time.RFC3339)
+		case toml.LocalDate:
+			fm[k] = val.String()
+		case toml.LocalDateTime:
+			fm[k] = val.String()
+		case toml.LocalTime:
+			fm[k] = val.String()
+		}
+	}
+}
+
+// errorLine extracts the 1-based line of a decoding error within data, defaulting to 1.
+func errorLine(err error, data []byte) int {
+	var tomlErr *toml.DecodeError
+	if errors.As(err, &tomlErr) {
+		row, _ := tomlErr.Position()
+		return row
+	}
+
+	var jsonErr *json.SyntaxError
+	if errors.As(err, &jsonErr) && int(jsonErr.Offset) <= len(data) {
+		return bytes.Count(data[:jsonErr.Offset], []byte("\n")) + 1
+	}
+
+	return yamlLine(err)
+}
@@ -0,0 +1,223 @@
+package mdparser_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+func TestParseMarkdownDoc_Formats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]interface{}
+		body    string
+	}{
+		{
+			name:    "TOML frontmatter",
+			content: "+++\ntitle = \"Hugo post\"\ndraft = true\ndate = 2024-03-01\ntags = [\"a\", \"b\"]\n+++\n\nBody",
+			want: map[string]interface{}{
+				"title": "Hugo post",
+				"draft": true,
+				"date":  "2024-03-01",
+				"tags":  []interface{}{"a", "b"},
+			},
+			body: "Body",
+		},
+		{
+			name:    "JSON frontmatter",
+			content: "{\n  \"title\": \"JSON post\",\n  \"count\": 3,\n  \"ratio\": 0.5\n}\n\nBody",
+			want: map[string]interface{}{
+				"title": "JSON post",
+				"count": 3,
+				"ratio": 0.5,
+			},
+			body: "Body",
+		},
+		{
+			name:    "empty JSON frontmatter",
+			content: "{\n}\nBody",
+			want:    map[string]interface{}{},
+			body:    "Body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if err := testutil.CreateTestFile(t, dir, "post.md", tt.content); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join(dir, "post.md"))
+			if err != nil {
+				t.Fatalf("ParseMarkdownDoc() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Frontmatter, tt.want) {
+				t.Errorf("Frontmatter = %#v, want %#v", got.Frontmatter, tt.want)
+			}
+			if got.Content != tt.body {
+				t.Errorf("Content = %q, want %q", got.Content, tt.body)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownDoc_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantLine    int
+		errContains string
+	}{
+		{
+			name:        "invalid TOML",
+			content:     "+++\ntitle = \"ok\"\ndraft = = true\n+++\n",
+			wantLine:    3,
+			errContains: "invalid frontmatter TOML",
+		},
+		{
+			name:        "invalid JSON",
+			content:     "\n{\n  \"title\": \"ok\",\n  \"draft\" true\n}\n",
+			wantLine:    4,
+			errContains: "invalid frontmatter JSON",
+		},
+		{
+			name:        "unclosed TOML",
+			content:     "+++\ntitle = \"ok\"\n",
+			wantLine:    2,
+			errContains: "unclosed frontmatter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if err := testutil.CreateTestFile(t, dir, "broken.md", tt.content); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join(dir, "broken.md"))
+
+			var parseErr *mdparser.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseMarkdownDoc() error = %v, want *ParseError", err)
+			}
+			if parseErr.Line != tt.wantLine || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v (line %d), want line %d containing %q", err, parseErr.Line, tt.wantLine, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestWriteMarkdownDoc_KeepsFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		fm       mdparser.Frontmatter
+		content  string
+		want     string
+	}{
+		{
+			name:     "unchanged TOML is identical",
+			existing: "+++\n# comment\ntitle = 'Post'\n+++\n\nBody\n",
+			fm:       mdparser.Frontmatter{"title": "Post"},
+			content:  "Body",
+			want:     "+++\n# comment\ntitle = 'Post'\n+++\n\nBody\n",
+		},
+		{
+			name:     "changed TOML stays TOML",
+			existing: "+++\ntitle = 'Post'\n+++\n\nBody\n",
+			fm:       mdparser.Frontmatter{"title": "Post", "draft": false},
+			content:  "Body",
+			want:     "+++\ndraft = false\ntitle = 'Post'\n+++\n\nBody\n",
+		},
+		{
+			name:     "changed JSON stays JSON",
+			existing: "{\n  \"title\": \"Post\"\n}\n\nBody",
+			fm:       mdparser.Frontmatter{"title": "Post", "count": 2},
+			content:  "New body",
+			want:     "{\n  \"count\": 2,\n  \"title\": \"Post\"\n}\n\nNew body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if err := testutil.CreateTestFile(t, dir, "post.md", tt.existing); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "post.md")
+
+			if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), tt.fm, tt.content, path); err != nil {
+				t.Fatalf("WriteMarkdownDoc() error = %v", err)
+			}
+
+			testutil.AssertFileContent(t, path, tt.want)
+
+			doc, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), path)
+			if err != nil {
+				t.Fatalf("ParseMarkdownDoc() error = %v", err)
+			}
+			if fmt.Sprint(doc.Frontmatter) != fmt.Sprint(map[string]interface{}(tt.fm)) {
+				t.Errorf("round trip Frontmatter = %v, want %v", doc.Frontmatter, tt.fm)
+			}
+		})
+	}
+}
+
+func TestRegisterFrontmatterCodec(t *testing.T) {
+	codec := mdparser.FrontmatterCodec{
+		Name:  "lines",
+		Open:  "%%%",
+		Close: []string{"%%%"},
+		Unmarshal: func(data []byte) (map[string]interface{}, error) {
+			fm := make(map[string]interface{})
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				key, value, _ := strings.Cut(line, "=")
+				fm[key] = value
+			}
+			return fm, nil
+		},
+		Marshal: func(fm mdparser.Frontmatter) ([]byte, error) {
+			var b strings.Builder
+			for key, value := range fm {
+				fmt.Fprintf(&b, "%s=%v\n", key, value)
+			}
+			return []byte(b.String()), nil
+		},
+	}
+
+	if err := mdparser.RegisterFrontmatterCodec(mdparser.FrontmatterCodec{Name: "broken"}); err == nil {
+		t.Error("RegisterFrontmatterCodec() expected error for an incomplete codec")
+	}
+	if err := mdparser.RegisterFrontmatterCodec(codec); err != nil {
+		t.Fatalf("RegisterFrontmatterCodec() error = %v", err)
+	}
+
+	dir := testutil.CreateTestDirectory(t)
+	if err := testutil.CreateTestFile(t, dir, "custom.md", "%%%\nstatus=open\n%%%\nBody"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "custom.md")
+
+	doc, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), path)
+	if err != nil {
+		t.Fatalf("ParseMarkdownDoc() error = %v", err)
+	}
+	if status, _ := mdparser.GetString(doc.Frontmatter, "status"); status != "open" {
+		t.Errorf("status = %q, want %q", status, "open")
+	}
+
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), mdparser.Frontmatter{"status": "done"}, "Body", path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+	testutil.AssertFileContent(t, path, "%%%\nstatus=done\n%%%\nBody")
+}
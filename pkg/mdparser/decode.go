@@ -0,0 +1,662 @@
+package mdparser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FieldError describes one invalid or missing frontmatter field.
+type FieldError struct {
+	Path    string // key path, such as "review.due" or "tags[2]"
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// DecodeError holds every field error found by Decode or Encode.
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid frontmatter field " + e.Errors[0].Error()
+	}
+	lines := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		lines = append(lines, fieldErr.Error())
+	}
+	return fmt.Sprintf("%d invalid frontmatter fields:\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// Unwrap returns the individual field errors.
+func (e *DecodeError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		errs = append(errs, fieldErr)
+	}
+	return errs
+}
+
+// fieldTag is a parsed `fm` struct tag.
+type fieldTag struct {
+	name       string
+	required   bool
+	omitempty  bool
+	date       bool // date-only values, YYYY-MM-DD
+	timestamp  bool // RFC3339 values
+	extra      bool // map collecting the keys not mapped to other fields
+	enum       []string
+	def        string
+	hasDefault bool
+}
+
+// optionValue is implemented by every *ptr.Option[T].
+type optionValue interface {
+	ElemType() reflect.Type
+	AnyValue() (any, bool)
+	SetAny(v any) error
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode fills a struct from frontmatter, driven by `fm` struct tags:
+//
+//	Title  string              `fm:"title,required"`
+//	Due    ptr.Option[string]  `fm:"due,date"`
+//	Done   time.Time           `fm:"done_at,timestamp"`
+//	Status string              `fm:"status,enum=todo|doing|done,default=todo"`
+//	Tags   []string            `fm:"tags"`
+//	Review struct{ ... }       `fm:"review"`
+//	Extra  map[string]any      `fm:",extra"`
+//
+// The key defaults to the snake_case field name, and `fm:"-"` skips a field.
+// Options:
+//   - required: the key must be present
+//   - default=value: used when the key is missing
+//   - enum=a|b|c: the allowed values, checked for each item of a list
+//   - date: a YYYY-MM-DD date, for time.Time and string fields
+//   - timestamp: an RFC3339 timestamp; time.Time fields accept both forms otherwise
+//   - extra: a map field receiving the keys not mapped to other fields
+//   - omitempty: Encode leaves out zero values
+//
+// ptr.Option fields are None when the key is missing. A single value is accepted for a
+// list field, and numbers and booleans may be written as strings. Decoding does not
+// stop at the first problem: every invalid or missing field is reported in a *DecodeError.
+//
+// Parameters:
+//   - fm: the frontmatter to decode
+//   - v: pointer to the struct to fill
+//
+// Returns:
+//   - error: a *DecodeError listing every invalid field with its key path, or an error
+//     if v is not a pointer to a struct
+func Decode(fm Frontmatter, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a non-nil pointer to a struct, got %T", v)
+	}
+
+	d := &codecState{}
+	d.decodeStruct("", fm, rv.Elem())
+	return d.result()
+}
+
+// Encode converts a struct into frontmatter, using the same `fm` struct tags as Decode.
+// Date fields are written as YYYY-MM-DD strings and other times as RFC3339 strings.
+// None options are left out, as are zero values of omitempty fields.
+//
+// Parameters:
+//   - v: the struct, or a pointer to it
+//
+// Returns:
+//   - Frontmatter: the encoded frontmatter
+//   - error: a *DecodeError listing empty required fields and values outside their enum
+func Encode(v any) (Frontmatter, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("encode source must be a struct, got %T", v)
+	}
+
+	e := &codecState{}
+	fm := e.encodeStruct("", rv)
+	if err := e.result(); err != nil {
+		return nil, err
+	}
+	return Frontmatter(fm), nil
+}
+
+// codecState collects field errors while decoding or encoding.
+type codecState struct {
+	errs []*FieldError
+}
+
+func (s *codecState) fail(path, format string, args ...any) {
+	s.errs = append(s.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *codecState) result() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return &DecodeError{Errors: s.errs}
+}
+
+// decodeStruct fills the fields of a struct value from a map.
+func (s *codecState) decodeStruct(prefix string, fm map[string]interface{}, target reflect.Value) {
+	known := make(map[string]bool)
+	var extra reflect.Value
+
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+		if tag.extra {
+			extra = target.Field(i)
+			continue
+		}
+		known[tag.name] = true
+
+		path := joinPath(prefix, tag.name)
+		raw, present := fm[tag.name]
+		if !present || raw == nil {
+			if !tag.hasDefault {
+				if tag.required {
+					s.fail(path, "is required")
+				}
+				continue
+			}
+			raw = tag.def
+		}
+
+		s.decodeValue(path, raw, target.Field(i), tag)
+	}
+
+	if extra.IsValid() {
+		s.decodeExtra(prefix, fm, known, extra)
+	}
+}
+
+// decodeExtra stores the keys not mapped to struct fields in a map field.
+func (s *codecState) decodeExtra(prefix string, fm map[string]interface{}, known map[string]bool, target reflect.Value) {
+	if target.Kind() != reflect.Map || target.Type().Key().Kind() != reflect.String {
+		s.fail(joinPath(prefix, "*"), "extra field must be a map with string keys")
+		return
+	}
+
+	for key, raw := range fm {
+		if known[key] {
+			continue
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(target.Type()))
+		}
+		elem := reflect.New(target.Type().Elem()).Elem()
+		if s.decodeValue(joinPath(prefix, key), raw, elem, fieldTag{}) {
+			target.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+		}
+	}
+}
+
+// decodeValue converts a raw frontmatter value into the target, reporting whether it succeeded.
+func (s *codecState) decodeValue(path string, raw interface{}, target reflect.Value, tag fieldTag) bool {
+	if opt, ok := target.Addr().Interface().(optionValue); ok {
+		elem := reflect.New(opt.ElemType()).Elem()
+		if !s.decodeValue(path, raw, elem, tag) {
+			return false
+		}
+		if err := opt.SetAny(elem.Interface()); err != nil {
+			s.fail(path, "%v", err)
+			return false
+		}
+		return true
+	}
+
+	if target.Type() == timeType {
+		t, ok := s.decodeTime(path, raw, tag)
+		if ok {
+			target.Set(reflect.ValueOf(t))
+		}
+		return ok
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		str, ok := scalarString(raw)
+		if !ok {
+			s.fail(path, "expected text, got %s", describe(raw))
+			return false
+		}
+		if tag.date {
+			if _, err := time.Parse("2006-01-02", str); err != nil {
+				s.fail(path, "expected a date (YYYY-MM-DD), got %q", str)
+				return false
+			}
+		}
+		if !s.checkEnum(path, str, tag) {
+			return false
+		}
+		target.SetString(str)
+
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if str, isString := raw.(string); isString {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(str))
+			b, ok = parsed, err == nil
+		}
+		if !ok {
+			s.fail(path, "expected true or false, got %s", describe(raw))
+			return false
+		}
+		target.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := ToInt(raw)
+		if !ok || target.OverflowInt(n) {
+			s.fail(path, "expected a whole number, got %s", describe(raw))
+			return false
+		}
+		if !s.checkEnum(path, strconv.FormatInt(n, 10), tag) {
+			return false
+		}
+		target.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := ToInt(raw)
+		if !ok || n < 0 || target.OverflowUint(uint64(n)) {
+			s.fail(path, "expected a non-negative whole number, got %s", describe(raw))
+			return false
+		}
+		target.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(raw)
+		if !ok || target.OverflowFloat(f) {
+			s.fail(path, "expected a number, got %s", describe(raw))
+			return false
+		}
+		target.SetFloat(f)
+
+	case reflect.Slice:
+		return s.decodeSlice(path, raw, target, tag)
+
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			s.fail(path, "expected a mapping, got %s", describe(raw))
+			return false
+		}
+		before := len(s.errs)
+		s.decodeStruct(path, m, target)
+		return len(s.errs) == before
+
+	case reflect.Map:
+		m, ok := raw.(map[string]interface{})
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			s.fail(path, "expected a mapping, got %s", describe(raw))
+			return false
+		}
+		result := reflect.MakeMapWithSize(target.Type(), len(m))
+		valid := true
+		for key, item := range m {
+			elem := reflect.New(target.Type().Elem()).Elem()
+			if s.decodeValue(joinPath(path, key), item, elem, fieldTag{}) {
+				result.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+			} else {
+				valid = false
+			}
+		}
+		target.Set(result)
+		return valid
+
+	case reflect.Interface:
+		if raw != nil && !reflect.TypeOf(raw).AssignableTo(target.Type()) {
+			s.fail(path, "unexpected %s", describe(raw))
+			return false
+		}
+		if raw != nil {
+			target.Set(reflect.ValueOf(raw))
+		}
+
+	default:
+		s.fail(path, "unsupported field type %s", target.Type())
+		return false
+	}
+
+	return true
+}
+
+// decodeSlice decodes a list, or a single value as a list of one item.
+func (s *codecState) decodeSlice(path string, raw interface{}, target reflect.Value, tag fieldTag) bool {
+	items, ok := raw.([]interface{})
+	if !ok {
+		rv := reflect.ValueOf(raw)
+		if rv.Kind() == reflect.Slice {
+			items = make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).Interface()
+			}
+		} else {
+			items = []interface{}{raw}
+		}
+	}
+
+	itemTag := fieldTag{date: tag.date, timestamp: tag.timestamp, enum: tag.enum}
+	result := reflect.MakeSlice(target.Type(), 0, len(items))
+	valid := true
+	for i, item := range items {
+		elem := reflect.New(target.Type().Elem()).Elem()
+		if s.decodeValue(fmt.Sprintf("%s[%d]", path, i), item, elem, itemTag) {
+			result = reflect.Append(result, elem)
+		} else {
+			valid = false
+		}
+	}
+	target.Set(result)
+	return valid
+}
+
+// decodeTime parses a date or timestamp, as allowed by the tag.
+func (s *codecState) decodeTime(path string, raw interface{}, tag fieldTag) (time.Time, bool) {
+	if t, ok := raw.(time.Time); ok {
+		return t, true
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		s.fail(path, "expected a date or timestamp, got %s", describe(raw))
+		return time.Time{}, false
+	}
+	str = strings.TrimSpace(str)
+
+	switch {
+	case tag.date:
+		t, err := time.Parse("2006-01-02", str)
+		if err != nil {
+			s.fail(path, "expected a date (YYYY-MM-DD), got %q", str)
+		}
+		return t, err == nil
+	case tag.timestamp:
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			s.fail(path, "expected an RFC3339 timestamp, got %q", str)
+		}
+		return t, err == nil
+	default:
+		if t, err := time.Parse(time.RFC3339, str); err == nil {
+			return t, true
+		}
+		t, err := time.Parse("2006-01-02", str)
+		if err != nil {
+			s.fail(path, "expected a date (YYYY-MM-DD) or RFC3339 timestamp, got %q", str)
+		}
+		return t, err == nil
+	}
+}
+
+// checkEnum reports whether a value is allowed by the tag's enum, if any.
+func (s *codecState) checkEnum(path, value string, tag fieldTag) bool {
+	if len(tag.enum) == 0 || slices.Contains(tag.enum, value) {
+		return true
+	}
+	s.fail(path, "must be one of %s, got %q", strings.Join(tag.enum, ", "), value)
+	return false
+}
+
+// encodeStruct converts the fields of a struct value into a map.
+func (s *codecState) encodeStruct(prefix string, source reflect.Value) map[string]interface{} {
+	fm := make(map[string]interface{})
+
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Type().Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok || !tag.extra {
+			continue
+		}
+		iter := source.Field(i).MapRange()
+		for iter.Next() {
+			fm[iter.Key().String()] = iter.Value().Interface()
+		}
+	}
+
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Type().Field(i)
+		tag, ok := parseFieldTag(field)
+		if !ok || tag.extra {
+			continue
+		}
+
+		path := joinPath(prefix, tag.name)
+		value, present := s.encodeValue(path, source.Field(i), tag)
+		if !present || (tag.required && source.Field(i).IsZero()) {
+			if tag.required {
+				s.fail(path, "is required")
+			}
+			continue
+		}
+		fm[tag.name] = value
+	}
+
+	return fm
+}
+
+// encodeValue converts a field value for frontmatter, reporting whether it should be written.
+func (s *codecState) encodeValue(path string, source reflect.Value, tag fieldTag) (interface{}, bool) {
+	if opt, ok := source.Interface().(interface{ AnyValue() (any, bool) }); ok {
+		value, valid := opt.AnyValue()
+		if !valid {
+			return nil, false
+		}
+		return s.encodeValue(path, reflect.ValueOf(value), tag)
+	}
+
+	if tag.omitempty && source.IsZero() {
+		return nil, false
+	}
+
+	if source.Type() == timeType {
+		t := source.Interface().(time.Time)
+		if tag.date {
+			return t.Format("2006-01-02"), true
+		}
+		return t.Format(time.RFC3339), true
+	}
+
+	switch source.Kind() {
+	case reflect.String:
+		s.checkEnum(path, source.String(), tag)
+		return source.String(), true
+	case reflect.Bool:
+		return source.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.checkEnum(path, strconv.FormatInt(source.Int(), 10), tag)
+		return int(source.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(source.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return source.Float(), true
+	case reflect.Slice:
+		itemTag := fieldTag{date: tag.date, timestamp: tag.timestamp, enum: tag.enum}
+		items := make([]interface{}, 0, source.Len())
+		for i := 0; i < source.Len(); i++ {
+			if item, ok := s.encodeValue(fmt.Sprintf("%s[%d]", path, i), source.Index(i), itemTag); ok {
+				items = append(items, item)
+			}
+		}
+		return items, true
+	case reflect.Struct:
+		return s.encodeStruct(path, source), true
+	case reflect.Map:
+		result := make(map[string]interface{}, source.Len())
+		iter := source.MapRange()
+		for iter.Next() {
+			if item, ok := s.encodeValue(joinPath(path, iter.Key().String()), iter.Value(), fieldTag{}); ok {
+				result[iter.Key().String()] = item
+			}
+		}
+		return result, true
+	case reflect.Interface:
+		if source.IsNil() {
+			return nil, false
+		}
+		return source.Interface(), true
+	default:
+		s.fail(path, "unsupported field type %s", source.Type())
+		return nil, false
+	}
+}
+
+// parseFieldTag reads the `fm` tag of a struct field.
+// It returns false for unexported fields and fields tagged "-".
+func parseFieldTag(field reflect.StructField) (fieldTag, bool) {
+	if !field.IsExported() {
+		return fieldTag{}, false
+	}
+
+	value := field.Tag.Get("fm")
+	if value == "-" {
+		return fieldTag{}, false
+	}
+
+	parts := strings.Split(value, ",")
+	tag := fieldTag{name: parts[0]}
+	if tag.name == "" {
+		tag.name = snakeCase(field.Name)
+	}
+
+	for _, option := range parts[1:] {
+		switch {
+		case option == "required":
+			tag.required = true
+		case option == "omitempty":
+			tag.omitempty = true
+		case option == "date":
+			tag.date = true
+		case option == "timestamp":
+			tag.timestamp = true
+		case option == "extra":
+			tag.extra = true
+		case strings.HasPrefix(option, "enum="):
+			tag.enum = strings.Split(strings.TrimPrefix(option, "enum="), "|")
+		case strings.HasPrefix(option, "default="):
+			tag.def = strings.TrimPrefix(option, "default=")
+			tag.hasDefault = true
+		}
+	}
+
+	return tag, true
+}
+
+// snakeCase converts a Go field name such as "DoDate" or "URLPath" into "do_date" or "url_path".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// joinPath appends a key to a key path.
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// scalarString converts a scalar value to text.
+func scalarString(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), true
+	case time.Time:
+		return v.Format(time.RFC3339), true
+	default:
+		return "", false
+	}
+}
+
+// ToInt converts a frontmatter whole number, or its text, to int64. YAML decodes numbers
+// to int, TOML to int64 and JSON to float64, so all of them are accepted.
+//
+// Parameters:
+//   - raw: the frontmatter value
+//
+// Returns:
+//   - int64: the number
+//   - bool: false if the value is not a whole number
+func ToInt(raw interface{}) (int64, bool) {
+	switch v := raw.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		return int64(v), v == math.Trunc(v)
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts a number, or its text, to float64.
+func toFloat(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// describe names the type of a raw value for error messages.
+func describe(raw interface{}) string {
+	switch v := raw.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a mapping"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,210 @@
+package mdparser_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+type review struct {
+	Due      string `fm:"due,date,omitempty"`
+	Reviewer string `fm:",required"`
+}
+
+type note struct {
+	Title     string                 `fm:"title,required"`
+	Due       ptr.Option[string]     `fm:"due,date"`
+	CreatedAt time.Time              `fm:",timestamp"`
+	Status    string                 `fm:"status,enum=todo|doing|done,default=todo"`
+	Tags      []string               `fm:"tags"`
+	Estimate  int                    `fm:",omitempty"`
+	Review    review                 `fm:"review"`
+	Extra     map[string]interface{} `fm:",extra"`
+}
+
+func TestDecode(t *testing.T) {
+	created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		fm         mdparser.Frontmatter
+		want       note
+		wantFields map[string]string
+	}{
+		{
+			name: "all fields",
+			fm: mdparser.Frontmatter{
+				"title":      "Plan",
+				"due":        "2024-02-01",
+				"created_at": "2024-01-01T10:00:00Z",
+				"status":     "doing",
+				"tags":       []interface{}{"work", "q1"},
+				"estimate":   "3",
+				"review":     map[string]interface{}{"due": "2024-01-15", "reviewer": "Dana"},
+				"source":     "import",
+			},
+			want: note{
+				Title:     "Plan",
+				Due:       ptr.Some("2024-02-01"),
+				CreatedAt: created,
+				Status:    "doing",
+				Tags:      []string{"work", "q1"},
+				Estimate:  3,
+				Review:    review{Due: "2024-01-15", Reviewer: "Dana"},
+				Extra:     map[string]interface{}{"source": "import"},
+			},
+		},
+		{
+			name: "defaults and single value list",
+			fm: mdparser.Frontmatter{
+				"title":  "Plan",
+				"tags":   "solo",
+				"review": map[string]interface{}{"reviewer": "Dana"},
+			},
+			want: note{
+				Title:  "Plan",
+				Due:    ptr.None[string](),
+				Status: "todo",
+				Tags:   []string{"solo"},
+				Review: review{Reviewer: "Dana"},
+			},
+		},
+		{
+			name: "every invalid field is reported",
+			fm: mdparser.Frontmatter{
+				"due":        "next week",
+				"created_at": "2024-01-01",
+				"status":     "blocked",
+				"tags":       []interface{}{"ok", []interface{}{"nested"}},
+				"estimate":   "lots",
+				"review":     map[string]interface{}{"due": "2024-13-01"},
+			},
+			wantFields: map[string]string{
+				"title":           "is required",
+				"due":             `expected a date (YYYY-MM-DD), got "next week"`,
+				"created_at":      `expected an RFC3339 timestamp, got "2024-01-01"`,
+				"status":          `must be one of todo, doing, done, got "blocked"`,
+				"tags[1]":         "expected text, got a list",
+				"estimate":        `expected a whole number, got "lots"`,
+				"review.due":      `expected a date (YYYY-MM-DD), got "2024-13-01"`,
+				"review.reviewer": "is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got note
+			err := mdparser.Decode(tt.fm, &got)
+
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+
+			var decodeErr *mdparser.DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Decode() error = %v, want *DecodeError", err)
+			}
+			gotFields := make(map[string]string)
+			for _, fieldErr := range decodeErr.Errors {
+				gotFields[fieldErr.Path] = fieldErr.Message
+			}
+			if !reflect.DeepEqual(gotFields, tt.wantFields) {
+				t.Errorf("field errors = %v, want %v", gotFields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestDecode_InvalidTarget(t *testing.T) {
+	var n note
+	for _, target := range []any{n, (*note)(nil), new(string)} {
+		if err := mdparser.Decode(mdparser.Frontmatter{}, target); err == nil {
+			t.Errorf("Decode(%T) expected error", target)
+		}
+	}
+}
+
+func TestDecode_ParsedFile(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	content := "---\ntitle: Plan\ndue: 2024-02-01\ncreated_at: 2024-01-01T10:00:00Z\nreview:\n  reviewer: Dana\n---\n"
+	if err := testutil.CreateTestFile(t, dir, "plan.md", content); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join(dir, "plan.md"))
+	if err != nil {
+		t.Fatalf("ParseMarkdownDoc() error = %v", err)
+	}
+
+	var got note
+	if err := mdparser.Decode(doc.Frontmatter, &got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Due.Value() != "2024-02-01" || !got.CreatedAt.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Decode() = due %q, created_at %v", got.Due.Value(), got.CreatedAt)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	n := note{
+		Title:     "Plan",
+		Due:       ptr.None[string](),
+		CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Status:    "done",
+		Tags:      []string{"work"},
+		Review:    review{Reviewer: "Dana"},
+		Extra:     map[string]interface{}{"source": "import", "title": "ignored"},
+	}
+
+	fm, err := mdparser.Encode(n)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"title":      "Plan",
+		"created_at": "2024-01-01T10:00:00Z",
+		"status":     "done",
+		"tags":       []interface{}{"work"},
+		"review":     map[string]interface{}{"reviewer": "Dana"},
+		"source":     "import",
+	}
+	if !reflect.DeepEqual(map[string]interface{}(fm), want) {
+		t.Errorf("Encode() = %v, want %v", fm, want)
+	}
+
+	var decoded note
+	if err := mdparser.Decode(fm, &decoded); err != nil {
+		t.Fatalf("Decode(Encode()) error = %v", err)
+	}
+	n.Extra = map[string]interface{}{"source": "import"}
+	if !reflect.DeepEqual(decoded, n) {
+		t.Errorf("Decode(Encode()) = %+v, want %+v", decoded, n)
+	}
+}
+
+func TestEncode_Errors(t *testing.T) {
+	_, err := mdparser.Encode(note{Status: "blocked", Review: review{Reviewer: "Dana"}})
+
+	var decodeErr *mdparser.DecodeError
+	if !errors.As(err, &decodeErr) || len(decodeErr.Errors) != 2 {
+		t.Fatalf("Encode() error = %v, want title and status errors", err)
+	}
+	if _, err := mdparser.Encode("text"); err == nil {
+		t.Error("Encode(string) expected error")
+	}
+}
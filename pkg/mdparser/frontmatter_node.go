@@ -0,0 +1,199 @@
+package mdparser
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseFrontmatterNode parses frontmatter YAML into its mapping node.
+// An empty frontmatter yields an empty mapping.
+func parseFrontmatterNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("frontmatter is not a mapping")
+	}
+	return doc.Content[0], nil
+}
+
+// patchFrontmatterNode applies the desired frontmatter to a mapping node in place.
+// Keys keep their position, comments and style; only values that differ are replaced.
+// Keys missing from fm are removed, and new keys are appended in alphabetical order.
+//
+// Parameters:
+//   - mapping: the mapping node parsed from the existing frontmatter
+//   - fm: the desired frontmatter
+//
+// Returns:
+//   - bool: whether the mapping was changed
+//   - error: if a value cannot be encoded
+func patchFrontmatterNode(mapping *yaml.Node, fm Frontmatter) (bool, error) {
+	changed := false
+	seen := make(map[string]bool, len(fm))
+	content := make([]*yaml.Node, 0, len(mapping.Content))
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		desired, ok := fm[key.Value]
+		if !ok || seen[key.Value] {
+			changed = true
+			continue
+		}
+		seen[key.Value] = true
+
+		desiredNode, err := encodeValue(desired)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode %s: %w", key.Value, err)
+		}
+		if !sameValue(value, desiredNode) {
+			replaceValue(value, desiredNode)
+			changed = true
+		}
+		content = append(content, key, value)
+	}
+
+	added := make([]string, 0)
+	for key := range fm {
+		if !seen[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	for _, key := range added {
+		valueNode, err := encodeValue(fm[key])
+		if err != nil {
+			return false, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+		changed = true
+	}
+
+	mapping.Content = content
+	return changed, nil
+}
+
+// encodeFrontmatterNode serializes a mapping node, using the indentation of the original YAML.
+func encodeFrontmatterNode(mapping *yaml.Node, original []byte) ([]byte, error) {
+	if len(mapping.Content) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(detectIndent(original))
+	if err := encoder.Encode(mapping); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// detectIndent returns the indentation width of the first indented line of YAML text,
+// defaulting to 4 which matches yaml.Marshal.
+func detectIndent(data []byte) int {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent > 0 && len(trimmed) > 0 && trimmed[0] != '#' {
+			if indent < 2 {
+				return 2
+			}
+			return indent
+		}
+	}
+	return 4
+}
+
+// encodeValue converts a Go value into a YAML node.
+func encodeValue(v interface{}) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// sameValue reports whether two nodes hold the same data, ignoring style and comments.
+// A quoted string and an unquoted timestamp with the same text are considered equal.
+func sameValue(a, b *yaml.Node) bool {
+	if a.Kind == yaml.AliasNode && a.Alias != nil {
+		a = a.Alias
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case yaml.ScalarNode:
+		if a.Value != b.Value {
+			return false
+		}
+		aTag, bTag := a.ShortTag(), b.ShortTag()
+		return aTag == bTag || isTextTag(aTag) && isTextTag(bTag)
+	case yaml.SequenceNode, yaml.MappingNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := range a.Content {
+			if !sameValue(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// isTextTag reports whether a tag is written as plain text: strings and timestamps.
+func isTextTag(tag string) bool {
+	return tag == "!!str" || tag == "!!timestamp"
+}
+
+// isTimestamp reports whether a plain YAML scalar with this text resolves to a timestamp.
+func isTimestamp(value string) bool {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return false
+	}
+	_, ok := v.(time.Time)
+	return ok
+}
+
+// replaceValue overwrites a node's data with another node's, keeping the original
+// comments, and the original quoting or flow style where it still applies.
+func replaceValue(target, source *yaml.Node) {
+	style, tag := source.Style, source.Tag
+	switch {
+	case target.Kind == yaml.ScalarNode && source.Kind == yaml.ScalarNode &&
+		source.ShortTag() == "!!str" && target.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0:
+		style = target.Style
+	case target.Kind == yaml.ScalarNode && source.Kind == yaml.ScalarNode &&
+		target.ShortTag() == "!!timestamp" && source.ShortTag() == "!!str" && isTimestamp(source.Value):
+		// keep unquoted timestamps unquoted
+		style, tag = 0, "!!timestamp"
+	case target.Kind == source.Kind && source.Kind != yaml.ScalarNode:
+		style = target.Style & yaml.FlowStyle
+	}
+
+	target.Kind = source.Kind
+	target.Tag = tag
+	target.Value = source.Value
+	target.Content = source.Content
+	target.Alias = nil
+	target.Anchor = ""
+	target.Style = style
+}
@@ -0,0 +1,116 @@
+package mdparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// withInlineFields adds the Dataview inline fields of the content to the frontmatter.
+// Frontmatter keys take precedence, and the first field wins when a key repeats.
+// Values "true" and "false" become bools and numbers become int or float64; other
+// values are kept as text.
+//
+// Parameters:
+//   - fm: the decoded frontmatter, may be nil
+//   - content: the document content
+//
+// Returns:
+//   - map[string]interface{}: the frontmatter with inline fields, nil if both are empty
+func withInlineFields(fm map[string]interface{}, content string) map[string]interface{} {
+	if !strings.Contains(content, "::") {
+		return fm
+	}
+
+	for _, field := range ParseBody(content).Fields {
+		if _, exists := fm[field.Key]; exists {
+			continue
+		}
+		if fm == nil {
+			fm = make(map[string]interface{})
+		}
+		fm[field.Key] = parseInlineValue(field.Value)
+	}
+	return fm
+}
+
+// parseInlineValue converts the text of an inline field value.
+func parseInlineValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(value); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strings.ContainsAny(value, "0123456789") {
+		return f
+	}
+	return value
+}
+
+// formatInlineValue converts a frontmatter value into inline field text.
+func formatInlineValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case []string:
+		return strings.Join(val, ", ")
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			items = append(items, formatInlineValue(item))
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// moveInlineFields writes frontmatter values that live in inline fields back into the content.
+// A key is kept inline when the content has a field for it and the existing frontmatter
+// does not have the key.
+//
+// Parameters:
+//   - fm: the frontmatter to write
+//   - content: the content to write
+//   - existing: frontmatter of the file on disk, nil if there is none
+//
+// Returns:
+//   - Frontmatter: the frontmatter without the inline keys
+//   - string: the content with updated inline field values
+func moveInlineFields(fm Frontmatter, content string, existing map[string]interface{}) (Frontmatter, string) {
+	if !strings.Contains(content, "::") {
+		return fm, content
+	}
+
+	body := ParseBody(content)
+	remaining := make(Frontmatter, len(fm))
+	for key, value := range fm {
+		remaining[key] = value
+	}
+
+	for key, value := range fm {
+		if _, inFrontmatter := existing[key]; inFrontmatter {
+			continue
+		}
+		field, ok := body.Field(key)
+		if !ok || field.Key != key {
+			continue
+		}
+		delete(remaining, key)
+
+		if text := formatInlineValue(value); text != field.Value {
+			if edited, err := body.SetField(key, text); err == nil {
+				body = edited
+			}
+		}
+	}
+
+	return remaining, body.Source
+}
@@ -0,0 +1,88 @@
+package mdparser_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+const dataviewNote = `---
+status: active
+---
+
+due:: 2026-10-20
+- reviewed:: true
+Estimate is [hours:: 3] and (owner:: Dana).
+status:: ignored, frontmatter wins
+created_at:: 2024-01-01T10:00:00Z
+
+` + "```" + `
+skipped:: yes
+` + "```"
+
+func TestParseMarkdownDoc_InlineFields(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	if err := testutil.CreateTestFile(t, dir, "note.md", dataviewNote); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join(dir, "note.md"))
+	if err != nil {
+		t.Fatalf("ParseMarkdownDoc() error = %v", err)
+	}
+	fm := mdparser.Frontmatter(doc.Frontmatter)
+
+	if due, ok := mdparser.GetString(fm, "due"); !ok || due != "2026-10-20" {
+		t.Errorf("GetString(due) = %q, %v", due, ok)
+	}
+	if reviewed, ok := mdparser.GetBool(fm, "reviewed"); !ok || !reviewed {
+		t.Errorf("GetBool(reviewed) = %v, %v", reviewed, ok)
+	}
+	if created, ok := mdparser.GetTime(fm, "created_at"); !ok || !created.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("GetTime(created_at) = %v, %v", created, ok)
+	}
+	if owner, _ := mdparser.GetString(fm, "owner"); owner != "Dana" {
+		t.Errorf("owner = %q, want %q", owner, "Dana")
+	}
+	if hours := fm["hours"]; hours != 3 {
+		t.Errorf("hours = %#v, want 3", hours)
+	}
+	if status, _ := mdparser.GetString(fm, "status"); status != "active" {
+		t.Errorf("status = %q, want frontmatter value", status)
+	}
+	if _, ok := fm["skipped"]; ok {
+		t.Error("fields inside code blocks should be ignored")
+	}
+}
+
+func TestWriteMarkdownDoc_InlineFields(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	existing := "---\nstatus: active\n---\n\ndue:: 2026-10-20\nSee [owner:: Dana].\n"
+	if err := testutil.CreateTestFile(t, dir, "note.md", existing); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "note.md")
+
+	doc, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), path)
+	if err != nil {
+		t.Fatalf("ParseMarkdownDoc() error = %v", err)
+	}
+
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), doc.Frontmatter, doc.Content, path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+	testutil.AssertFileContent(t, path, existing)
+
+	fm := mdparser.Frontmatter(doc.Frontmatter)
+	fm["due"] = "2026-11-01"
+	fm["owner"] = "Sam"
+	fm["priority"] = "high"
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), fm, doc.Content, path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+	testutil.AssertFileContent(t, path, "---\nstatus: active\npriority: high\n---\n\ndue:: 2026-11-01\nSee [owner:: Sam].")
+}
@@ -2,12 +2,14 @@ package mdparser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,90 +20,286 @@ type MarkdownDocument struct {
 	Content     string
 }
 
-// ParseMarkdownDoc parses raw markdown text into a structured MarkdownDocument.
-func ParseMarkdownDoc(filePath string) (MarkdownDocument, error) {
-	baseFile := filepath.Base(filePath)
-	title := strings.TrimSuffix(baseFile, filepath.Ext(baseFile))
+// ParseError describes a malformed markdown document, pointing at the offending line.
+type ParseError struct {
+	Line    int // 1-based line number in the file
+	Message string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid markdown at line %d: %s", e.Line, e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// yamlErrorLine matches the line number reported in yaml.v3 error messages.
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// rawDocument is a markdown file split into its parts, kept verbatim so that
+// unchanged parts can be written back untouched.
+type rawDocument struct {
+	head        []byte // BOM, leading blank lines and the opening marker line
+	frontmatter []byte // data between the markers, without the markers
+	closing     []byte // the closing marker line, with its line ending
+	body        []byte // everything after the closing marker line
+	markerStart int    // offset of the opening marker line within head
+	openLine    int    // 1-based line number of the opening marker
+	crlf        bool   // whether the file uses CRLF line endings
+	codec       FrontmatterCodec
+}
+
+// encoded returns the frontmatter data as passed to the codec.
+func (raw rawDocument) encoded() []byte {
+	if !raw.codec.Enclosed {
+		return raw.frontmatter
+	}
+	data := append([]byte{}, raw.head[raw.markerStart:]...)
+	data = append(data, raw.frontmatter...)
+	return append(data, raw.closing...)
+}
+
+// ParseMarkdownDoc parses a markdown file into a structured MarkdownDocument.
+// The title is the file name without its extension. See Parse for the accepted format.
+//
+// Parameters:
+//   - fsys: filesystem holding the file
+//   - filePath: path of the markdown file
+//
+// Returns:
+//   - MarkdownDocument: the parsed document
+//   - error: reading errors, or a *ParseError with the line of a malformed frontmatter
+func ParseMarkdownDoc(fsys afero.Fs, filePath string) (MarkdownDocument, error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return MarkdownDocument{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	return Parse(f, filePath)
+}
+
+// Parse reads a markdown document from a reader.
+//
+// Frontmatter follows the common convention: a block of YAML starting with a "---" line,
+// which must be the first non-blank line of the file, and ending with a "---" or "..." line.
+// TOML frontmatter between "+++" lines and JSON frontmatter between "{" and "}" lines are
+// also recognized, as are formats added with RegisterFrontmatterCodec.
+// A UTF-8 byte order mark and CRLF line endings are accepted. Any other "---" line, before
+// or after the frontmatter, is a thematic break and belongs to the content.
+//
+// Dataview inline fields in the content, such as "due:: 2024-01-01", are added to the
+// frontmatter unless the frontmatter already has the key.
+//
+// Parameters:
+//   - r: source of the document
+//   - name: name of the document, such as a file path; may be empty
+//   - opts: options such as WithTitleSource; by default the title comes from the name,
+//     see TitleFromVaultFile
+//
+// Returns:
+//   - MarkdownDocument: the parsed document
+//   - error: reading errors, or a *ParseError with the line of a malformed frontmatter
+func Parse(r io.Reader, name string, opts ...ParseOption) (MarkdownDocument, error) {
+	options := parseOptions{title: TitleFromVaultFile}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-	data, err := os.ReadFile(filePath)
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return MarkdownDocument{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	content := string(data)
+	doc, err := parseMarkdown(data)
+	if err != nil {
+		return MarkdownDocument{}, err
+	}
+	doc.Title = options.title(name, doc)
 
-	// Empty file is valid
-	if strings.TrimSpace(content) == "" {
-		return MarkdownDocument{Title: title}, nil
+	return doc, nil
+}
+
+// parseMarkdown parses markdown bytes into a document without a title.
+func parseMarkdown(data []byte) (MarkdownDocument, error) {
+	raw, found, err := splitRawDocument(data)
+	if err != nil {
+		return MarkdownDocument{}, err
 	}
 
-	// First check whitespace
-	if strings.TrimLeft(content, " \t\n") != content {
-		return MarkdownDocument{}, fmt.Errorf("invalid markdown: whitespace before frontmatter")
+	if !found {
+		content := normalizeContent(bytes.TrimPrefix(data, utf8BOM))
+		return MarkdownDocument{
+			Frontmatter: withInlineFields(nil, content),
+			Content:     content,
+		}, nil
 	}
 
-	// Check for frontmatter markers
-	trimmedContent := strings.TrimSpace(content)
-	if strings.HasPrefix(trimmedContent, "--") {
-		// If it starts with any number of dashes but not exactly 3, it's invalid
-		if !strings.HasPrefix(trimmedContent, "---") || strings.HasPrefix(trimmedContent, "----") {
-			return MarkdownDocument{}, fmt.Errorf("invalid markdown: incorrect frontmatter markers")
+	fm, err := decodeFrontmatter(raw)
+	if err != nil {
+		return MarkdownDocument{}, err
+	}
+
+	content := normalizeContent(raw.body)
+	return MarkdownDocument{
+		Frontmatter: withInlineFields(fm, content),
+		Content:     content,
+	}, nil
+}
+
+// splitRawDocument locates the frontmatter block of a markdown file.
+//
+// Parameters:
+//   - data: raw file content
+//
+// Returns:
+//   - rawDocument: the split document
+//   - bool: false if the file has no frontmatter
+//   - error: a *ParseError if a frontmatter starting with a field is never closed
+func splitRawDocument(data []byte) (rawDocument, bool, error) {
+	raw := rawDocument{crlf: bytes.Contains(data, []byte("\r\n"))}
+
+	offset := 0
+	if bytes.HasPrefix(data, utf8BOM) {
+		offset = len(utf8BOM)
+	}
+
+	lineNo := 0
+	opened := false
+	fmStart := 0
+
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end == -1 {
+			end = len(data)
+		} else {
+			end += offset + 1
 		}
-	} else if strings.Contains(content, "---") {
-		return MarkdownDocument{}, fmt.Errorf("invalid markdown: content before frontmatter")
-	} else {
-		return MarkdownDocument{Title: title, Content: trimmedContent}, nil
-	}
-
-	// Look for closing frontmatter marker
-	lines := strings.Split(content, "\n")
-	var closingIndex int
-	for i := 1; i < len(lines); i++ {
-		if lines[i] == "---" {
-			closingIndex = i
-			break
+		line := data[offset:end]
+		lineNo++
+
+		switch {
+		case !opened:
+			if len(bytes.TrimSpace(line)) == 0 {
+				break
+			}
+			codec, ok := codecByOpen(line)
+			if !ok {
+				return rawDocument{}, false, nil
+			}
+			opened = true
+			raw.codec = codec
+			raw.openLine = lineNo
+			raw.markerStart = offset
+			raw.head = data[:end]
+			fmStart = end
+
+		case raw.codec.closes(line):
+			raw.frontmatter = data[fmStart:offset]
+			raw.closing = line
+			raw.body = data[end:]
+			return raw, true, nil
 		}
+
+		offset = end
 	}
 
-	if closingIndex == 0 {
-		return MarkdownDocument{}, fmt.Errorf("invalid markdown: unclosed frontmatter")
+	if !opened || !looksLikeField(firstLine(data[fmStart:])) {
+		// A leading marker that is never closed and not followed by a field, such as a
+		// horizontal rule at the top of a note, is content.
+		return rawDocument{}, false, nil
 	}
 
-	// Check for multiple frontmatter blocks
-	firstClose := strings.Index(content[3:], "\n---\n")
-	if firstClose != -1 {
-		secondBlock := strings.Index(content[firstClose+7:], "\n---\n")
-		if secondBlock != -1 {
-			return MarkdownDocument{}, fmt.Errorf("invalid markdown: multiple frontmatter blocks")
-		}
+	return rawDocument{}, false, &ParseError{
+		Line:    lineNo,
+		Message: fmt.Sprintf("unclosed frontmatter opened at line %d", raw.openLine),
 	}
+}
 
-	// Parse frontmatter
-	frontmatter := strings.Join(lines[1:closingIndex], "\n")
-	fm := make(map[string]interface{})
+// fieldPattern matches the start of a frontmatter field: "key:" in YAML, "key =" in
+// TOML or "\"key\":" in JSON.
+var fieldPattern = regexp.MustCompile(`^"?[A-Za-z_][\w.-]*"?\s*[:=]`)
 
-	decoder := yaml.NewDecoder(bytes.NewReader([]byte(frontmatter)))
-	decoder.KnownFields(true)
+// looksLikeField reports whether a line starts a frontmatter field.
+func looksLikeField(line []byte) bool {
+	return fieldPattern.Match(bytes.TrimSpace(line))
+}
 
-	if err := decoder.Decode(&fm); err != nil {
-		return MarkdownDocument{}, fmt.Errorf("invalid frontmatter YAML: %w", err)
+// firstLine returns the first line of data that is not blank.
+func firstLine(data []byte) []byte {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line
+		}
 	}
+	return nil
+}
 
-	// Convert time values to strings
-	for k, v := range fm {
-		if t, ok := v.(time.Time); ok {
-			fm[k] = t.Format("2006-01-02")
+// isMarker reports whether a line consists of the marker, optionally followed by
+// trailing whitespace. Markers must not be indented.
+func isMarker(line []byte, marker string) bool {
+	return string(bytes.TrimRight(line, " \t\r\n")) == marker
+}
+
+// decodeFrontmatter decodes a frontmatter block with its codec.
+// Dates and timestamps are decoded as strings.
+//
+// Parameters:
+//   - raw: the split document
+//
+// Returns:
+//   - map[string]interface{}: the decoded frontmatter, empty for an empty block
+//   - error: a *ParseError pointing at the invalid line
+func decodeFrontmatter(raw rawDocument) (map[string]interface{}, error) {
+	if len(bytes.TrimSpace(raw.frontmatter)) == 0 {
+		return make(map[string]interface{}), nil
+	}
+
+	data := raw.encoded()
+	fm, err := raw.codec.Unmarshal(data)
+	if errors.Is(err, errNotMapping) {
+		return nil, &ParseError{
+			Line:    raw.openLine + 1,
+			Message: errNotMapping.Error(),
+		}
+	}
+	if err != nil {
+		line := raw.openLine + errorLine(err, data)
+		if raw.codec.Enclosed {
+			line--
+		}
+		return nil, &ParseError{
+			Line:    line,
+			Message: fmt.Sprintf("invalid frontmatter %s", strings.ToUpper(raw.codec.Name)),
+			Err:     err,
 		}
 	}
 
-	remainingContent := ""
-	if closingIndex+1 < len(lines) {
-		remainingContent = strings.TrimSpace(strings.Join(lines[closingIndex+1:], "\n"))
+	normalizeFrontmatter(fm)
+	return fm, nil
+}
+
+// yamlLine extracts the 1-based line number from a yaml.v3 error, defaulting to 1.
+func yamlLine(err error) int {
+	var typeErr *yaml.TypeError
+	msg := err.Error()
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		if line, err := strconv.Atoi(m[1]); err == nil {
+			return line
+		}
 	}
+	return 1
+}
 
-	return MarkdownDocument{
-		Title:       title,
-		Frontmatter: fm,
-		Content:     remainingContent,
-	}, nil
+// normalizeContent converts line endings to LF and trims surrounding whitespace.
+func normalizeContent(data []byte) string {
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
 }
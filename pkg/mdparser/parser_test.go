@@ -1,6 +1,7 @@
 package mdparser_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/avivSarig/cerebgo/pkg/mdparser"
 	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
 )
 
 func TestParseMarkdownDocFileHandling(t *testing.T) {
@@ -67,30 +69,54 @@ key: value
 			},
 		},
 		{
-			name: "whitespace before frontmatter",
+			name: "indented marker is content",
 			content: ` ---
 key: value
 ---`,
-			wantErr:     true,
-			errContains: "whitespace before frontmatter",
+			want: mdparser.MarkdownDocument{
+				Title:   "test",
+				Content: "---\nkey: value\n---",
+			},
 		},
 		{
-			name: "invalid frontmatter marker",
+			name: "four dashes is a thematic break",
 			content: `----
 key: value
 ---`,
-			wantErr:     true,
-			errContains: "incorrect frontmatter markers",
+			want: mdparser.MarkdownDocument{
+				Title:   "test",
+				Content: "----\nkey: value\n---",
+			},
 		},
 		{
 			name: "unclosed frontmatter",
 			content: `---
 key: value`,
 			wantErr:     true,
-			errContains: "unclosed frontmatter",
+			errContains: "line 2: unclosed frontmatter opened at line 1",
+		},
+		{
+			name: "unclosed horizontal rule at the top is content",
+			content: `---
+
+Some text`,
+			want: mdparser.MarkdownDocument{
+				Title:   "test",
+				Content: "---\n\nSome text",
+			},
 		},
 		{
-			name: "multiple frontmatter blocks",
+			name: "horizontal rule before a heading is content",
+			content: `---
+# Title
+body`,
+			want: mdparser.MarkdownDocument{
+				Title:   "test",
+				Content: "---\n# Title\nbody",
+			},
+		},
+		{
+			name: "thematic breaks after frontmatter belong to content",
 			content: `---
 key1: value1
 ---
@@ -98,22 +124,69 @@ content
 ---
 key2: value2
 ---`,
-			wantErr:     true,
-			errContains: "multiple frontmatter blocks",
+			want: mdparser.MarkdownDocument{
+				Title: "test",
+				Frontmatter: map[string]interface{}{
+					"key1": "value1",
+				},
+				Content: "content\n---\nkey2: value2\n---",
+			},
 		},
 		{
 			name: "invalid YAML in frontmatter",
 			content: `---
+title: ok
 key: : invalid : yaml :
 ---`,
 			wantErr:     true,
-			errContains: "invalid frontmatter YAML",
+			errContains: "line 3: invalid frontmatter YAML",
 		},
 		{
-			name:        "dashes in content without frontmatter",
-			content:     "Some content\n---\nMore content",
+			name: "non-mapping frontmatter",
+			content: `---
+- item
+---`,
 			wantErr:     true,
-			errContains: "content before frontmatter",
+			errContains: "line 2: frontmatter must be a mapping",
+		},
+		{
+			name:    "dashes in content without frontmatter",
+			content: "Some content\n---\nMore content",
+			want: mdparser.MarkdownDocument{
+				Title:   "test",
+				Content: "Some content\n---\nMore content",
+			},
+		},
+		{
+			name:    "CRLF line endings and BOM",
+			content: "\uFEFF---\r\nkey: value\r\n---\r\n\r\nLine one\r\nLine two\r\n",
+			want: mdparser.MarkdownDocument{
+				Title: "test",
+				Frontmatter: map[string]interface{}{
+					"key": "value",
+				},
+				Content: "Line one\nLine two",
+			},
+		},
+		{
+			name:    "leading blank lines and dots closing marker",
+			content: "\n  \n---\nkey: value\n...\nBody",
+			want: mdparser.MarkdownDocument{
+				Title: "test",
+				Frontmatter: map[string]interface{}{
+					"key": "value",
+				},
+				Content: "Body",
+			},
+		},
+		{
+			name:    "empty frontmatter",
+			content: "---\n---\n\nJust content",
+			want: mdparser.MarkdownDocument{
+				Title:       "test",
+				Frontmatter: map[string]interface{}{},
+				Content:     "Just content",
+			},
 		},
 	}
 
@@ -128,7 +201,7 @@ key: : invalid : yaml :
 			}
 
 			// Run test
-			got, err := mdparser.ParseMarkdownDoc(filepath)
+			got, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath)
 
 			// Verify error cases
 			if tt.wantErr {
@@ -193,7 +266,7 @@ func TestParseMarkdownDoc_FileErrors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			filepath := tt.setup(t)
-			_, err := mdparser.ParseMarkdownDoc(filepath)
+			_, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath)
 
 			if tt.wantErr {
 				if err == nil {
@@ -209,6 +282,231 @@ func TestParseMarkdownDoc_FileErrors(t *testing.T) {
 	}
 }
 
+func TestParseMarkdownDoc_ErrorLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int
+	}{
+		{
+			name:     "invalid YAML after blank lines",
+			content:  "\n\n---\ntitle: ok\nkey: : invalid\n---\n",
+			wantLine: 5,
+		},
+		{
+			name:     "unclosed frontmatter",
+			content:  "---\ntitle: ok\n\nbody",
+			wantLine: 4,
+		},
+		{
+			name:     "scalar frontmatter",
+			content:  "---\njust text\n---\n",
+			wantLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if err := testutil.CreateTestFile(t, dir, "broken.md", tt.content); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join(dir, "broken.md"))
+
+			var parseErr *mdparser.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseMarkdownDoc() error = %v, want *ParseError", err)
+			}
+			if parseErr.Line != tt.wantLine {
+				t.Errorf("ParseError.Line = %d, want %d (%v)", parseErr.Line, tt.wantLine, err)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownDoc_ObsidianCorpus(t *testing.T) {
+	tests := []struct {
+		file string
+		want mdparser.MarkdownDocument
+	}{
+		{
+			file: "bom-crlf.md",
+			want: mdparser.MarkdownDocument{
+				Title: "bom-crlf",
+				Frontmatter: map[string]interface{}{
+					"title": "Windows note",
+					"tags":  []interface{}{"inbox"},
+				},
+				Content: "Written on Windows.",
+			},
+		},
+		{
+			file: "daily-note.md",
+			want: mdparser.MarkdownDocument{
+				Title: "daily-note",
+				Frontmatter: map[string]interface{}{
+					"aliases": []interface{}{"Today"},
+					"created": "2024-03-01",
+					"tags":    []interface{}{"daily"},
+				},
+				Content: "# Friday\n\n- [ ] Review inbox\n\n---\n\nNotes after a thematic break.",
+			},
+		},
+		{
+			file: "dots-closing.md",
+			want: mdparser.MarkdownDocument{
+				Title:       "dots-closing",
+				Frontmatter: map[string]interface{}{"status": "draft"},
+				Content:     "Body after a dots marker.",
+			},
+		},
+		{
+			file: "empty-frontmatter.md",
+			want: mdparser.MarkdownDocument{
+				Title:       "empty-frontmatter",
+				Frontmatter: map[string]interface{}{},
+				Content:     "Empty block.",
+			},
+		},
+		{
+			file: "no-frontmatter.md",
+			want: mdparser.MarkdownDocument{
+				Title:   "no-frontmatter",
+				Content: "Just a quick thought.\n\n---\n\nWith a separator.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filepath.Join("testdata", "obsidian", tt.file))
+			if err != nil {
+				t.Fatalf("ParseMarkdownDoc() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMarkdownDoc() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownDocFrontmatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		filename string
+		want     mdparser.MarkdownDocument
+		wantErr  bool
+	}{
+		{
+			name: "valid frontmatter and content",
+			content: `---
+key: value
+date: 2024-01-01
+---
+
+# Content here`,
+			filename: "test.md",
+			want: mdparser.MarkdownDocument{
+				Title: "test",
+				Frontmatter: mdparser.Frontmatter{
+					"key":  "value",
+					"date": "2024-01-01",
+				},
+				Content: "# Content here",
+			},
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			filename: "empty.md",
+			want: mdparser.MarkdownDocument{
+				Title: "empty",
+			},
+		},
+		{
+			name: "invalid frontmatter",
+			content: `---
+invalid: [yaml
+---`,
+			filename: "invalid.md",
+			wantErr:  true,
+		},
+		{
+			name: "thematic break after frontmatter",
+			content: `---
+key: value
+---
+content
+---
+more: stuff
+---`,
+			filename: "multiple.md",
+			want: mdparser.MarkdownDocument{
+				Title:       "multiple",
+				Frontmatter: mdparser.Frontmatter{"key": "value"},
+				Content:     "content\n---\nmore: stuff\n---",
+			},
+		},
+		{
+			name: "thematic break without frontmatter",
+			content: `Some content
+---
+key: value
+---`,
+			filename: "breaks.md",
+			want: mdparser.MarkdownDocument{
+				Title:   "breaks",
+				Content: "Some content\n---\nkey: value\n---",
+			},
+		},
+		{
+			name: "unclosed frontmatter",
+			content: `---
+key: value
+content`,
+			filename: "unclosed.md",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create temporary file
+			tmpFile := testutil.CreateTestDirectory(t)
+			filePath := tmpFile + "/" + tt.filename
+			err := testutil.CreateTestFile(t, tmpFile, tt.filename, tt.content)
+			if err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			got, err := mdparser.ParseMarkdownDoc(afero.NewOsFs(), filePath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMarkdownDoc() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if got.Title != tt.want.Title {
+					t.Errorf("Title = %v, want %v", got.Title, tt.want.Title)
+				}
+				if got.Content != tt.want.Content {
+					t.Errorf("Content = %v, want %v", got.Content, tt.want.Content)
+				}
+				// Compare frontmatter
+				if len(got.Frontmatter) != len(tt.want.Frontmatter) {
+					t.Errorf("Frontmatter length = %v, want %v", len(got.Frontmatter), len(tt.want.Frontmatter))
+				}
+				for k, v := range tt.want.Frontmatter {
+					if got.Frontmatter[k] != v {
+						t.Errorf("Frontmatter[%v] = %v, want %v", k, got.Frontmatter[k], v)
+					}
+				}
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring.
 func contains(s, substr string) bool {
 	return len(substr) > 0 && s != "" && s != substr && strings.Contains(s, substr)
@@ -0,0 +1,105 @@
+package mdparser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TitleSource derives the title of a parsed document.
+//
+// Parameters:
+//   - name: the name passed to Parse, such as a file path
+//   - doc: the parsed document, without a title
+//
+// Returns:
+//   - string: the title, empty if this source has none
+type TitleSource func(name string, doc MarkdownDocument) string
+
+// ParseOption configures Parse.
+type ParseOption func(*parseOptions)
+
+// parseOptions holds the settings of a Parse call.
+type parseOptions struct {
+	title TitleSource
+}
+
+// WithTitleSource sets how Parse derives the document title.
+func WithTitleSource(source TitleSource) ParseOption {
+	return func(o *parseOptions) {
+		o.title = source
+	}
+}
+
+// TitleFromFilename uses the base name without its extension, as for files in a vault.
+// Characters escaped by files.TitleToName are decoded.
+func TitleFromFilename(name string, _ MarkdownDocument) string {
+	if name == "" {
+		return ""
+	}
+	return files.FilenameToTitle(name)
+}
+
+// TitleFromVaultFile uses the "title" frontmatter field when the file name is the safe
+// form of that title, as written by SetTitleField, and the file name otherwise. A title
+// field that does not match the file name is ignored, so the title always leads back to
+// the file.
+func TitleFromVaultFile(name string, doc MarkdownDocument) string {
+	if name == "" {
+		return ""
+	}
+	base := filepath.Base(name)
+	stem := norm.NFC.String(strings.TrimSuffix(base, filepath.Ext(base)))
+	if title := TitleFromFrontmatter(name, doc); title != "" && files.TitleToName(title) == stem {
+		return norm.NFC.String(title)
+	}
+	return TitleFromFilename(name, doc)
+}
+
+// SetTitleField keeps a title in the "title" frontmatter field when its file name cannot
+// hold it unchanged, so TitleFromVaultFile reads back the exact title.
+//
+// Parameters:
+//   - fm: the frontmatter to update
+//   - title: the title of the document
+func SetTitleField(fm Frontmatter, title string) {
+	if files.TitleToName(title) != title {
+		fm["title"] = title
+	}
+}
+
+// TitleFromFirstH1 uses the text of the first level 1 heading of the content.
+func TitleFromFirstH1(_ string, doc MarkdownDocument) string {
+	for _, heading := range ParseBody(doc.Content).Headings() {
+		if heading.Level == 1 {
+			return heading.Text
+		}
+	}
+	return ""
+}
+
+// TitleFromFrontmatter uses the "title" frontmatter field.
+func TitleFromFrontmatter(_ string, doc MarkdownDocument) string {
+	title, _ := GetString(doc.Frontmatter, "title")
+	return strings.TrimSpace(title)
+}
+
+// FirstTitle combines title sources, using the first one that yields a title.
+//
+// Parameters:
+//   - sources: title sources in order of preference
+//
+// Returns:
+//   - TitleSource: the combined source
+func FirstTitle(sources ...TitleSource) TitleSource {
+	return func(name string, doc MarkdownDocument) string {
+		for _, source := range sources {
+			if title := source(name, doc); title != "" {
+				return title
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,157 @@
+package mdparser_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+)
+
+func TestParse_TitleSources(t *testing.T) {
+	const src = "---\ntitle: From Frontmatter\n---\n\nIntro\n\n# From Heading\n"
+	const noTitles = "Just text"
+
+	tests := []struct {
+		name    string
+		src     string
+		docName string
+		opts    []mdparser.ParseOption
+		want    string
+	}{
+		{
+			name:    "filename by default",
+			src:     src,
+			docName: "notes/From File.md",
+			want:    "From File",
+		},
+		{
+			name:    "escaped file name",
+			src:     noTitles,
+			docName: "notes/Read a%2Fb.md",
+			want:    "Read a/b",
+		},
+		{
+			name:    "frontmatter title of an escaped file name",
+			src:     "---\ntitle: \"Why? 10:30\"\n---\n",
+			docName: "notes/Why%3F 10%3A30.md",
+			want:    "Why? 10:30",
+		},
+		{
+			name:    "no name gives no title",
+			src:     src,
+			docName: "",
+			want:    "",
+		},
+		{
+			name:    "first H1",
+			src:     src,
+			docName: "stdin",
+			opts:    []mdparser.ParseOption{mdparser.WithTitleSource(mdparser.TitleFromFirstH1)},
+			want:    "From Heading",
+		},
+		{
+			name:    "frontmatter title",
+			src:     src,
+			docName: "stdin",
+			opts:    []mdparser.ParseOption{mdparser.WithTitleSource(mdparser.TitleFromFrontmatter)},
+			want:    "From Frontmatter",
+		},
+		{
+			name:    "fallback chain",
+			src:     noTitles,
+			docName: "inbox/Fallback.md",
+			opts: []mdparser.ParseOption{mdparser.WithTitleSource(mdparser.FirstTitle(
+				mdparser.TitleFromFrontmatter,
+				mdparser.TitleFromFirstH1,
+				mdparser.TitleFromFilename,
+			))},
+			want: "Fallback",
+		},
+		{
+			name:    "custom source",
+			src:     noTitles,
+			docName: "x",
+			opts: []mdparser.ParseOption{mdparser.WithTitleSource(func(_ string, doc mdparser.MarkdownDocument) string {
+				return strings.ToUpper(doc.Content)
+			})},
+			want: "JUST TEXT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := mdparser.Parse(strings.NewReader(tt.src), tt.docName, tt.opts...)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if doc.Title != tt.want {
+				t.Errorf("Title = %q, want %q", doc.Title, tt.want)
+			}
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("boom") }
+
+func TestParse_ReadError(t *testing.T) {
+	if _, err := mdparser.Parse(failingReader{}, "x.md"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Parse() error = %v, want read error", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      mdparser.MarkdownDocument
+		original string
+		want     string
+	}{
+		{
+			name: "new document",
+			doc: mdparser.MarkdownDocument{
+				Title:       "ignored",
+				Frontmatter: mdparser.Frontmatter{"b": 2, "a": "one"},
+				Content:     "Body",
+			},
+			want: "---\na: one\nb: 2\n---\n\nBody",
+		},
+		{
+			name: "patches original",
+			doc: mdparser.MarkdownDocument{
+				Frontmatter: mdparser.Frontmatter{"b": 3, "a": "one"},
+				Content:     "Body",
+			},
+			original: "---\nb: 2 # count\na: one\n---\n\nBody\n",
+			want:     "---\nb: 3 # count\na: one\n---\n\nBody\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var opts []mdparser.WriteOption
+			if tt.original != "" {
+				opts = append(opts, mdparser.WithOriginal([]byte(tt.original)))
+			}
+
+			if err := mdparser.Write(&buf, tt.doc, opts...); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Write() = %q, want %q", buf.String(), tt.want)
+			}
+
+			parsed, err := mdparser.Parse(&buf, "")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if parsed.Content != tt.doc.Content {
+				t.Errorf("round trip Content = %q, want %q", parsed.Content, tt.doc.Content)
+			}
+		})
+	}
+}
@@ -1,38 +1,134 @@
 package mdparser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
+	"reflect"
 	"strings"
 
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
 // WriteMarkdownDoc writes a markdown document with frontmatter to a file.
-// The frontmatter is converted to YAML format and enclosed in --- markers.
+// The file is patched as described in Write, using its current content as the original,
+// and a file whose content would not change is not rewritten at all. The file is replaced
+// atomically, see files.WriteFileAtomic.
 //
 // Parameters:
+//   - fsys: filesystem to write to
 //   - fm: Frontmatter metadata as key-value pairs
 //   - content: Main markdown content
 //   - path: File path to write the document to
 //
 // Returns:
 //   - error if marshaling frontmatter or writing file fails
-func WriteMarkdownDoc(fm Frontmatter, content string, path string) error {
+func WriteMarkdownDoc(fsys afero.Fs, fm Frontmatter, content string, path string) error {
+	existing, err := afero.ReadFile(fsys, path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	doc := MarkdownDocument{Frontmatter: fm, Content: content}
+	if err := Write(&buf, doc, WithOriginal(existing)); err != nil {
+		return err
+	}
+
+	if existing != nil && bytes.Equal(existing, buf.Bytes()) {
+		return nil
+	}
+
+	return files.WriteFileAtomic(fsys, path, buf.Bytes(), 0644)
+}
+
+// WriteOption configures Write.
+type WriteOption func(*writeOptions)
+
+// writeOptions holds the settings of a Write call.
+type writeOptions struct {
+	original []byte
+}
+
+// WithOriginal makes Write patch the original bytes of the document instead of
+// rendering it from scratch.
+func WithOriginal(original []byte) WriteOption {
+	return func(o *writeOptions) {
+		o.original = original
+	}
+}
+
+// Write renders a markdown document with its frontmatter. The title is not written.
+//
+// New documents get YAML frontmatter with keys in alphabetical order. When the original
+// document has YAML frontmatter, only the changed fields are applied to it: key order,
+// comments and value styles are preserved, and the original body bytes are kept when the
+// content is unchanged.
+//
+// Original TOML and JSON frontmatter is written back in the same format. Keys that the
+// content holds as Dataview inline fields, and that the original frontmatter does not
+// have, are updated in place in the content instead of being added to the frontmatter.
+//
+// Parameters:
+//   - w: destination of the document
+//   - doc: the document to write
+//   - opts: options such as WithOriginal
+//
+// Returns:
+//   - error if marshaling frontmatter or writing fails
+func Write(w io.Writer, doc MarkdownDocument, opts ...WriteOption) error {
+	var options writeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Validate no function values in frontmatter
-	for _, v := range fm {
+	for _, v := range doc.Frontmatter {
 		if vType := fmt.Sprintf("%T", v); strings.Contains(vType, "func(") {
 			return fmt.Errorf("frontmatter contains unsupported function value")
 		}
 	}
 
+	raw, found, err := splitRawDocument(options.original)
+	if err != nil {
+		found = false
+	}
+	var originalFM map[string]interface{}
+	if found {
+		originalFM, _ = decodeFrontmatter(raw)
+	}
+	fm, content := moveInlineFields(doc.Frontmatter, doc.Content, originalFM)
+
+	mdDoc, patched, err := patchMarkdownDoc(raw, found, fm, content)
+	if err != nil {
+		return err
+	}
+	if !patched {
+		mdDoc, err = renderMarkdownDoc(fm, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write(mdDoc); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+	return nil
+}
+
+// renderMarkdownDoc renders a new markdown document, with frontmatter keys in alphabetical order.
+func renderMarkdownDoc(fm Frontmatter, content string) ([]byte, error) {
 	var fmBytes []byte
 	var err error
 
 	if len(fm) > 0 {
 		fmBytes, err = yaml.Marshal(fm)
 		if err != nil {
-			return fmt.Errorf("failed to marshal frontmatter: %w", err)
+			return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
 		}
 	}
 
@@ -45,5 +141,110 @@ func WriteMarkdownDoc(fm Frontmatter, content string, path string) error {
 		}(),
 		content)
 
-	return os.WriteFile(path, []byte(mdDoc), 0644)
+	return []byte(mdDoc), nil
+}
+
+// patchMarkdownDoc applies frontmatter and content to the bytes of an existing document.
+//
+// Parameters:
+//   - raw: the existing document, split
+//   - found: whether the existing document has frontmatter
+//   - fm: the frontmatter to write
+//   - content: the content to write
+//
+// Returns:
+//   - []byte: the patched document
+//   - bool: false if the existing document has no usable frontmatter to patch
+//   - error: if a frontmatter value cannot be encoded
+func patchMarkdownDoc(raw rawDocument, found bool, fm Frontmatter, content string) ([]byte, bool, error) {
+	if !found {
+		return nil, false, nil
+	}
+
+	head, fmBytes, closing := raw.head, raw.frontmatter, raw.closing
+	var err error
+	if raw.codec.Name == FormatYAML {
+		mapping, parseErr := parseFrontmatterNode(raw.frontmatter)
+		if parseErr != nil {
+			return nil, false, nil
+		}
+		fmBytes, err = patchYAMLFrontmatter(raw, mapping, fm)
+	} else {
+		head, fmBytes, closing, err = patchCodecFrontmatter(raw, fm)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	body := raw.body
+	if normalizeContent(body) != strings.TrimSpace(content) {
+		if written := append(append([]byte{}, fmBytes...), closing...); !bytes.HasSuffix(written, []byte("\n")) {
+			closing = append(append([]byte{}, closing...), withLineEndings([]byte("\n"), raw.crlf)...)
+		}
+		body = withLineEndings([]byte("\n"+content), raw.crlf)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(head)
+	buf.Write(fmBytes)
+	buf.Write(closing)
+	buf.Write(body)
+
+	return buf.Bytes(), true, nil
+}
+
+// patchYAMLFrontmatter applies frontmatter to the parsed existing YAML, keeping its order,
+// comments and styles.
+func patchYAMLFrontmatter(raw rawDocument, mapping *yaml.Node, fm Frontmatter) ([]byte, error) {
+	changed, err := patchFrontmatterNode(mapping, fm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+	if !changed {
+		return raw.frontmatter, nil
+	}
+
+	fmBytes, err := encodeFrontmatterNode(mapping, raw.frontmatter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+	return withLineEndings(fmBytes, raw.crlf), nil
+}
+
+// patchCodecFrontmatter re-encodes frontmatter in the format of the existing document.
+// The existing bytes are kept when they already decode to the same values.
+//
+// Returns:
+//   - head, frontmatter and closing marker to write
+//   - error: if the frontmatter cannot be encoded
+func patchCodecFrontmatter(raw rawDocument, fm Frontmatter) ([]byte, []byte, []byte, error) {
+	encoded, err := raw.codec.Marshal(fm)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+
+	current, currentErr := decodeFrontmatter(raw)
+	desired, desiredErr := raw.codec.Unmarshal(encoded)
+	if desiredErr == nil {
+		normalizeFrontmatter(desired)
+	}
+	if currentErr == nil && desiredErr == nil && reflect.DeepEqual(current, desired) {
+		return raw.head, raw.frontmatter, raw.closing, nil
+	}
+
+	encoded = withLineEndings(encoded, raw.crlf)
+	if raw.codec.Enclosed {
+		// the markers are part of the encoded data
+		return raw.head[:raw.markerStart], encoded, nil, nil
+	}
+	return raw.head, encoded, raw.closing, nil
+}
+
+// withLineEndings converts LF line endings to CRLF when the document uses them.
+func withLineEndings(data []byte, crlf bool) []byte {
+	if !crlf {
+		return data
+	}
+	lf := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))
 }
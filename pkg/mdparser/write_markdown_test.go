@@ -4,9 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/avivSarig/cerebgo/pkg/mdparser"
 	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
 )
 
 func TestWriteMarkdownDoc(t *testing.T) {
@@ -134,7 +136,7 @@ Hello مرحبا こんにちは`,
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup(t)
 
-			err := mdparser.WriteMarkdownDoc(tt.fm, tt.content, path)
+			err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), tt.fm, tt.content, path)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("WriteMarkdownDoc() error = %v, wantErr %v", err, tt.wantErr)
@@ -147,3 +149,175 @@ Hello مرحبا こんにちは`,
 		})
 	}
 }
+
+// TestWriteMarkdownDoc_PatchesExisting verifies that rewriting an existing document only
+// touches changed fields, keeping key order, comments, styles and the original body.
+func TestWriteMarkdownDoc_PatchesExisting(t *testing.T) {
+	const existing = `---
+# task metadata
+title: 'My Task' # shown in lists
+done: false
+do_date: 2024-01-01
+tags: [home, chores]
+created_at: 2024-01-01T10:00:00Z
+---
+
+Body text
+
+with trailing newline
+`
+
+	tests := []struct {
+		name    string
+		fm      mdparser.Frontmatter
+		content string
+		want    string
+	}{
+		{
+			name: "unchanged document is identical",
+			fm: mdparser.Frontmatter{
+				"title":      "My Task",
+				"done":       false,
+				"do_date":    "2024-01-01",
+				"tags":       []string{"home", "chores"},
+				"created_at": "2024-01-01T10:00:00Z",
+			},
+			content: "Body text\n\nwith trailing newline",
+			want:    existing,
+		},
+		{
+			name: "changed values keep order, comments and style",
+			fm: mdparser.Frontmatter{
+				"title":      "Renamed",
+				"done":       true,
+				"do_date":    "2024-01-02",
+				"tags":       []string{"home"},
+				"created_at": "2024-01-01T11:00:00Z",
+			},
+			content: "Body text\n\nwith trailing newline",
+			want: `---
+# task metadata
+title: 'Renamed' # shown in lists
+done: true
+do_date: 2024-01-02
+tags: [home]
+created_at: 2024-01-01T11:00:00Z
+---
+
+Body text
+
+with trailing newline
+`,
+		},
+		{
+			name: "removed keys are dropped and new keys appended",
+			fm: mdparser.Frontmatter{
+				"title":      "My Task",
+				"done":       false,
+				"do_date":    "2024-01-01",
+				"created_at": "2024-01-01T10:00:00Z",
+				"priority":   "high",
+				"completed":  false,
+			},
+			content: "New body",
+			want: `---
+# task metadata
+title: 'My Task' # shown in lists
+done: false
+do_date: 2024-01-01
+created_at: 2024-01-01T10:00:00Z
+completed: false
+priority: high
+---
+
+New body`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if err := testutil.CreateTestFile(t, dir, "task.md", existing); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "task.md")
+
+			if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), tt.fm, tt.content, path); err != nil {
+				t.Fatalf("WriteMarkdownDoc() error = %v", err)
+			}
+
+			testutil.AssertFileContent(t, path, tt.want)
+		})
+	}
+}
+
+// TestWriteMarkdownDoc_SkipsUnchanged verifies that a file is not rewritten when its
+// content would not change.
+func TestWriteMarkdownDoc_SkipsUnchanged(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	path := filepath.Join(dir, "note.md")
+	fm := mdparser.Frontmatter{"title": "Note"}
+
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), fm, "Content", path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), fm, "Content", path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("file was rewritten: mod time = %v, want %v", info.ModTime(), past)
+	}
+}
+
+// TestWriteMarkdownDoc_KeepsBOMAndCRLF verifies that patching a Windows file keeps its
+// byte order mark and CRLF line endings.
+func TestWriteMarkdownDoc_KeepsBOMAndCRLF(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	existing := "\uFEFF---\r\ntitle: Note\r\ndone: false\r\n---\r\n\r\nOld body\r\n"
+	if err := testutil.CreateTestFile(t, dir, "note.md", existing); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "note.md")
+
+	fm := mdparser.Frontmatter{"title": "Note", "done": true}
+	if err := mdparser.WriteMarkdownDoc(afero.NewOsFs(), fm, "New body\nsecond line", path); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+
+	testutil.AssertFileContent(t, path, "\uFEFF---\r\ntitle: Note\r\ndone: true\r\n---\r\n\r\nNew body\r\nsecond line")
+}
+
+// TestWriteMarkdownDoc_Overlay verifies that writes through a copy-on-write overlay
+// leave the read-only base layer untouched.
+func TestWriteMarkdownDoc_Overlay(t *testing.T) {
+	existing := "---\ndone: false\n---\n\nBody\n"
+	base := testutil.CreateMemFs(t, map[string]string{"/vault/note.md": existing})
+	overlay := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(base), afero.NewMemMapFs())
+
+	doc, err := mdparser.ParseMarkdownDoc(overlay, "/vault/note.md")
+	if err != nil {
+		t.Fatalf("ParseMarkdownDoc() error = %v", err)
+	}
+	doc.Frontmatter["done"] = true
+
+	if err := mdparser.WriteMarkdownDoc(afero.NewReadOnlyFs(base), doc.Frontmatter, doc.Content, "/vault/note.md"); err == nil {
+		t.Error("WriteMarkdownDoc() on a read-only filesystem expected error")
+	}
+	if err := mdparser.WriteMarkdownDoc(overlay, doc.Frontmatter, doc.Content, "/vault/note.md"); err != nil {
+		t.Fatalf("WriteMarkdownDoc() error = %v", err)
+	}
+
+	testutil.AssertFsFileContent(t, overlay, "/vault/note.md", "---\ndone: true\n---\n\nBody\n")
+	testutil.AssertFsFileContent(t, base, "/vault/note.md", existing)
+}
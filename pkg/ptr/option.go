@@ -1,5 +1,10 @@
 package ptr
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // Option[T] represents an optional value that may or may not be present.
 // Use Some(value) to create a valid Option, or None[T]() for an empty Option.
 type Option[T any] struct {
@@ -51,3 +56,48 @@ func (o Option[T]) Value() T {
 	}
 	return o.value
 }
+
+// ElemType returns the type of the value an Option holds.
+// Together with AnyValue and SetAny it lets reflection based code, such as
+// frontmatter decoders, handle any Option[T] without knowing T.
+//
+// Returns:
+//
+//	The reflect.Type of T
+func (o Option[T]) ElemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// AnyValue returns the contained value as an untyped value.
+//
+// Returns:
+//
+//	The contained value and true if Option is Some, nil and false if None
+func (o Option[T]) AnyValue() (any, bool) {
+	if !o.valid {
+		return nil, false
+	}
+	return o.value, true
+}
+
+// SetAny replaces the Option with Some(v), or with None if v is nil.
+// The Option is left unchanged if v is not of type T.
+//
+// Parameters:
+//   - v: the new value, of type T, or nil
+//
+// Returns:
+//
+//	An error if v is not of type T
+func (o *Option[T]) SetAny(v any) error {
+	if v == nil {
+		*o = None[T]()
+		return nil
+	}
+	value, ok := v.(T)
+	if !ok {
+		return fmt.Errorf("expected %v, got %T", o.ElemType(), v)
+	}
+	*o = Some(value)
+	return nil
+}
@@ -146,3 +146,40 @@ func TestOption(t *testing.T) {
 		})
 	}
 }
+
+func TestOption_Reflection(t *testing.T) {
+	opt := ptr.None[time.Time]()
+
+	if got := opt.ElemType(); got != reflect.TypeOf(time.Time{}) {
+		t.Errorf("ElemType() = %v, want time.Time", got)
+	}
+	if v, ok := opt.AnyValue(); ok || v != nil {
+		t.Errorf("AnyValue() on None = %v, %v", v, ok)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := opt.SetAny(now); err != nil {
+		t.Fatalf("SetAny(time) error = %v", err)
+	}
+	if !opt.IsValid() || !opt.Value().Equal(now) {
+		t.Errorf("SetAny(time) = %v", opt)
+	}
+	if v, ok := opt.AnyValue(); !ok || v != any(now) {
+		t.Errorf("AnyValue() on Some = %v, %v", v, ok)
+	}
+
+	if err := opt.SetAny(nil); err != nil {
+		t.Fatalf("SetAny(nil) error = %v", err)
+	}
+	if opt.IsValid() {
+		t.Error("SetAny(nil) should produce None")
+	}
+
+	opt = ptr.Some(now)
+	if err := opt.SetAny("not a time"); err == nil {
+		t.Error("SetAny(string) on Option[time.Time] should fail")
+	}
+	if !opt.IsValid() || !opt.Value().Equal(now) {
+		t.Errorf("SetAny with a wrong type changed the option to %v", opt)
+	}
+}
@@ -0,0 +1,172 @@
+package records
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/spf13/afero"
+)
+
+// CollisionPolicy defines what happens when a record is written to a path that already
+// holds a record with the same title.
+type CollisionPolicy string
+
+const (
+	// CollisionOverwrite replaces the existing record.
+	CollisionOverwrite CollisionPolicy = "overwrite"
+	// CollisionSuffix writes the new record next to the existing one with a numeric suffix.
+	CollisionSuffix CollisionPolicy = "suffix"
+	// CollisionMerge appends the new record to the existing one under a dated section.
+	CollisionMerge CollisionPolicy = "merge"
+	// CollisionVersion copies the existing record into the versions folder before overwriting it.
+	CollisionVersion CollisionPolicy = "version"
+)
+
+// VersionsDir is the folder, relative to the records directory, holding previous versions.
+const VersionsDir = ".versions"
+
+// ParseCollisionPolicy converts a configuration value into a CollisionPolicy.
+// An empty value defaults to CollisionOverwrite.
+//
+// Parameters:
+//   - value: policy name as written in the configuration
+//
+// Returns:
+//   - CollisionPolicy: the parsed policy
+//   - error: if the value is not a known policy
+func ParseCollisionPolicy(value string) (CollisionPolicy, error) {
+	switch policy := CollisionPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return CollisionOverwrite, nil
+	case CollisionOverwrite, CollisionSuffix, CollisionMerge, CollisionVersion:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown collision policy %q", value)
+	}
+}
+
+// MergeRecords merges an incoming record into an existing one.
+// The existing content is kept and the incoming content is appended under a section
+// named after the incoming archive date. Tags are combined without duplicates, and
+// the existing creation time, URL, author, publish date and extra fields take precedence.
+//
+// Parameters:
+//   - existing: record already on disk
+//   - incoming: record being written
+//   - now: the merge time, used when the incoming record has no archive date
+//
+// Returns:
+//   - models.Record: the merged record
+func MergeRecords(existing, incoming models.Record, now time.Time) models.Record {
+	mergedAt := now
+	if incoming.ArchivedAt.IsValid() {
+		mergedAt = incoming.ArchivedAt.Value()
+	}
+
+	content := ""
+	if existing.Content.IsValid() {
+		content = existing.Content.Value()
+	}
+	if incoming.Content.IsValid() && incoming.Content.Value() != "" {
+		section := fmt.Sprintf("## Merged %s\n\n%s", mergedAt.Format("2006-01-02"), incoming.Content.Value())
+		if content == "" {
+			content = section
+		} else {
+			content = content + "\n\n" + section
+		}
+	}
+
+	merged := models.Record{
+		Title:       existing.Title,
+		Content:     ptr.None[string](),
+		Tags:        mergeTags(existing.Tags, incoming.Tags),
+		URL:         existing.URL,
+		Author:      existing.Author,
+		PublishedAt: existing.PublishedAt,
+		CreatedAt:   existing.CreatedAt,
+		UpdatedAt:   incoming.UpdatedAt,
+		ArchivedAt:  ptr.Some(mergedAt),
+		Extra:       mergeExtra(existing.Extra, incoming.Extra),
+	}
+
+	if content != "" {
+		merged.Content = ptr.Some(content)
+	}
+	if !merged.URL.IsValid() {
+		merged.URL = incoming.URL
+	}
+	if !merged.Author.IsValid() {
+		merged.Author = incoming.Author
+	}
+	if !merged.PublishedAt.IsValid() {
+		merged.PublishedAt = incoming.PublishedAt
+	}
+	if merged.CreatedAt.IsZero() || (!incoming.CreatedAt.IsZero() && incoming.CreatedAt.Before(merged.CreatedAt)) {
+		merged.CreatedAt = incoming.CreatedAt
+	}
+	if existing.UpdatedAt.After(merged.UpdatedAt) {
+		merged.UpdatedAt = existing.UpdatedAt
+	}
+
+	return merged
+}
+
+// mergeTags combines two tag lists, keeping the first occurrence order.
+func mergeTags(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	result := make([]string, 0, len(a)+len(b))
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// mergeExtra combines two sets of extra frontmatter fields, preferring the existing values.
+func mergeExtra(existing, incoming map[string]interface{}) map[string]interface{} {
+	if len(existing) == 0 && len(incoming) == 0 {
+		return nil
+	}
+	merged := make(map[string]interface{}, len(existing)+len(incoming))
+	for key, value := range incoming {
+		merged[key] = value
+	}
+	for key, value := range existing {
+		merged[key] = value
+	}
+	return merged
+}
+
+// saveVersion copies an existing record file into the versions folder.
+// Versions are stored as <VersionsDir>/<title>/<timestamp>.md, named after the archive
+// date of the incoming record.
+func saveVersion(fsys afero.Fs, target files.FilePath, incoming models.Record) error {
+	versionTime := incoming.ArchivedAt.Value()
+
+	data, err := afero.ReadFile(fsys, target.FullPath())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", target.FullPath(), err)
+	}
+
+	versionDir := filepath.Join(target.Dir, VersionsDir, strings.TrimSuffix(target.Name, filepath.Ext(target.Name)))
+	if err := fsys.MkdirAll(versionDir, 0755); err != nil {
+		return fmt.Errorf("failed to create versions directory: %w", err)
+	}
+
+	version, err := files.NextAvailableName(fsys, files.FilePath{
+		Dir:  versionDir,
+		Name: versionTime.UTC().Format("20060102T150405Z") + ".md",
+	})
+	if err != nil {
+		return err
+	}
+
+	return files.WriteFileAtomic(fsys, version.FullPath(), data, 0644)
+}
@@ -0,0 +1,164 @@
+package records_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestParseCollisionPolicy tests parsing of configured collision policies.
+func TestParseCollisionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    records.CollisionPolicy
+		wantErr bool
+	}{
+		{name: "empty defaults to overwrite", value: "", want: records.CollisionOverwrite},
+		{name: "suffix", value: "suffix", want: records.CollisionSuffix},
+		{name: "case insensitive", value: " Merge ", want: records.CollisionMerge},
+		{name: "version", value: "version", want: records.CollisionVersion},
+		{name: "unknown policy", value: "skip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := records.ParseCollisionPolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCollisionPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseCollisionPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteRecord_Collisions tests each collision policy against an existing record.
+func TestWriteRecord_Collisions(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	archiveTime := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	existing := models.Record{
+		Title:      "project",
+		Content:    ptr.Some("first content"),
+		Tags:       []string{"old"},
+		URL:        ptr.Some("https://example.com/first"),
+		CreatedAt:  baseTime,
+		UpdatedAt:  baseTime,
+		ArchivedAt: ptr.Some(baseTime),
+	}
+	incoming := models.Record{
+		Title:      "project",
+		Content:    ptr.Some("second content"),
+		Tags:       []string{"old", "new"},
+		URL:        ptr.None[string](),
+		CreatedAt:  archiveTime,
+		UpdatedAt:  archiveTime,
+		ArchivedAt: ptr.Some(archiveTime),
+	}
+
+	tests := []struct {
+		name     string
+		policy   records.CollisionPolicy
+		wantFile string
+		validate func(t *testing.T, dir string)
+	}{
+		{
+			name:     "overwrite replaces existing record",
+			policy:   records.CollisionOverwrite,
+			wantFile: "project.md",
+			validate: func(t *testing.T, dir string) {
+				content := readFile(t, filepath.Join(dir, "project.md"))
+				if strings.Contains(content, "first content") {
+					t.Error("existing record should have been overwritten")
+				}
+			},
+		},
+		{
+			name:     "suffix keeps both records",
+			policy:   records.CollisionSuffix,
+			wantFile: "project (2).md",
+			validate: func(t *testing.T, dir string) {
+				if !strings.Contains(readFile(t, filepath.Join(dir, "project.md")), "first content") {
+					t.Error("existing record should be untouched")
+				}
+				if !strings.Contains(readFile(t, filepath.Join(dir, "project (2).md")), "second content") {
+					t.Error("new record should be written with a suffix")
+				}
+			},
+		},
+		{
+			name:     "merge appends dated section",
+			policy:   records.CollisionMerge,
+			wantFile: "project.md",
+			validate: func(t *testing.T, dir string) {
+				merged, err := records.ReadRecordFile(afero.NewOsFs(), filepath.Join(dir, "project.md"))
+				if err != nil {
+					t.Fatalf("ReadRecordFile() error = %v", err)
+				}
+				want := "first content\n\n## Merged 2024-02-01\n\nsecond content"
+				if !merged.Content.IsValid() || merged.Content.Value() != want {
+					t.Errorf("merged content = %v, want %q", merged.Content, want)
+				}
+				if strings.Join(merged.Tags, ",") != "old,new" {
+					t.Errorf("merged tags = %v, want [old new]", merged.Tags)
+				}
+				if !merged.URL.IsValid() || merged.URL.Value() != "https://example.com/first" {
+					t.Errorf("merged url = %v, want existing url", merged.URL)
+				}
+			},
+		},
+		{
+			name:     "version keeps previous copy",
+			policy:   records.CollisionVersion,
+			wantFile: "project.md",
+			validate: func(t *testing.T, dir string) {
+				if !strings.Contains(readFile(t, filepath.Join(dir, "project.md")), "second content") {
+					t.Error("record should be overwritten with new content")
+				}
+				version := filepath.Join(dir, records.VersionsDir, "project", "20240201T120000Z.md")
+				if !strings.Contains(readFile(t, version), "first content") {
+					t.Error("previous record should be kept in versions folder")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTestDirectory(t)
+			if _, err := records.WriteRecordToFile(afero.NewOsFs(), existing, dir, records.CollisionOverwrite, baseTime); err != nil {
+				t.Fatalf("failed to write existing record: %v", err)
+			}
+
+			got, err := records.WriteRecordToFile(afero.NewOsFs(), incoming, dir, tt.policy, baseTime)
+			if err != nil {
+				t.Fatalf("WriteRecordToFile() error = %v", err)
+			}
+			if want := filepath.Join(dir, tt.wantFile); got != want {
+				t.Errorf("WriteRecordToFile() path = %v, want %v", got, want)
+			}
+
+			tt.validate(t, dir)
+		})
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	return string(content)
+}
@@ -0,0 +1,154 @@
+package records
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/afero"
+)
+
+// DuplicatePair describes two records that look like duplicates of each other.
+type DuplicatePair struct {
+	First      string  // path of the first record
+	Second     string  // path of the second record
+	Similarity float64 // 0..1, 1 meaning identical
+	Reason     string  // "title" or "content"
+}
+
+// copySuffix matches the numeric suffix added by CollisionSuffix, e.g. "Title (2)".
+var copySuffix = regexp.MustCompile(`\s*\(\d+\)$`)
+
+// shingleSize is the number of consecutive words compared between record contents.
+const shingleSize = 3
+
+// FindDuplicates scans a records directory for near-duplicate records.
+// Two records are reported when their normalized titles match, or when the
+// similarity of their contents reaches the threshold.
+//
+// Parameters:
+//   - fsys: filesystem holding the records
+//   - dir: directory containing markdown records (.md extension)
+//   - threshold: minimal content similarity (0..1) to report a pair
+//
+// Returns:
+//   - []DuplicatePair: duplicate pairs, most similar first
+//   - error: reading directory or parsing record errors with context
+func FindDuplicates(fsys afero.Fs, dir string, threshold float64) ([]DuplicatePair, error) {
+	entries, err := afero.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	type candidate struct {
+		path     string
+		title    string
+		shingles map[string]bool
+	}
+
+	var candidates []candidate
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		record, err := ReadRecordFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record from %s: %w", path, err)
+		}
+
+		content := ""
+		if record.Content.IsValid() {
+			content = record.Content.Value()
+		}
+
+		candidates = append(candidates, candidate{
+			path:     path,
+			title:    normalizeTitle(record.Title),
+			shingles: shingles(content),
+		})
+	}
+
+	var pairs []DuplicatePair
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			a, b := candidates[i], candidates[j]
+
+			if a.title != "" && a.title == b.title {
+				pairs = append(pairs, DuplicatePair{
+					First:      a.path,
+					Second:     b.path,
+					Similarity: 1,
+					Reason:     "title",
+				})
+				continue
+			}
+
+			if similarity := jaccard(a.shingles, b.shingles); similarity >= threshold {
+				pairs = append(pairs, DuplicatePair{
+					First:      a.path,
+					Second:     b.path,
+					Similarity: similarity,
+					Reason:     "content",
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Similarity > pairs[j].Similarity
+	})
+
+	return pairs, nil
+}
+
+// normalizeTitle lowercases a title and drops copy suffixes and punctuation,
+// so "Car Research (2)" and "car research" compare equal.
+func normalizeTitle(title string) string {
+	title = copySuffix.ReplaceAllString(title, "")
+	return strings.Join(words(title), " ")
+}
+
+// words splits text into lowercase words, ignoring punctuation.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// shingles returns the set of consecutive word sequences of a text.
+// Texts shorter than a single shingle are represented by their words.
+func shingles(text string) map[string]bool {
+	ws := words(text)
+	set := make(map[string]bool)
+	if len(ws) < shingleSize {
+		for _, w := range ws {
+			set[w] = true
+		}
+		return set
+	}
+	for i := 0; i+shingleSize <= len(ws); i++ {
+		set[strings.Join(ws[i:i+shingleSize], " ")] = true
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two sets. Empty sets are never similar.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
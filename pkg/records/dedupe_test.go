@@ -0,0 +1,55 @@
+package records_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+)
+
+// TestFindDuplicates tests detection of near-duplicate records by title and content.
+func TestFindDuplicates(t *testing.T) {
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/archive/Car Research.md":     "---\ntags: []\n---\n\nCompare prices of electric cars before buying.",
+		"/archive/car research (2).md": "---\ntags: []\n---\n\nSomething else entirely.",
+		"/archive/Trip notes.md":       "---\ntags: []\n---\n\nPack the tent, the stove and enough water for three days.",
+		"/archive/Camping.md":          "---\ntags: []\n---\n\nPack the tent, the stove and enough water for three days!",
+		"/archive/Unrelated.md":        "---\ntags: []\n---\n\nRead a book about Go generics.",
+		"/archive/notes.txt":           "Pack the tent, the stove and enough water for three days.",
+	})
+
+	pairs, err := records.FindDuplicates(fsys, "/archive", 0.8)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+
+	want := map[[2]string]string{
+		{"Car Research.md", "car research (2).md"}: "title",
+		{"Camping.md", "Trip notes.md"}:            "content",
+	}
+
+	if len(pairs) != len(want) {
+		t.Fatalf("FindDuplicates() returned %d pairs, want %d: %+v", len(pairs), len(want), pairs)
+	}
+
+	for _, pair := range pairs {
+		key := [2]string{filepath.Base(pair.First), filepath.Base(pair.Second)}
+		reason, ok := want[key]
+		if !ok {
+			t.Errorf("unexpected pair %v", key)
+			continue
+		}
+		if pair.Reason != reason {
+			t.Errorf("pair %v reason = %v, want %v", key, pair.Reason, reason)
+		}
+	}
+}
+
+// TestFindDuplicates_InvalidDirectory tests that a missing directory returns an error.
+func TestFindDuplicates_InvalidDirectory(t *testing.T) {
+	if _, err := records.FindDuplicates(afero.NewMemMapFs(), "/nonexistent/dir", 0.8); err == nil {
+		t.Error("FindDuplicates() expected error for missing directory")
+	}
+}
@@ -2,48 +2,265 @@ package records
 
 import (
 	"fmt"
-	"path/filepath"
+	"io"
+	"slices"
 	"time"
 
 	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/files"
 	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/spf13/afero"
 )
 
-// WriteRecordToFile writes a Record to a markdown file
+// WriteRecordToFile writes a Record to a markdown file, resolving title collisions with
+// the given policy.
 //
 // Parameters:
+//   - fsys: filesystem to write to
 //   - record: Record to write
+//   - path: directory to write the record into
+//   - policy: how to handle an existing record with the same title
+//   - now: the time of the write, used as archived_at when the record has none
 //
 // Returns:
-//   - error: writing file errors with context
-//
-// FUTURE: consider add overwrite flag (at the moment, it always overwrites).
-func WriteRecordToFile(record models.Record, path string) error {
+//   - string: path of the file that was written
+//   - error: reading, merging or writing errors with context
+func WriteRecordToFile(fsys afero.Fs, record models.Record, path string, policy CollisionPolicy, now time.Time) (string, error) {
 	if record.Title == "" {
-		return fmt.Errorf("record title cannot be empty")
+		return "", fmt.Errorf("record title cannot be empty")
+	}
+	if !record.ArchivedAt.IsValid() {
+		record.ArchivedAt = ptr.Some(now)
+	}
+
+	target, exists, err := files.Resolve(fsys, files.FilePath{Dir: path, Name: files.TitleToFilename(record.Title)})
+	if err != nil {
+		return "", fmt.Errorf("failed to check existing record: %w", err)
+	}
+
+	if exists {
+		switch policy {
+		case CollisionOverwrite:
+		case CollisionSuffix:
+			target, err = files.NextAvailableName(fsys, target)
+			if err != nil {
+				return "", fmt.Errorf("failed to find free record name: %w", err)
+			}
+		case CollisionMerge:
+			existing, err := ReadRecordFile(fsys, target.FullPath())
+			if err != nil {
+				return "", fmt.Errorf("failed to read existing record: %w", err)
+			}
+			record = MergeRecords(existing, record, now)
+		case CollisionVersion:
+			if err := saveVersion(fsys, target, record); err != nil {
+				return "", fmt.Errorf("failed to save record version: %w", err)
+			}
+		default:
+			return "", fmt.Errorf("unknown collision policy %q", policy)
+		}
+	}
+
+	if err := writeRecordFile(fsys, record, target.FullPath()); err != nil {
+		return "", err
+	}
+	return target.FullPath(), nil
+}
+
+// ReadRecordFile reads and parses a markdown file into a Record model.
+//
+// Parameters:
+//   - fsys: filesystem holding the file
+//   - filePath: path to the markdown file
+//
+// Returns:
+//   - models.Record: the parsed record
+//   - error: parsing or conversion errors with context
+func ReadRecordFile(fsys afero.Fs, filePath string) (models.Record, error) {
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return models.Record{}, fmt.Errorf("failed to parse markdown from %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	return ReadRecord(f, filePath)
+}
+
+// ReadRecord reads a markdown record from a reader. The record title is taken from the name.
+//
+// Parameters:
+//   - r: source of the markdown document
+//   - name: file name or path of the record
+//
+// Returns:
+//   - models.Record: the parsed record
+//   - error: parsing errors with context
+func ReadRecord(r io.Reader, name string) (models.Record, error) {
+	doc, err := mdparser.Parse(r, name)
+	if err != nil {
+		return models.Record{}, fmt.Errorf("failed to parse markdown from %s: %w", name, err)
+	}
+
+	return DocumentToRecord(doc), nil
+}
+
+// EncodeRecord writes a record as a new markdown document.
+//
+// Parameters:
+//   - w: destination of the document
+//   - record: Record to write
+//
+// Returns:
+//   - error: marshaling or writing errors
+func EncodeRecord(w io.Writer, record models.Record) error {
+	return mdparser.Write(w, RecordToDocument(record))
+}
+
+// DocumentToRecord converts a markdown document into a Record model.
+// Missing timestamps are left as zero values, since records are often written by hand.
+// Any frontmatter field that is not a Record field is kept in Record.Extra.
+//
+// Parameters:
+//   - doc: MarkdownDocument containing frontmatter and content
+//
+// Returns:
+//   - models.Record: the converted record
+func DocumentToRecord(doc mdparser.MarkdownDocument) models.Record {
+	fm := doc.Frontmatter
+
+	record := models.Record{
+		Title:       doc.Title,
+		Content:     ptr.None[string](),
+		Tags:        getStrings(fm, "tags"),
+		URL:         ptr.None[string](),
+		Author:      ptr.None[string](),
+		PublishedAt: ptr.None[time.Time](),
+		ArchivedAt:  ptr.None[time.Time](),
 	}
 
-	fm := mdparser.Frontmatter{
-		"tags":       record.Tags,
-		"created_at": record.CreatedAt,
-		"updated_at": record.UpdatedAt,
+	if doc.Content != "" {
+		record.Content = ptr.Some(doc.Content)
 	}
+	if url, ok := mdparser.GetString(fm, "url"); ok {
+		record.URL = ptr.Some(url)
+	}
+	if author, ok := mdparser.GetString(fm, "author"); ok {
+		record.Author = ptr.Some(author)
+	}
+	if publishedAt, ok := getTimestamp(fm, "published_at"); ok {
+		record.PublishedAt = ptr.Some(publishedAt)
+	}
+	if createdAt, ok := getTimestamp(fm, "created_at"); ok {
+		record.CreatedAt = createdAt
+	}
+	if updatedAt, ok := getTimestamp(fm, "updated_at"); ok {
+		record.UpdatedAt = updatedAt
+	}
+	if archivedAt, ok := getTimestamp(fm, "archived_at"); ok {
+		record.ArchivedAt = ptr.Some(archivedAt)
+	}
+
+	for key, value := range fm {
+		if slices.Contains(recordFields, key) {
+			continue
+		}
+		if record.Extra == nil {
+			record.Extra = make(map[string]interface{})
+		}
+		record.Extra[key] = value
+	}
+
+	return record
+}
+
+// recordFields are the frontmatter fields mapped to Record model fields.
+var recordFields = []string{
+	"tags", "url", "author", "published_at", "created_at", "updated_at", "archived_at",
+}
+
+// RecordToDocument converts a Record model into a markdown document, including
+// the unknown fields kept in Record.Extra.
+//
+// Parameters:
+//   - record: Record to convert
+//
+// Returns:
+//   - mdparser.MarkdownDocument: the document to write
+func RecordToDocument(record models.Record) mdparser.MarkdownDocument {
+	content := ""
+	if record.Content.IsValid() {
+		content = record.Content.Value()
+	}
+
+	return mdparser.MarkdownDocument{
+		Title:       record.Title,
+		Frontmatter: recordToFrontmatter(record),
+		Content:     content,
+	}
+}
+
+// recordToFrontmatter builds the frontmatter written for a record.
+func recordToFrontmatter(record models.Record) mdparser.Frontmatter {
+	fm := mdparser.Frontmatter{}
+	for key, value := range record.Extra {
+		fm[key] = value
+	}
+
+	mdparser.SetTitleField(fm, record.Title)
+	fm["tags"] = record.Tags
+	fm["created_at"] = record.CreatedAt
+	fm["updated_at"] = record.UpdatedAt
 
 	if record.URL.IsValid() {
 		fm["url"] = record.URL.Value()
 	}
+	if record.Author.IsValid() {
+		fm["author"] = record.Author.Value()
+	}
+	if record.PublishedAt.IsValid() {
+		fm["published_at"] = record.PublishedAt.Value().Format(time.RFC3339)
+	}
 
 	if record.ArchivedAt.IsValid() {
 		fm["archived_at"] = record.ArchivedAt.Value()
-	} else {
-		fm["archived_at"] = time.Now().Format(time.RFC3339)
 	}
 
-	content := ""
-	if record.Content.IsValid() {
-		content = record.Content.Value()
+	return fm
+}
+
+// writeRecordFile writes a record to the given file path, without any collision handling.
+func writeRecordFile(fsys afero.Fs, record models.Record, filename string) error {
+	doc := RecordToDocument(record)
+	return mdparser.WriteMarkdownDoc(fsys, doc.Frontmatter, doc.Content, filename)
+}
+
+// getStrings extracts a list of strings from Frontmatter, skipping non-string items.
+func getStrings(fm mdparser.Frontmatter, key string) []string {
+	result := make([]string, 0)
+	switch values := fm[key].(type) {
+	case []string:
+		result = append(result, values...)
+	case []interface{}:
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				result = append(result, s)
+			}
+		}
 	}
+	return result
+}
 
-	filename := filepath.Join(path, record.Title+".md")
-	return mdparser.WriteMarkdownDoc(fm, content, filename)
+// getTimestamp extracts a time from Frontmatter, accepting both RFC3339 and
+// date-only (YYYY-MM-DD) values.
+func getTimestamp(fm mdparser.Frontmatter, key string) (time.Time, bool) {
+	if t, ok := mdparser.GetTime(fm, key); ok {
+		return t, true
+	}
+	str, ok := mdparser.GetString(fm, key)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02", str)
+	return t, err == nil
 }
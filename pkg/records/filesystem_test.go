@@ -11,6 +11,7 @@ import (
 	"github.com/avivSarig/cerebgo/pkg/ptr"
 	"github.com/avivSarig/cerebgo/pkg/records"
 	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
 )
 
 // TestWriteRecordToFile_Creation tests the basic file creation functionality.
@@ -74,6 +75,24 @@ func TestWriteRecordToFile_Creation(t *testing.T) {
 				ArchivedAt: ptr.None[time.Time](),
 			},
 		},
+		{
+			name: "missing archive date is the write time",
+			record: models.Record{
+				Title:     "unarchived",
+				CreatedAt: baseTime,
+				UpdatedAt: baseTime,
+				Tags:      []string{},
+			},
+			validate: func(t *testing.T, dir string, record models.Record) {
+				content, err := os.ReadFile(filepath.Join(dir, "unarchived.md"))
+				if err != nil {
+					t.Fatalf("Failed to read file: %v", err)
+				}
+				if !strings.Contains(string(content), "archived_at: "+baseTime.Format(time.RFC3339)) {
+					t.Errorf("archived_at is not the write time:\n%s", content)
+				}
+			},
+		},
 		{
 			name: "existing file should be overwritten",
 			record: models.Record{
@@ -108,7 +127,7 @@ func TestWriteRecordToFile_Creation(t *testing.T) {
 				tt.setup(t, dir)
 			}
 
-			err := records.WriteRecordToFile(tt.record, dir)
+			_, err := records.WriteRecordToFile(afero.NewOsFs(), tt.record, dir, records.CollisionOverwrite, baseTime)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("WriteRecordToFile() error = %v, wantErr %v", err, tt.wantErr)
@@ -213,7 +232,7 @@ func TestWriteRecordToFile_Errors(t *testing.T) {
 				}
 			}
 
-			err := records.WriteRecordToFile(tt.record, dir)
+			_, err := records.WriteRecordToFile(afero.NewOsFs(), tt.record, dir, records.CollisionOverwrite, baseTime)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("WriteRecordToFile() error = %v, wantErr %v", err, tt.wantErr)
@@ -221,3 +240,79 @@ func TestWriteRecordToFile_Errors(t *testing.T) {
 		})
 	}
 }
+
+// TestReadRecordFile_RoundTrip tests that a written record reads back with the same fields.
+func TestReadRecordFile_RoundTrip(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishedAt := time.Date(2023, 6, 15, 9, 30, 0, 0, time.UTC)
+
+	record := models.Record{
+		Title:       "clipped-article",
+		Content:     ptr.Some("# Heading\n\nBody"),
+		Tags:        []string{"clip", "reading"},
+		URL:         ptr.Some("https://example.com/article"),
+		Author:      ptr.Some("Jane Doe"),
+		PublishedAt: ptr.Some(publishedAt),
+		CreatedAt:   baseTime,
+		UpdatedAt:   baseTime,
+		ArchivedAt:  ptr.Some(baseTime),
+		Extra:       map[string]interface{}{"aliases": []interface{}{"article"}, "source": "browser"},
+	}
+
+	dir := testutil.CreateTestDirectory(t)
+	if _, err := records.WriteRecordToFile(afero.NewOsFs(), record, dir, records.CollisionOverwrite, baseTime); err != nil {
+		t.Fatalf("WriteRecordToFile() error = %v", err)
+	}
+
+	got, err := records.ReadRecordFile(afero.NewOsFs(), filepath.Join(dir, "clipped-article.md"))
+	if err != nil {
+		t.Fatalf("ReadRecordFile() error = %v", err)
+	}
+
+	results := []testutil.ValidationResult{
+		testutil.ValidateEqual("Title", got.Title, record.Title),
+		testutil.ValidateEqual("Tags", strings.Join(got.Tags, ","), "clip,reading"),
+		testutil.ValidateOptional("Content", got.Content, record.Content, testutil.StringComparer),
+		testutil.ValidateOptional("URL", got.URL, record.URL, testutil.StringComparer),
+		testutil.ValidateOptional("Author", got.Author, record.Author, testutil.StringComparer),
+		testutil.ValidateOptional("PublishedAt", got.PublishedAt, record.PublishedAt, testutil.TimeComparer),
+		testutil.ValidateOptional("ArchivedAt", got.ArchivedAt, record.ArchivedAt, testutil.TimeComparer),
+		testutil.ValidateDeepEqual("Extra", got.Extra, record.Extra),
+	}
+	testutil.ReportResults(t, results)
+}
+
+// TestEncodeRecord_RoundTrip verifies that a record written to a stream reads back unchanged.
+func TestEncodeRecord_RoundTrip(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := models.Record{
+		Title:       "Streamed",
+		Content:     ptr.Some("Body"),
+		Tags:        []string{"inbox"},
+		URL:         ptr.Some("https://example.com"),
+		Author:      ptr.None[string](),
+		PublishedAt: ptr.None[time.Time](),
+		CreatedAt:   baseTime,
+		UpdatedAt:   baseTime,
+		ArchivedAt:  ptr.Some(baseTime),
+	}
+
+	var buf strings.Builder
+	if err := records.EncodeRecord(&buf, record); err != nil {
+		t.Fatalf("EncodeRecord() error = %v", err)
+	}
+
+	got, err := records.ReadRecord(strings.NewReader(buf.String()), "Streamed.md")
+	if err != nil {
+		t.Fatalf("ReadRecord() error = %v", err)
+	}
+
+	results := []testutil.ValidationResult{
+		testutil.ValidateEqual("Title", got.Title, record.Title),
+		testutil.ValidateEqual("Tags", strings.Join(got.Tags, ","), "inbox"),
+		testutil.ValidateOptional("Content", got.Content, record.Content, testutil.StringComparer),
+		testutil.ValidateOptional("URL", got.URL, record.URL, testutil.StringComparer),
+		testutil.ValidateOptional("ArchivedAt", got.ArchivedAt, record.ArchivedAt, testutil.TimeComparer),
+	}
+	testutil.ReportResults(t, results)
+}
@@ -0,0 +1,222 @@
+package tasks
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/spf13/afero"
+)
+
+// View selects the tasks of an agenda.
+type View string
+
+const (
+	// ViewToday lists the active tasks to do today or earlier.
+	ViewToday View = "today"
+	// ViewOverdue lists the active tasks whose due date has passed.
+	ViewOverdue View = "overdue"
+	// ViewUpcoming lists the active tasks to do in the next days, after today.
+	ViewUpcoming View = "upcoming"
+	// ViewDue lists the active tasks with a due date, the nearest first.
+	ViewDue View = "due"
+	// ViewProjects lists the active projects.
+	ViewProjects View = "projects"
+	// ViewCompleted lists the tasks completed in the last days, the latest first.
+	ViewCompleted View = "completed"
+	// ViewAll lists every active task.
+	ViewAll View = "all"
+)
+
+// Views lists every view.
+var Views = []View{ViewToday, ViewOverdue, ViewUpcoming, ViewDue, ViewProjects, ViewCompleted, ViewAll}
+
+// SortKey orders the tasks of an agenda.
+type SortKey string
+
+const (
+	// SortPriority puts high priority tasks first, then orders by do date.
+	SortPriority SortKey = "priority"
+	// SortDoDate orders by do date, the earliest first.
+	SortDoDate SortKey = "do_date"
+	// SortDueDate orders by due date, the earliest first and tasks without one last.
+	SortDueDate SortKey = "due_date"
+	// SortAge orders by creation time, the oldest first.
+	SortAge SortKey = "age"
+)
+
+// sortCompletedAt orders by completion time, the latest first. It is the order of the
+// completed view.
+const sortCompletedAt SortKey = "completed_at"
+
+// SortKeys lists every sort key.
+var SortKeys = []SortKey{SortPriority, SortDoDate, SortDueDate, SortAge}
+
+// AgendaOptions selects and orders the tasks of an agenda.
+type AgendaOptions struct {
+	View     View
+	Days     int     // how far ahead upcoming and how far back completed look
+	Sort     SortKey // empty for the order of the view
+	HighOnly bool    // only high priority tasks
+	Folder   string  // only the task area in this folder, relative to the task folder
+	Search   string  // only titles containing this text, case-insensitive
+}
+
+// AgendaItem is a task of an agenda with the file it is read from.
+type AgendaItem struct {
+	Task models.Task
+	Path string
+}
+
+// Agenda lists the tasks of a view over the task folder and its task areas.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - opts: the view, filters and order
+//   - now: the current time; "today" is its date
+//
+// Returns:
+//   - []AgendaItem: the selected tasks, in order
+//   - error: if the view or sort key is unknown, or the task folders cannot be read
+func Agenda(fsys afero.Fs, cfg *config.Config, opts AgendaOptions, now time.Time) ([]AgendaItem, error) {
+	sortKey, err := viewSort(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	areas, err := taskAreas(fsys, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []AgendaItem
+	found := opts.Folder == ""
+	for _, area := range areas {
+		if opts.Folder != "" {
+			rel, err := filepath.Rel(cfg.Paths.Tasks, area.active)
+			if err != nil || !strings.EqualFold(rel, filepath.Clean(opts.Folder)) {
+				continue
+			}
+			found = true
+		}
+
+		dir := area.active
+		if opts.View == ViewCompleted {
+			if area.pending {
+				continue
+			}
+			dir = area.completed
+		}
+		areaTasks, err := readTasksFromDirectory(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range areaTasks {
+			if inView(task, opts, now) {
+				items = append(items, AgendaItem{Task: task, Path: filepath.Join(dir, taskFilename(task))})
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no task area in %s", opts.Folder)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return lessTask(items[i].Task, items[j].Task, sortKey)
+	})
+	return items, nil
+}
+
+// viewSort checks the view and sort key of opts, and returns the order to use.
+func viewSort(opts AgendaOptions) (SortKey, error) {
+	known := false
+	for _, view := range Views {
+		known = known || view == opts.View
+	}
+	if !known {
+		return "", fmt.Errorf("unknown view %q", opts.View)
+	}
+
+	switch opts.Sort {
+	case SortPriority, SortDoDate, SortDueDate, SortAge:
+		return opts.Sort, nil
+	case "":
+		switch opts.View {
+		case ViewDue, ViewOverdue:
+			return SortDueDate, nil
+		case ViewCompleted:
+			return sortCompletedAt, nil
+		default:
+			return SortPriority, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown sort key %q", opts.Sort)
+	}
+}
+
+// inView reports whether a task belongs to the view and passes the filters of opts.
+func inView(task models.Task, opts AgendaOptions, now time.Time) bool {
+	if opts.HighOnly && !task.IsHighPriority {
+		return false
+	}
+	if opts.Search != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(opts.Search)) {
+		return false
+	}
+
+	today := now.Format(dateLayout)
+	switch opts.View {
+	case ViewToday:
+		return !task.Done && (task.DoDate == today || !IsValidDoDate(task, now))
+	case ViewOverdue:
+		return !task.Done && task.DueDate.IsValid() && !IsValidDueDate(task, now)
+	case ViewUpcoming:
+		last := now.AddDate(0, 0, opts.Days).Format(dateLayout)
+		return !task.Done && IsValidDoDate(task, now) && task.DoDate != today && task.DoDate <= last
+	case ViewDue:
+		return !task.Done && task.DueDate.IsValid()
+	case ViewProjects:
+		return !task.Done && task.IsProject
+	case ViewCompleted:
+		return IsCompleted(task) && now.Sub(task.CompletedAt.Value()) <= time.Duration(opts.Days)*24*time.Hour
+	default:
+		return !task.Done
+	}
+}
+
+// lessTask orders two tasks by key, then by title.
+func lessTask(a, b models.Task, key SortKey) bool {
+	switch key {
+	case SortPriority:
+		if a.IsHighPriority != b.IsHighPriority {
+			return a.IsHighPriority
+		}
+		if a.DoDate != b.DoDate {
+			return a.DoDate < b.DoDate
+		}
+	case SortDoDate:
+		if a.DoDate != b.DoDate {
+			return a.DoDate < b.DoDate
+		}
+	case SortDueDate:
+		if a.DueDate.IsValid() != b.DueDate.IsValid() {
+			return a.DueDate.IsValid()
+		}
+		if a.DueDate.IsValid() && a.DueDate.Value() != b.DueDate.Value() {
+			return a.DueDate.Value() < b.DueDate.Value()
+		}
+	case SortAge:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case sortCompletedAt:
+		if !a.CompletedAt.Value().Equal(b.CompletedAt.Value()) {
+			return a.CompletedAt.Value().After(b.CompletedAt.Value())
+		}
+	}
+	return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+}
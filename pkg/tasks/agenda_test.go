@@ -0,0 +1,241 @@
+package tasks_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/viper"
+)
+
+// TestAgenda verifies that each view selects, filters and orders the tasks of every task
+// area.
+func TestAgenda(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	task := func(created, doDate, extra string) string {
+		return "---\ncreated_at: " + created + "T09:00:00Z\nupdated_at: " + created + "T09:00:00Z\ndo_date: \"" + doDate + "\"\n" + extra + "---\n"
+	}
+
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":           "",
+		"/vault/Archive/.keep":            "",
+		"/vault/Tasks/Water plants.md":    task("2026-10-01", "2026-10-19", ""),
+		"/vault/Tasks/Pay rent.md":        task("2026-09-01", "2026-10-12", "due_date: \"2026-10-15\"\nis_high_priority: true\n"),
+		"/vault/Tasks/Book flights.md":    task("2026-10-10", "2026-10-21", "due_date: \"2026-11-01\"\n"),
+		"/vault/Tasks/Plan trip.md":       task("2026-10-05", "2026-12-01", "is_project: true\n"),
+		"/vault/Tasks/Work/.cerebgo.yaml": "settings:\n  retention:\n    empty_task: 90\n",
+		"/vault/Tasks/Work/Report.md":     task("2026-10-02", "2026-10-19", "due_date: \"2026-10-30\"\nis_high_priority: true\n"),
+		"/vault/Tasks/Completed/Buy milk.md": task("2026-10-01", "2026-10-10",
+			"done: true\ncompleted_at: 2026-10-18T09:00:00Z\n"),
+		"/vault/Tasks/Completed/Old errand.md": task("2026-08-01", "2026-08-10",
+			"done: true\ncompleted_at: 2026-08-18T09:00:00Z\n"),
+		"/vault/Tasks/Work/Completed/Slides.md": task("2026-10-01", "2026-10-10",
+			"done: true\ncompleted_at: 2026-10-17T09:00:00Z\n"),
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    tasks.AgendaOptions
+		want    []string // titles, in order
+		wantErr bool
+	}{
+		{
+			name: "today, high priority first",
+			opts: tasks.AgendaOptions{View: tasks.ViewToday},
+			want: []string{"Pay rent", "Report", "Water plants"},
+		},
+		{
+			name: "overdue",
+			opts: tasks.AgendaOptions{View: tasks.ViewOverdue},
+			want: []string{"Pay rent"},
+		},
+		{
+			name: "upcoming within the window",
+			opts: tasks.AgendaOptions{View: tasks.ViewUpcoming, Days: 7},
+			want: []string{"Book flights"},
+		},
+		{
+			name: "by due date",
+			opts: tasks.AgendaOptions{View: tasks.ViewDue},
+			want: []string{"Pay rent", "Report", "Book flights"},
+		},
+		{
+			name: "projects",
+			opts: tasks.AgendaOptions{View: tasks.ViewProjects},
+			want: []string{"Plan trip"},
+		},
+		{
+			name: "completed, latest first",
+			opts: tasks.AgendaOptions{View: tasks.ViewCompleted, Days: 7},
+			want: []string{"Buy milk", "Slides"},
+		},
+		{
+			name: "all sorted by age",
+			opts: tasks.AgendaOptions{View: tasks.ViewAll, Sort: tasks.SortAge},
+			want: []string{"Pay rent", "Water plants", "Report", "Plan trip", "Book flights"},
+		},
+		{
+			name: "all sorted by do date",
+			opts: tasks.AgendaOptions{View: tasks.ViewAll, Sort: tasks.SortDoDate},
+			want: []string{"Pay rent", "Report", "Water plants", "Book flights", "Plan trip"},
+		},
+		{
+			name: "high priority in a folder",
+			opts: tasks.AgendaOptions{View: tasks.ViewAll, HighOnly: true, Folder: "work"},
+			want: []string{"Report"},
+		},
+		{
+			name: "search",
+			opts: tasks.AgendaOptions{View: tasks.ViewAll, Search: "PLAN"},
+			want: []string{"Water plants", "Plan trip"},
+		},
+		{
+			name:    "unknown folder",
+			opts:    tasks.AgendaOptions{View: tasks.ViewAll, Folder: "Home"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown view",
+			opts:    tasks.AgendaOptions{View: "someday"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sort key",
+			opts:    tasks.AgendaOptions{View: tasks.ViewAll, Sort: "size"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := tasks.Agenda(fsys, cfg, tt.opts, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Agenda() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.Task.Title)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Agenda() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAgenda_LocalDate verifies that the today, overdue and upcoming views follow the
+// calendar day of the user's time zone, not the UTC one.
+func TestAgenda_LocalDate(t *testing.T) {
+	task := func(doDate, extra string) string {
+		return "---\ncreated_at: 2026-10-01T09:00:00Z\nupdated_at: 2026-10-01T09:00:00Z\ndo_date: \"" + doDate + "\"\n" + extra + "---\n"
+	}
+
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Tasks/Call bank.md":    task("2026-10-18", "due_date: \"2026-10-18\"\n"),
+		"/vault/Tasks/Water plants.md": task("2026-10-19", "due_date: \"2026-10-19\"\n"),
+		"/vault/Tasks/Book flights.md": task("2026-10-20", ""),
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	// Late evening on the 19th west of UTC, already the 20th in UTC.
+	evening := time.Date(2026, 10, 19, 22, 0, 0, 0, time.FixedZone("UTC-8", -8*3600))
+	// Early morning on the 19th east of UTC, still the 18th in UTC.
+	morning := time.Date(2026, 10, 19, 7, 0, 0, 0, time.FixedZone("UTC+9", 9*3600))
+
+	tests := []struct {
+		name string
+		now  time.Time
+		opts tasks.AgendaOptions
+		want []string // titles, in order
+	}{
+		{
+			name: "today in the evening",
+			now:  evening,
+			opts: tasks.AgendaOptions{View: tasks.ViewToday},
+			want: []string{"Call bank", "Water plants"},
+		},
+		{
+			name: "overdue in the evening",
+			now:  evening,
+			opts: tasks.AgendaOptions{View: tasks.ViewOverdue},
+			want: []string{"Call bank"},
+		},
+		{
+			name: "upcoming in the evening",
+			now:  evening,
+			opts: tasks.AgendaOptions{View: tasks.ViewUpcoming, Days: 1},
+			want: []string{"Book flights"},
+		},
+		{
+			name: "today in the morning",
+			now:  morning,
+			opts: tasks.AgendaOptions{View: tasks.ViewToday},
+			want: []string{"Call bank", "Water plants"},
+		},
+		{
+			name: "overdue in the morning",
+			now:  morning,
+			opts: tasks.AgendaOptions{View: tasks.ViewOverdue},
+			want: []string{"Call bank"},
+		},
+		{
+			name: "upcoming in the morning",
+			now:  morning,
+			opts: tasks.AgendaOptions{View: tasks.ViewUpcoming, Days: 1},
+			want: []string{"Book flights"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := tasks.Agenda(fsys, cfg, tt.opts, tt.now)
+			if err != nil {
+				t.Fatalf("Agenda() error = %v", err)
+			}
+
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.Task.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Agenda() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/spf13/afero"
+)
+
+// taskArea is a folder of active tasks with its folder of completed tasks.
+type taskArea struct {
+	active    string
+	completed string
+	pending   bool // the completed folder does not exist yet
+}
+
+// folders returns the active folder of the area and its completed folder, unless it
+// does not exist yet.
+func (a taskArea) folders() []string {
+	if a.pending {
+		return []string{a.active}
+	}
+	return []string{a.active, a.completed}
+}
+
+// taskAreas returns the task folder and every subfolder of it holding a folder file,
+// see config.FolderFile. Such a subfolder is processed like the task folder, with its
+// own completed folder at the same relative path, which may not exist yet: it is created
+// when a task is moved there. Completed folders and hidden folders are never areas.
+func taskAreas(fsys afero.Fs, cfg *config.Config) ([]taskArea, error) {
+	completedRel, err := filepath.Rel(cfg.Paths.Tasks, cfg.Paths.CompletedTasks)
+	if err != nil || strings.HasPrefix(completedRel, "..") {
+		completedRel = filepath.Base(cfg.Paths.CompletedTasks)
+	}
+
+	root := taskArea{active: cfg.Paths.Tasks, completed: cfg.Paths.CompletedTasks}
+	areas := []taskArea{root}
+	completed := map[string]bool{root.completed: true}
+
+	err = afero.Walk(fsys, cfg.Paths.Tasks, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == cfg.Paths.Tasks {
+			return nil
+		}
+		if completed[path] || strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if exists, err := afero.Exists(fsys, filepath.Join(path, config.FolderFile)); err != nil || !exists {
+			return err
+		}
+		area := taskArea{active: path, completed: filepath.Join(path, completedRel)}
+		if _, err := fsys.Stat(area.completed); errors.Is(err, fs.ErrNotExist) {
+			area.pending = true
+		}
+		areas = append(areas, area)
+		completed[area.completed] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find task areas: %w", err)
+	}
+	return areas, nil
+}
+
+// config returns the configuration for a folder of the area, with the task folders of
+// the area.
+func (a taskArea) config(fsys afero.Fs, cfg *config.Config, dir string) (*config.Config, error) {
+	resolved, err := config.ForPath(fsys, cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Paths.Tasks = a.active
+	resolved.Paths.CompletedTasks = a.completed
+	return resolved, nil
+}
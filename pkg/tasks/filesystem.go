@@ -1,44 +1,87 @@
 package tasks
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
+	"slices"
 	"time"
 
+	"github.com/avivSarig/cerebgo/config"
 	"github.com/avivSarig/cerebgo/internal/models"
 	"github.com/avivSarig/cerebgo/pkg/files"
 	"github.com/avivSarig/cerebgo/pkg/mdparser"
 	"github.com/avivSarig/cerebgo/pkg/ptr"
 	"github.com/avivSarig/cerebgo/pkg/records"
+	"github.com/avivSarig/cerebgo/pkg/trash"
+	"github.com/spf13/afero"
 )
 
 // ReadTaskFile reads and parses a markdown file into a Task model
 //
 // Parameters:
+//   - fsys: filesystem holding the file
 //   - filePath: path to the markdown file
 //     TODO: Check if path absolute or relative
 //
 // Returns:
 //   - Option[Task]: Some(Task) if parsing succeeds, None if fails
 //   - error: parsing or conversion errors with context
-func ReadTaskFile(filePath string) (ptr.Option[models.Task], error) {
-	doc, err := mdparser.ParseMarkdownDoc(filePath)
+func ReadTaskFile(fsys afero.Fs, filePath string) (ptr.Option[models.Task], error) {
+	f, err := fsys.Open(filePath)
 	if err != nil {
 		return ptr.None[models.Task](), fmt.Errorf("failed to parse markdown from %s: %w", filePath, err)
 	}
+	defer f.Close()
 
-	task, err := DocumentToTask(doc)
+	task, err := ReadTask(f, filePath)
 	if err != nil {
-		return ptr.None[models.Task](), fmt.Errorf("failed to convert document to task from %s: %w", filePath, err)
+		return ptr.None[models.Task](), err
 	}
 
 	return ptr.Some(task), nil
 }
 
+// ReadTask reads a markdown task from a reader. The task title is taken from the name,
+// and the base name is kept in Task.Filename.
+//
+// Parameters:
+//   - r: source of the markdown document
+//   - name: file name or path of the task
+//
+// Returns:
+//   - models.Task: the parsed task
+//   - error: parsing or conversion errors with context
+func ReadTask(r io.Reader, name string) (models.Task, error) {
+	doc, err := mdparser.Parse(r, name)
+	if err != nil {
+		return models.Task{}, fmt.Errorf("failed to parse markdown from %s: %w", name, err)
+	}
+
+	task, err := DocumentToTask(doc)
+	if err != nil {
+		return models.Task{}, fmt.Errorf("failed to convert document to task from %s: %w", name, err)
+	}
+	task.Filename = filepath.Base(name)
+
+	return task, nil
+}
+
+// taskFilename returns the name of the file of a task: the file it was read from, which
+// may predate the safe file names of TitleToFilename, or the safe file name of its title
+// for a task not written yet.
+func taskFilename(task models.Task) string {
+	if task.Filename != "" {
+		return task.Filename
+	}
+	return files.TitleToFilename(task.Title)
+}
+
 // readTasksFromDirectory scans a directory for markdown files and converts them to Tasks
 //
 // Parameters:
+//   - fsys: filesystem holding the directory
 //   - dir: directory path containing markdown task files (.md extension)
 //
 // Returns:
@@ -50,8 +93,8 @@ func ReadTaskFile(filePath string) (ptr.Option[models.Task], error) {
 //   - don't have .md extension
 //   - fail to parse
 //   - have invalid task data
-func readTasksFromDirectory(dir string) ([]models.Task, error) {
-	entries, err := os.ReadDir(dir)
+func readTasksFromDirectory(fsys afero.Fs, dir string) ([]models.Task, error) {
+	entries, err := afero.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
 	}
@@ -63,7 +106,7 @@ func readTasksFromDirectory(dir string) ([]models.Task, error) {
 		}
 
 		filePath := filepath.Join(dir, entry.Name())
-		taskResult, err := ReadTaskFile(filePath)
+		taskResult, err := ReadTaskFile(fsys, filePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read task from %s: %w", filePath, err)
 		}
@@ -76,151 +119,186 @@ func readTasksFromDirectory(dir string) ([]models.Task, error) {
 	return tasks, nil
 }
 
-// DocumentToTask converts a markdown document into a Task model. It extracts task metadata
-// from the document's frontmatter and content.
-//
-// Required frontmatter fields:
-// - created_at: timestamp of task creation
-// - do_date: string representing when the task should be done
-//
-// Optional frontmatter fields:
-// - updated_at: timestamp of last update (defaults to created_at)
-// - due_date: string deadline for the task
-// - done: boolean indicating completion status
-// - is_project: boolean marking task as a project
-// - is_high_priority: boolean for priority level
-//
-// Returns error if required fields are missing.
-func DocumentToTask(doc mdparser.MarkdownDocument) (models.Task, error) {
-	fm := doc.Frontmatter
-
-	createdAt, ok := mdparser.GetTime(fm, "created_at")
-	if !ok {
-		return models.Task{}, fmt.Errorf("missing required field: created_at")
-	}
-
-	doDate, ok := mdparser.GetString(fm, "do_date")
-	if !ok {
-		return models.Task{}, fmt.Errorf("missing required field: do_date")
-	}
-
-	task := models.Task{
-		Title:     doc.Title,
-		CreatedAt: createdAt,
-		DoDate:    doDate,
-	}
-
-	if updatedAt, ok := mdparser.GetTime(fm, "updated_at"); ok {
-		task.UpdatedAt = updatedAt
-	} else {
-		task.UpdatedAt = createdAt
-	}
-
-	if doc.Content != "" {
-		task.Content = ptr.Some(doc.Content)
-	} else {
-		task.Content = ptr.None[string]()
-	}
-
-	if dueDate, ok := mdparser.GetString(fm, "due_date"); ok {
-		task.DueDate = ptr.Some(dueDate)
-	} else {
-		task.DueDate = ptr.None[string]()
-	}
-
-	if isDone, ok := mdparser.GetBool(fm, "done"); ok {
-		task.Done = isDone
-	}
-
-	if isProject, ok := mdparser.GetBool(fm, "is_project"); ok {
-		task.IsProject = isProject
-	}
-
-	if isHighPriority, ok := mdparser.GetBool(fm, "is_high_priority"); ok {
-		task.IsHighPriority = isHighPriority
+// extraFields returns the frontmatter fields not listed in known,
+// or nil if there are none.
+func extraFields(fm mdparser.Frontmatter, known []string) map[string]interface{} {
+	var extra map[string]interface{}
+	for key, value := range fm {
+		if slices.Contains(known, key) {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		extra[key] = value
 	}
-
-	return task, nil
+	return extra
 }
 
-// DeleteTaskFile deletes a task file from the filesystem
+// DeleteTaskFile deletes a task file from the filesystem. The file is moved to the vault
+// trash, unless permanent deletion is configured.
 //
 // Parameters:
+//   - fsys: filesystem holding the task file
+//   - cfg: the vault configuration
 //   - task: task model to delete
+//   - path: directory holding the task file
+//   - now: the deletion time
 //
 // Returns:
 //   - error: deletion error with context
-func DeleteTaskFile(task models.Task, path string) error {
+func DeleteTaskFile(fsys afero.Fs, cfg *config.Config, task models.Task, path string, now time.Time) error {
 	src := files.FilePath{
 		Dir:  path,
-		Name: task.Title + ".md",
+		Name: taskFilename(task),
 	}
 
-	if err := files.DeleteFile(src); err != nil {
+	if err := trash.ForVault(fsys, cfg).Delete(src, now); err != nil {
 		return fmt.Errorf("failed to delete file %s: %w", src.Name, err)
 	}
 	return nil
 }
 
-// ArchiveTask archives by creating a record and deleting the task file
+// moveTaskFile moves a task file under a collision policy, creating the destination
+// folder when missing. An older file that MoveKeepNewer does not move is deleted through
+// the vault trash.
+func moveTaskFile(fsys afero.Fs, cfg *config.Config, src, dest files.FilePath, policy files.MovePolicy, now time.Time) (files.FilePath, error) {
+	if err := fsys.MkdirAll(dest.Dir, 0755); err != nil {
+		return files.FilePath{}, fmt.Errorf("failed to create %s: %w", dest.Dir, err)
+	}
+	moved, err := files.MoveFile(fsys, src, dest, policy)
+	if errors.Is(err, files.ErrSourceOlder) {
+		if err := trash.ForVault(fsys, cfg).Delete(src, now); err != nil {
+			return files.FilePath{}, fmt.Errorf("failed to delete older %s: %w", src.Name, err)
+		}
+		return moved, nil
+	}
+	return moved, err
+}
+
+// ArchiveTask archives a completed task by creating a record in the archives directory
+// and deleting the task file from the completed directory.
+// The task's tags and other unknown frontmatter fields are carried over to the record.
+// Title collisions with existing records follow the configured archive collision policy.
 //
 // Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
 //   - task: task model to archive
+//   - now: the archiving time
 //
 // Returns:
 //   - error: writing or deletion error with context
-func ArchiveTask(task models.Task, now time.Time) error {
-	activePath := filepath.Join(
-		configuration.GetString("base_path"),
-		configuration.GetString("paths.base.tasks"),
-	)
-	completedPath := filepath.Join(
-		configuration.GetString("base_path"),
-		configuration.GetString("paths.subdirs.tasks.completed"),
-	)
+func ArchiveTask(fsys afero.Fs, cfg *config.Config, task models.Task, now time.Time) error {
+	extra := extraFields(task.Extra, []string{"tags"})
+	tags := make([]string, 0)
+	if taskTags, ok := task.Extra["tags"].([]interface{}); ok {
+		for _, tag := range taskTags {
+			if s, ok := tag.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+	}
 
 	record := models.Record{
 		Title:      task.Title,
 		Content:    task.Content,
-		Tags:       make([]string, 0),
+		Tags:       tags,
 		URL:        ptr.None[string](),
 		CreatedAt:  task.CreatedAt,
 		UpdatedAt:  task.UpdatedAt,
 		ArchivedAt: ptr.Some(now),
+		Extra:      extra,
 	}
 
-	err := records.WriteRecordToFile(record, completedPath)
+	_, err := records.WriteRecordToFile(fsys, record, cfg.Paths.Archives, cfg.Archive.Collision, now)
 	if err != nil {
 		return fmt.Errorf("failed to archive task: %w", err)
 	}
 
-	return DeleteTaskFile(task, activePath)
+	return DeleteTaskFile(fsys, cfg, task, cfg.Paths.CompletedTasks, now)
 }
 
-// WriteTaskToFile writes a task model to a markdown file
+// TaskToFile writes a task model to a new markdown file.
+// Unknown frontmatter fields kept in Task.Extra are written back unchanged.
+// An existing file with the same name is handled according to policy: MoveError refuses
+// to write, MoveSuffix writes next to it with a numeric suffix, and MoveKeepNewer
+// replaces it, as the task being written is the newer one. Use RewriteTask to update
+// the file a task was read from.
 //
 // Parameters:
+//   - fsys: filesystem to write to
 //   - task: task model to write
+//   - path: directory to write the task into
+//   - policy: how to handle an existing file with the same name
 //
 // Returns:
-//   - error: writing error with context
-//
-// FUTURE: consider add overwrite flag (at the moment, it always overwrites).
-func TaskToFile(task models.Task, path string) error {
-	fm := mdparser.Frontmatter{
-		"is_project":       task.IsProject,
-		"is_high_priority": task.IsHighPriority,
-		"done":             task.Done,
-		"do_date":          task.DoDate,
-		"created_at":       task.CreatedAt.Format(time.RFC3339),
-		"updated_at":       task.UpdatedAt.Format(time.RFC3339),
+//   - string: path of the file that was written
+//   - error: if the file exists under MoveError, or writing fails
+func TaskToFile(fsys afero.Fs, task models.Task, path string, policy files.MovePolicy) (string, error) {
+	target, exists, err := files.Resolve(fsys, files.FilePath{Dir: path, Name: files.TitleToFilename(task.Title)})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve task file: %w", err)
 	}
 
-	if task.CompletedAt.IsValid() {
-		fm["completed_at"] = task.CompletedAt.Value().Format(time.RFC3339)
+	if exists {
+		switch policy {
+		case files.MoveError:
+			return "", fmt.Errorf("task file %s already exists", target.FullPath())
+		case files.MoveSuffix:
+			target, err = files.NextAvailableName(fsys, target)
+			if err != nil {
+				return "", fmt.Errorf("failed to find free task name: %w", err)
+			}
+		case files.MoveKeepNewer:
+		default:
+			return "", fmt.Errorf("unknown move policy %q", policy)
+		}
 	}
-	if task.DueDate.IsValid() {
-		fm["due_date"] = task.DueDate.Value()
+
+	if err := writeTaskFile(fsys, task, target.FullPath()); err != nil {
+		return "", err
+	}
+	return target.FullPath(), nil
+}
+
+// writeTaskFile writes a task in the current schema to the given file.
+func writeTaskFile(fsys afero.Fs, task models.Task, filename string) error {
+	doc, err := TaskToDocument(task)
+	if err != nil {
+		return err
+	}
+	return mdparser.WriteMarkdownDoc(fsys, doc.Frontmatter, doc.Content, filename)
+}
+
+// TaskToDocument converts a Task model into a markdown document in the current schema,
+// including the unknown fields kept in Task.Extra.
+//
+// Parameters:
+//   - task: task model to convert
+//
+// Returns:
+//   - mdparser.MarkdownDocument: the document to write
+//   - error: a *mdparser.DecodeError if a required field is empty
+func TaskToDocument(task models.Task) (mdparser.MarkdownDocument, error) {
+	fm, err := mdparser.Encode(taskFrontmatter{
+		SchemaVersion:  SchemaVersion,
+		CreatedAt:      task.CreatedAt,
+		DoDate:         task.DoDate,
+		UpdatedAt:      ptr.Some(task.UpdatedAt),
+		CompletedAt:    task.CompletedAt,
+		DueDate:        task.DueDate,
+		Done:           task.Done,
+		IsProject:      task.IsProject,
+		IsHighPriority: task.IsHighPriority,
+		Extra:          task.Extra,
+	})
+	if err != nil {
+		return mdparser.MarkdownDocument{}, fmt.Errorf("invalid task %q: %w", task.Title, err)
+	}
+
+	if task.Filename == "" || files.FilenameToTitle(task.Filename) != task.Title {
+		mdparser.SetTitleField(fm, task.Title)
 	}
 
 	content := ""
@@ -228,17 +306,42 @@ func TaskToFile(task models.Task, path string) error {
 		content = task.Content.Value()
 	}
 
-	filename := filepath.Join(path, task.Title+".md")
-	return mdparser.WriteMarkdownDoc(fm, content, filename)
+	return mdparser.MarkdownDocument{Title: task.Title, Frontmatter: fm, Content: content}, nil
 }
 
-// RewriteTask rewrites a task to a markdown file
+// EncodeTask writes a task as a new markdown document.
+//
 // Parameters:
-//   - task: task model to rewrite
-func RewriteTask(task models.Task, path string) error {
+//   - w: destination of the document
+//   - task: task model to write
+//
+// Returns:
+//   - error: marshaling or writing errors
+func EncodeTask(w io.Writer, task models.Task) error {
+	doc, err := TaskToDocument(task)
+	if err != nil {
+		return err
+	}
+	return mdparser.Write(w, doc)
+}
 
-	err := TaskToFile(task, path)
+// RewriteTask rewrites a task to the markdown file it was read from, replacing the
+// previous content.
+//
+// Parameters:
+//   - fsys: filesystem to write to
+//   - task: task model to rewrite
+//   - path: directory holding the task file
+//
+// Returns:
+//   - error: writing error with context
+func RewriteTask(fsys afero.Fs, task models.Task, path string) error {
+	target, _, err := files.Resolve(fsys, files.FilePath{Dir: path, Name: taskFilename(task)})
 	if err != nil {
+		return fmt.Errorf("failed to resolve task file: %w", err)
+	}
+
+	if err := writeTaskFile(fsys, task, target.FullPath()); err != nil {
 		return fmt.Errorf("failed to convert task to file: %w", err)
 	}
 	return nil
@@ -0,0 +1,255 @@
+package tasks_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TestDocumentToTask_Extra verifies that unknown frontmatter fields are kept in Task.Extra.
+func TestDocumentToTask_Extra(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	doc := mdparser.MarkdownDocument{
+		Title: "Task",
+		Frontmatter: mdparser.Frontmatter{
+			"created_at":   baseTime.Format(time.RFC3339),
+			"completed_at": completedAt.Format(time.RFC3339),
+			"do_date":      "2024-01-01",
+			"done":         true,
+			"tags":         []interface{}{"home"},
+			"aliases":      []interface{}{"chore"},
+			"context":      "errands",
+		},
+	}
+
+	want := models.Task{
+		Title:       "Task",
+		Content:     ptr.None[string](),
+		Done:        true,
+		CompletedAt: ptr.Some(completedAt),
+		DueDate:     ptr.None[string](),
+		DoDate:      "2024-01-01",
+		CreatedAt:   baseTime,
+		UpdatedAt:   baseTime,
+		Extra: map[string]interface{}{
+			"tags":    []interface{}{"home"},
+			"aliases": []interface{}{"chore"},
+			"context": "errands",
+		},
+	}
+
+	got, err := tasks.DocumentToTask(doc)
+	if err != nil {
+		t.Fatalf("DocumentToTask() error = %v", err)
+	}
+
+	testutil.AssertTaskEqual(t, got, want)
+}
+
+// TestRewriteTask_PreservesExtra verifies that unknown frontmatter fields survive a
+// read, modify and write cycle unchanged.
+func TestRewriteTask_PreservesExtra(t *testing.T) {
+	dir := testutil.CreateTestDirectory(t)
+	original := `---
+tags:
+    - home
+aliases:
+    - chore
+cssclasses: wide
+context: errands
+created_at: "2024-01-01T00:00:00Z"
+updated_at: "2024-01-01T00:00:00Z"
+do_date: "2024-01-01"
+---
+
+Task body`
+	if err := testutil.CreateTestFile(t, dir, "Task.md", original); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tasks.ReadTaskFile(afero.NewOsFs(), filepath.Join(dir, "Task.md"))
+	if err != nil || !result.IsValid() {
+		t.Fatalf("ReadTaskFile() error = %v", err)
+	}
+
+	now := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	modified, err := tasks.ApplyModifiers(result.Value(), now,
+		tasks.HighPriorityModifier(),
+		tasks.ProjectModifier(now),
+	)
+	if err != nil {
+		t.Fatalf("ApplyModifiers() error = %v", err)
+	}
+
+	if err := tasks.RewriteTask(afero.NewOsFs(), modified, dir); err != nil {
+		t.Fatalf("RewriteTask() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "Task.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"tags:\n    - home\n",
+		"aliases:\n    - chore\n",
+		"cssclasses: wide\n",
+		"context: errands\n",
+		"is_high_priority: true\n",
+		"is_project: true\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("file content missing %q:\n%s", want, content)
+		}
+	}
+}
+
+// TestEncodeTask_RoundTrip verifies that a task written to a stream reads back unchanged.
+func TestEncodeTask_RoundTrip(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	task := models.Task{
+		Title:          "Stream Task",
+		Content:        ptr.Some("Some notes"),
+		Done:           false,
+		CompletedAt:    ptr.None[time.Time](),
+		IsProject:      true,
+		IsHighPriority: true,
+		DoDate:         "2024-01-02",
+		DueDate:        ptr.Some("2024-01-05"),
+		CreatedAt:      baseTime,
+		UpdatedAt:      baseTime,
+		Extra:          map[string]interface{}{"context": "errands"},
+	}
+
+	var buf strings.Builder
+	if err := tasks.EncodeTask(&buf, task); err != nil {
+		t.Fatalf("EncodeTask() error = %v", err)
+	}
+
+	got, err := tasks.ReadTask(strings.NewReader(buf.String()), "inbox/Stream Task.md")
+	if err != nil {
+		t.Fatalf("ReadTask() error = %v", err)
+	}
+
+	testutil.AssertTaskEqual(t, got, task)
+}
+
+// TestTaskToFile_UnsafeTitle verifies that titles which cannot be file names are written
+// under their safe name inside the task directory and read back unchanged.
+func TestTaskToFile_UnsafeTitle(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	nfd := norm.NFD.String("Café notes.md")
+
+	tests := []struct {
+		name      string
+		title     string
+		rewrite   bool
+		wantFile  string
+		wantFiles int
+	}{
+		{name: "reserved characters", title: "Why? a/b", wantFile: "/vault/Tasks/Why%3F a%2Fb.md", wantFiles: 2},
+		{name: "traversal", title: "../../outside", wantFile: "/vault/Tasks/%2E.%2F..%2Foutside.md", wantFiles: 2},
+		{name: "existing NFD file is rewritten", title: "Café notes", rewrite: true, wantFile: "/vault/Tasks/" + nfd, wantFiles: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Tasks/" + nfd: "---\ncreated_at: \"2024-01-01T09:30:00Z\"\ndo_date: \"2024-01-01\"\n---\n",
+			})
+			task := models.Task{
+				Title:       tt.title,
+				Content:     ptr.None[string](),
+				CompletedAt: ptr.None[time.Time](),
+				DueDate:     ptr.None[string](),
+				DoDate:      "2024-01-02",
+				CreatedAt:   baseTime,
+				UpdatedAt:   baseTime,
+			}
+
+			if tt.rewrite {
+				if err := tasks.RewriteTask(fsys, task, "/vault/Tasks"); err != nil {
+					t.Fatalf("RewriteTask() error = %v", err)
+				}
+			} else if _, err := tasks.TaskToFile(fsys, task, "/vault/Tasks", files.MoveError); err != nil {
+				t.Fatalf("TaskToFile() error = %v", err)
+			}
+
+			entries, _ := afero.ReadDir(fsys, "/vault/Tasks")
+			if len(entries) != tt.wantFiles {
+				t.Errorf("/vault/Tasks holds %d files, want %d", len(entries), tt.wantFiles)
+			}
+
+			result, err := tasks.ReadTaskFile(fsys, tt.wantFile)
+			if err != nil || !result.IsValid() {
+				t.Fatalf("ReadTaskFile() error = %v", err)
+			}
+			if got := result.Value(); got.Title != tt.title || got.DoDate != "2024-01-02" {
+				t.Errorf("ReadTaskFile() title = %q, do_date = %q, want %q, 2024-01-02", got.Title, got.DoDate, tt.title)
+			}
+		})
+	}
+}
+
+// TestTaskToFile_Collision verifies that writing a task over an existing file follows
+// the collision policy.
+func TestTaskToFile_Collision(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	existing := "---\ncreated_at: \"2024-01-01T09:30:00Z\"\ndo_date: \"2024-01-01\"\n---\n\nExisting body"
+
+	tests := []struct {
+		name        string
+		policy      files.MovePolicy
+		wantPath    string
+		wantErr     bool
+		wantContent string // content of /vault/Tasks/Task.md after the write
+	}{
+		{name: "error keeps the existing file", policy: files.MoveError, wantErr: true, wantContent: "Existing body"},
+		{name: "suffix writes next to it", policy: files.MoveSuffix, wantPath: "/vault/Tasks/Task (2).md", wantContent: "Existing body"},
+		{name: "keep newer replaces it", policy: files.MoveKeepNewer, wantPath: "/vault/Tasks/Task.md", wantContent: "New body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{"/vault/Tasks/Task.md": existing})
+			task := models.Task{
+				Title:       "Task",
+				Content:     ptr.Some("New body"),
+				CompletedAt: ptr.None[time.Time](),
+				DueDate:     ptr.None[string](),
+				DoDate:      "2024-01-02",
+				CreatedAt:   baseTime,
+				UpdatedAt:   baseTime,
+			}
+
+			got, err := tasks.TaskToFile(fsys, task, "/vault/Tasks", tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TaskToFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantPath {
+				t.Errorf("TaskToFile() = %q, want %q", got, tt.wantPath)
+			}
+
+			content, err := afero.ReadFile(fsys, "/vault/Tasks/Task.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), tt.wantContent) {
+				t.Errorf("/vault/Tasks/Task.md = %q, want it to contain %q", content, tt.wantContent)
+			}
+		})
+	}
+}
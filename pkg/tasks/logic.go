@@ -3,21 +3,23 @@ package tasks
 import (
 	"time"
 
+	"github.com/avivSarig/cerebgo/config"
 	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/spf13/afero"
 )
 
 // PlanCompletedTaskActions plans the actions to take on a completed task.
 //
 // Parameters:
+//   - fsys: The filesystem the actions operate on.
+//   - cfg: The vault configuration.
 //   - task: The task to process.
 //   - now: The current timestamp.
-//   - config: The retention configuration.
 //
 // Returns:
 //   - []TaskAction: The actions to take on the task.
 //   - error: An error if the actions cannot be planned.
-func PlanCompletedTaskActions(task models.Task, now time.Time, config RetentionConfig) ([]TaskModifier, error) {
-	dir := configuration.GetString("paths.subdirs.tasks.completed")
+func PlanCompletedTaskActions(fsys afero.Fs, cfg *config.Config, task models.Task, now time.Time) ([]TaskModifier, error) {
 	actions := make([]TaskModifier, 0)
 
 	if !IsCompleted(task) {
@@ -29,31 +31,37 @@ func PlanCompletedTaskActions(task models.Task, now time.Time, config RetentionC
 		// is completion but not done?
 		if task.CompletedAt.IsValid() && !task.Done {
 			actions = append(actions, UncompleteModifier())
-			actions = append(actions, ReactivateModifier())
+			actions = append(actions, ReactivateModifier(fsys, cfg))
 		}
 	}
 
-	if !ShouldRetainTask(task, now, config) {
+	retention := RetentionConfig{
+		EmptyTaskRetention: cfg.Retention.EmptyTask,
+		ProjectRetention:   cfg.Retention.ProjectBeforeArchive,
+	}
+	if !ShouldRetainTask(task, now, retention) {
 		if task.IsProject {
-			actions = append(actions, ArchiveModifier())
+			actions = append(actions, ArchiveModifier(fsys, cfg))
 		}
-		actions = append(actions, DeleteModifier(dir))
+		actions = append(actions, DeleteModifier(fsys, cfg, cfg.Paths.CompletedTasks))
 	}
 
 	return actions, nil
 }
 
-// PlanActiveTaskActions plans the actions to take on an active task.
+// PlanActiveTaskActions plans the actions to take on an active task. A past do date is
+// moved to the day of the run unless rollover is turned off for the task's folder.
 //
 // Parameters:
+//   - fsys: The filesystem the actions operate on.
+//   - cfg: The vault configuration.
 //   - task: The task to process.
 //   - now: The current timestamp.
-//   - config: The retention configuration.
 //
 // Returns:
 //   - []TaskAction: The actions to take on the task.
 //   - error: An error if the actions cannot be planned.
-func PlanActiveTaskActions(task models.Task, now time.Time) ([]TaskModifier, error) {
+func PlanActiveTaskActions(fsys afero.Fs, cfg *config.Config, task models.Task, now time.Time) ([]TaskModifier, error) {
 	actions := make([]TaskModifier, 0)
 
 	if task.Content.IsValid() && !task.IsProject {
@@ -63,7 +71,7 @@ func PlanActiveTaskActions(task models.Task, now time.Time) ([]TaskModifier, err
 		actions = append(actions, UnprojectModifier(now))
 	}
 
-	if IsValidDoDate(task, now) {
+	if cfg.Tasks.Rollover && !IsValidDoDate(task, now) {
 		actions = append(actions, DoDateTodayModifier())
 	}
 
@@ -71,7 +79,7 @@ func PlanActiveTaskActions(task models.Task, now time.Time) ([]TaskModifier, err
 
 	if task.Done {
 		actions = append(actions, CompletionModifier(now))
-		actions = append(actions, DeactivateModifier())
+		actions = append(actions, DeactivateModifier(fsys, cfg))
 	}
 
 	return actions, nil
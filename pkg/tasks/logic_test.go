@@ -0,0 +1,105 @@
+package tasks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/spf13/afero"
+)
+
+// TestPlanActiveTaskActions_DoDate verifies that only do dates in the past are moved to
+// the day of the run, and that tasks with a current do date are left untouched.
+func TestPlanActiveTaskActions_DoDate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		doDate      string
+		now         time.Time // the default time when zero
+		noRollover  bool
+		wantActions int
+		wantDoDate  string
+	}{
+		{
+			name:        "past do date moves to today",
+			doDate:      "2026-10-12",
+			wantActions: 1,
+			wantDoDate:  "2026-10-19",
+		},
+		{
+			name:        "do date today is kept",
+			doDate:      "2026-10-19",
+			wantActions: 0,
+			wantDoDate:  "2026-10-19",
+		},
+		{
+			name:        "future do date is kept",
+			doDate:      "2026-11-01",
+			wantActions: 0,
+			wantDoDate:  "2026-11-01",
+		},
+		{
+			name:        "local today is kept when UTC is a day ahead",
+			doDate:      "2026-10-19",
+			now:         time.Date(2026, 10, 19, 22, 0, 0, 0, time.FixedZone("UTC-8", -8*3600)),
+			wantActions: 0,
+			wantDoDate:  "2026-10-19",
+		},
+		{
+			name:        "local yesterday moves to the local today",
+			doDate:      "2026-10-18",
+			now:         time.Date(2026, 10, 19, 7, 0, 0, 0, time.FixedZone("UTC+9", 9*3600)),
+			wantActions: 1,
+			wantDoDate:  "2026-10-19",
+		},
+		{
+			name:        "past do date kept with rollover off",
+			doDate:      "2026-10-12",
+			noRollover:  true,
+			wantActions: 0,
+			wantDoDate:  "2026-10-12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := now
+			if !tt.now.IsZero() {
+				now = tt.now
+			}
+			task := models.Task{
+				Title:     "Water plants",
+				DoDate:    tt.doDate,
+				CreatedAt: created,
+				UpdatedAt: created,
+			}
+
+			actions, err := tasks.PlanActiveTaskActions(afero.NewMemMapFs(), &config.Config{Tasks: config.Tasks{Rollover: !tt.noRollover}}, task, now)
+			if err != nil {
+				t.Fatalf("PlanActiveTaskActions() error = %v", err)
+			}
+			if len(actions) != tt.wantActions {
+				t.Fatalf("PlanActiveTaskActions() planned %d actions, want %d", len(actions), tt.wantActions)
+			}
+
+			got, err := tasks.ApplyModifiers(task, now, actions...)
+			if err != nil {
+				t.Fatalf("ApplyModifiers() error = %v", err)
+			}
+			if got.DoDate != tt.wantDoDate {
+				t.Errorf("do date = %s, want %s", got.DoDate, tt.wantDoDate)
+			}
+			wantUpdated := created
+			if tt.wantActions > 0 {
+				wantUpdated = now
+			}
+			if !got.UpdatedAt.Equal(wantUpdated) {
+				t.Errorf("updated_at = %v, want %v", got.UpdatedAt, wantUpdated)
+			}
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/avivSarig/cerebgo/config"
 	"github.com/avivSarig/cerebgo/internal/models"
 	"github.com/avivSarig/cerebgo/pkg/files"
 	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/spf13/afero"
 )
 
 // TaskModifier defines a function that modifies a task based on the current time.
@@ -35,6 +37,8 @@ func CompletionModifier(completionTime time.Time) TaskModifier {
 				DoDate:         task.DoDate,
 				CreatedAt:      task.CreatedAt,
 				UpdatedAt:      task.CompletedAt.Value(), // Don't update timestamp
+				Extra:          task.Extra,
+				Filename:       task.Filename,
 			}, nil
 		}
 
@@ -49,6 +53,8 @@ func CompletionModifier(completionTime time.Time) TaskModifier {
 			DoDate:         task.DoDate,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now, // update timestamp
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
@@ -71,6 +77,8 @@ func UncompleteModifier() TaskModifier {
 			DoDate:         task.DoDate,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now, // update timestamp
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
@@ -97,6 +105,8 @@ func ProjectModifier(now time.Time) TaskModifier {
 			DoDate:         task.DoDate,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now, // update timestamp
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
@@ -114,20 +124,25 @@ func UnprojectModifier(now time.Time) TaskModifier {
 			DoDate:         task.DoDate,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now, // update timestamp
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
 
-// DeleteModifier returns a TaskModifier that deletes a task.
+// DeleteModifier returns a TaskModifier that deletes a task, moving its file to the trash
+// unless permanent deletion is configured.
 //
 // Parameters:
+// - fsys: The filesystem holding the task file.
+// - cfg: The vault configuration.
 // - path: The path to the task file.
 //
 // Returns:
 // - TaskModifier: A function to delete a task that returns an empty task.
-func DeleteModifier(path string) TaskModifier {
+func DeleteModifier(fsys afero.Fs, cfg *config.Config, path string) TaskModifier {
 	return func(task models.Task, now time.Time) (models.Task, error) {
-		if err := DeleteTaskFile(task, path); err != nil {
+		if err := DeleteTaskFile(fsys, cfg, task, path, now); err != nil {
 			return models.Task{}, fmt.Errorf("failed to delete task file: %w", err)
 		}
 		return models.Task{}, nil
@@ -136,20 +151,29 @@ func DeleteModifier(path string) TaskModifier {
 
 // DeactivateModifier returns a TaskModifier that moves a task from active to completed.
 //
+// Parameters:
+//   - fsys: The filesystem holding the task directories.
+//   - cfg: The vault configuration, with the task directories and the policy for a
+//     completed task file with the same name.
+//
 // Returns:
 //   - TaskModifier: A function to deactivate a task.
 //     The function moves the task file from the active directory to the completed directory.
-func DeactivateModifier() TaskModifier {
+func DeactivateModifier(fsys afero.Fs, cfg *config.Config) TaskModifier {
 	return func(task models.Task, now time.Time) (models.Task, error) {
-		err := files.MoveFile(
+		_, err := moveTaskFile(
+			fsys,
+			cfg,
 			files.FilePath{
-				Dir:  configuration.GetString("paths.base.tasks"),
-				Name: task.Title + ".md",
+				Dir:  cfg.Paths.Tasks,
+				Name: taskFilename(task),
 			},
 			files.FilePath{
-				Dir:  configuration.GetString("paths.subdirs.tasks.completed"),
-				Name: task.Title + ".md",
+				Dir:  cfg.Paths.CompletedTasks,
+				Name: taskFilename(task),
 			},
+			cfg.Tasks.Collision,
+			now,
 		)
 
 		if err != nil {
@@ -160,17 +184,30 @@ func DeactivateModifier() TaskModifier {
 	}
 }
 
-func ReactivateModifier() TaskModifier {
+// ReactivateModifier returns a TaskModifier that moves a task from completed back to active.
+//
+// Parameters:
+//   - fsys: The filesystem holding the task directories.
+//   - cfg: The vault configuration, with the task directories and the policy for an
+//     active task file with the same name.
+//
+// Returns:
+//   - TaskModifier: A function to reactivate a task.
+func ReactivateModifier(fsys afero.Fs, cfg *config.Config) TaskModifier {
 	return func(task models.Task, now time.Time) (models.Task, error) {
-		err := files.MoveFile(
+		_, err := moveTaskFile(
+			fsys,
+			cfg,
 			files.FilePath{
-				Dir:  configuration.GetString("paths.subdirs.tasks.completed"),
-				Name: task.Title + ".md",
+				Dir:  cfg.Paths.CompletedTasks,
+				Name: taskFilename(task),
 			},
 			files.FilePath{
-				Dir:  configuration.GetString("paths.base.tasks"),
-				Name: task.Title + ".md",
+				Dir:  cfg.Paths.Tasks,
+				Name: taskFilename(task),
 			},
+			cfg.Tasks.Collision,
+			now,
 		)
 
 		if err != nil {
@@ -192,7 +229,7 @@ func ReactivateModifier() TaskModifier {
 //	TaskModifier function that takes (models.Task, time.Time) and returns modified models.Task
 func DoDateTodayModifier() TaskModifier {
 	return func(task models.Task, now time.Time) (models.Task, error) {
-		today := time.Now().Format("2006-01-02")
+		today := now.Format("2006-01-02")
 		return models.Task{
 			Title:          task.Title,
 			Content:        task.Content,
@@ -204,6 +241,8 @@ func DoDateTodayModifier() TaskModifier {
 			DoDate:         today,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now,
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
@@ -226,13 +265,15 @@ func HighPriorityModifier() TaskModifier {
 			DoDate:         task.DoDate,
 			CreatedAt:      task.CreatedAt,
 			UpdatedAt:      now,
+			Extra:          task.Extra,
+			Filename:       task.Filename,
 		}, nil
 	}
 }
 
-func ArchiveModifier() TaskModifier {
+func ArchiveModifier(fsys afero.Fs, cfg *config.Config) TaskModifier {
 	return func(task models.Task, now time.Time) (models.Task, error) {
-		err := ArchiveTask(task, now)
+		err := ArchiveTask(fsys, cfg, task, now)
 		if err != nil {
 			return models.Task{}, fmt.Errorf("failed to archive task: %w", err)
 		}
@@ -285,7 +326,7 @@ func ApplyModifiers(task models.Task, now time.Time, modifiers ...TaskModifier)
 			return models.Task{}, fmt.Errorf("modifier failed: %w", err)
 		}
 
-		if result == (models.Task{}) {
+		if IsEmptyTask(result) {
 			return result, nil
 		}
 	}
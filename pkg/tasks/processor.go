@@ -2,33 +2,67 @@ package tasks
 
 import (
 	"fmt"
+	"io/fs"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
-	"github.com/spf13/viper"
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/spf13/afero"
+	"golang.org/x/text/unicode/norm"
 )
 
-func ProcessAllTasks(now time.Time, configuration *viper.Viper) error {
-	retentionConfig := RetentionConfig{
-		EmptyTaskRetention: time.Duration(configuration.GetInt("settings.retention.empty_task")) * 24 * time.Hour,
-		ProjectRetention:   time.Duration(configuration.GetInt("settings.retention.project_before_archive")) * 24 * time.Hour,
+// ProcessAllTasks applies the planned actions to every completed and active task, in the
+// task folder and in every task area below it, see taskAreas. Each folder is processed
+// with the settings of its folder files, see config.ForPath.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault; wrap it in a files.ChangeFs to record changes
+//   - now: the current timestamp
+//   - cfg: the vault configuration
+//
+// Returns:
+//   - error: reading, planning or writing errors with context
+func ProcessAllTasks(fsys afero.Fs, now time.Time, cfg *config.Config) error {
+	areas, err := taskAreas(fsys, cfg)
+	if err != nil {
+		return err
 	}
 
-	activeTasksPath := filepath.Join(
-		configuration.GetString("base_path"),
-		configuration.GetString("paths.base.tasks"),
-	)
-	completedTasksPath := filepath.Join(
-		configuration.GetString("base_path"),
-		configuration.GetString("paths.subdirs.tasks.completed"),
-	)
-	// process completed tasks:
-	completedTasks, err := readTasksFromDirectory(completedTasksPath)
+	for _, area := range areas {
+		if err := processTaskArea(fsys, now, cfg, area); err != nil {
+			return fmt.Errorf("failed to process %s: %w", area.active, err)
+		}
+	}
+	return nil
+}
+
+// processTaskArea applies the planned actions to the completed and active tasks of an area.
+func processTaskArea(fsys afero.Fs, now time.Time, cfg *config.Config, area taskArea) error {
+	activeCfg, err := area.config(fsys, cfg, area.active)
 	if err != nil {
-		return fmt.Errorf("failed to read completed tasks: %w", err)
+		return err
+	}
+	completedCfg, err := area.config(fsys, cfg, area.completed)
+	if err != nil {
+		return err
+	}
+
+	activeTasksPath := area.active
+	completedTasksPath := area.completed
+	// process completed tasks:
+	var completedTasks []models.Task
+	if !area.pending {
+		completedTasks, err = readTasksFromDirectory(fsys, completedTasksPath)
+		if err != nil {
+			return fmt.Errorf("failed to read completed tasks: %w", err)
+		}
 	}
 	for _, task := range completedTasks {
-		modifiers, err := PlanCompletedTaskActions(task, time.Now(), retentionConfig)
+		modifiers, err := PlanCompletedTaskActions(fsys, completedCfg, task, now)
 		if err != nil {
 			return fmt.Errorf("failed to process completed tasks: %w", err)
 		}
@@ -38,20 +72,25 @@ func ProcessAllTasks(now time.Time, configuration *viper.Viper) error {
 			return fmt.Errorf("failed to apply task modifiers: %w", err)
 		}
 
-		err = RewriteTask(resultTask, completedTasksPath)
+		if resultTask.Title == "" {
+			// The task file was moved or deleted.
+			continue
+		}
+
+		err = RewriteTask(fsys, resultTask, completedTasksPath)
 		if err != nil {
 			return fmt.Errorf("failed to rewrite task: %w", err)
 		}
 	}
 
 	// process active tasks:
-	activeTasks, err := readTasksFromDirectory(activeTasksPath)
+	activeTasks, err := readTasksFromDirectory(fsys, activeTasksPath)
 	if err != nil {
 		return fmt.Errorf("failed to read active tasks: %w", err)
 	}
 
 	for _, task := range activeTasks {
-		modifiers, err := PlanActiveTaskActions(task, time.Now())
+		modifiers, err := PlanActiveTaskActions(fsys, activeCfg, task, now)
 		if err != nil {
 			return fmt.Errorf("failed to process active tasks: %w", err)
 		}
@@ -61,7 +100,12 @@ func ProcessAllTasks(now time.Time, configuration *viper.Viper) error {
 			return fmt.Errorf("failed to apply task modifiers: %w", err)
 		}
 
-		err = RewriteTask(resultTask, activeTasksPath)
+		if resultTask.Title == "" {
+			// The task file was moved or deleted.
+			continue
+		}
+
+		err = RewriteTask(fsys, resultTask, activeTasksPath)
 		if err != nil {
 			return fmt.Errorf("failed to rewrite task: %w", err)
 		}
@@ -69,3 +113,121 @@ func ProcessAllTasks(now time.Time, configuration *viper.Viper) error {
 
 	return nil
 }
+
+// CompleteTask marks an active task as done and moves it to the completed folder of its
+// task area, as a run would do for a task whose done field is set.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - title: the title of the task, matched like its file name
+//   - now: the completion time
+//
+// Returns:
+//   - string: the path of the completed task file
+//   - error: if no active task or several have this title, or the task cannot be
+//     written or moved
+func CompleteTask(fsys afero.Fs, cfg *config.Config, title string, now time.Time) (string, error) {
+	areas, err := taskAreas(fsys, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	name := files.TitleToFilename(title)
+	var found []taskArea
+	var src files.FilePath
+	for _, area := range areas {
+		path, exists, err := files.Resolve(fsys, files.FilePath{Dir: area.active, Name: name})
+		if err != nil {
+			return "", fmt.Errorf("failed to find task %q: %w", title, err)
+		}
+		if exists {
+			found = append(found, area)
+			src = path
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return "", fmt.Errorf("no active task %q: %w", title, fs.ErrNotExist)
+	case len(found) > 1:
+		dirs := make([]string, 0, len(found))
+		for _, area := range found {
+			dirs = append(dirs, area.active)
+		}
+		return "", fmt.Errorf("task %q is in several folders: %s", title, strings.Join(dirs, ", "))
+	}
+
+	area := found[0]
+	areaCfg, err := area.config(fsys, cfg, area.active)
+	if err != nil {
+		return "", err
+	}
+
+	taskResult, err := ReadTaskFile(fsys, src.FullPath())
+	if err != nil {
+		return "", err
+	}
+	if !taskResult.IsValid() {
+		return "", fmt.Errorf("%s is not a task", src.FullPath())
+	}
+
+	task, err := ApplyModifiers(taskResult.Value(), now, CompletionModifier(now))
+	if err != nil {
+		return "", err
+	}
+	if err := RewriteTask(fsys, task, area.active); err != nil {
+		return "", err
+	}
+
+	dest, err := moveTaskFile(fsys, cfg, src, files.FilePath{Dir: area.completed, Name: src.Name}, areaCfg.Tasks.Collision, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to move task file: %w", err)
+	}
+	return dest.FullPath(), nil
+}
+
+// AddTask writes a new task to the task folder. The title must be a single line that no
+// active or completed task of any task area has, compared case-insensitively so that the
+// file name is unique on every platform.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - task: the task to write
+//
+// Returns:
+//   - string: the path of the new task file
+//   - error: if the title is invalid or taken, or the file cannot be written
+func AddTask(fsys afero.Fs, cfg *config.Config, task models.Task) (string, error) {
+	title := norm.NFC.String(task.Title)
+	switch {
+	case strings.TrimSpace(title) == "":
+		return "", fmt.Errorf("task title is empty")
+	case title != strings.TrimSpace(title):
+		return "", fmt.Errorf("task title %q starts or ends with spaces", title)
+	case strings.IndexFunc(title, unicode.IsControl) >= 0:
+		return "", fmt.Errorf("task title %q has control characters or line breaks", title)
+	}
+
+	areas, err := taskAreas(fsys, cfg)
+	if err != nil {
+		return "", err
+	}
+	for _, area := range areas {
+		for _, dir := range area.folders() {
+			entries, err := afero.ReadDir(fsys, dir)
+			if err != nil {
+				return "", fmt.Errorf("failed to read directory %s: %w", dir, err)
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && filepath.Ext(entry.Name()) == files.MarkdownExt &&
+					strings.EqualFold(files.FilenameToTitle(entry.Name()), title) {
+					return "", fmt.Errorf("task %q already exists: %s", title, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+	}
+
+	task.Title = title
+	return TaskToFile(fsys, task, cfg.Paths.Tasks, cfg.Tasks.Collision)
+}
@@ -0,0 +1,510 @@
+package tasks_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+// processConfig is the configuration of the vault at /vault used by TestProcessAllTasks.
+const processConfig = `
+paths:
+  base:
+    tasks: Tasks
+    journal: Journals
+    archives: Archive
+  subdirs:
+    tasks:
+      completed: ${paths.base.tasks}/Completed
+settings:
+  retention:
+    empty_task: 30
+    project_before_archive: 7
+  patterns:
+    date_format: "YYYY-MM-DD"
+`
+
+// TestProcessAllTasks verifies that a run completes, archives and deletes tasks in the
+// directories of the configuration it is given, relative to the data path.
+func TestProcessAllTasks(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	created := now.AddDate(0, 0, -90).Format(time.RFC3339)
+	completed := now.AddDate(0, 0, -60).Format(time.RFC3339)
+
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep": "",
+		"/vault/Archive/.keep":  "",
+		"/vault/Tasks/Finished.md": "---\ncreated_at: " + created + "\nupdated_at: " + created +
+			"\ndo_date: \"2024-01-01\"\ndone: true\n---\n",
+		"/vault/Tasks/Completed/Old Chore.md": "---\ncreated_at: " + created + "\nupdated_at: " + created +
+			"\ndo_date: \"2024-01-01\"\ndone: true\ncompleted_at: " + completed + "\n---\n",
+		"/vault/Tasks/Completed/Old Project.md": "---\ncreated_at: " + created + "\nupdated_at: " + created +
+			"\ndo_date: \"2024-01-01\"\ndone: true\ncompleted_at: " + completed + "\nis_project: true\n---\n\nProject notes\n",
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if err := tasks.ProcessAllTasks(fsys, now, cfg); err != nil {
+		t.Fatalf("ProcessAllTasks() error = %v", err)
+	}
+
+	for path, want := range map[string]bool{
+		"/vault/Tasks/Finished.md":              false,
+		"/vault/Tasks/.md":                      false,
+		"/vault/Tasks/Completed/.md":            false,
+		"/vault/Tasks/Completed/Finished.md":    true,
+		"/vault/Tasks/Completed/Old Chore.md":   false,
+		"/vault/Tasks/Completed/Old Project.md": false,
+		"/vault/Archive/Old Project.md":         true,
+	} {
+		if exists, _ := afero.Exists(fsys, path); exists != want {
+			t.Errorf("%s exists = %v, want %v", path, exists, want)
+		}
+	}
+
+	trashed, err := afero.Glob(fsys, filepath.Join("/vault/.trash", "*", "Tasks", "Completed", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 2 {
+		t.Errorf("trashed files = %v, want the old chore and project", trashed)
+	}
+}
+
+// TestProcessAllTasks_FolderOverrides verifies that subfolders with a folder file are
+// processed with their own retention and completed folder, and that a missing completed
+// folder is created only when a task is moved there.
+func TestProcessAllTasks_FolderOverrides(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	task := func(completedDaysAgo int) string {
+		created := now.AddDate(0, 0, -120).Format(time.RFC3339)
+		content := "---\ncreated_at: " + created + "\nupdated_at: " + created + "\ndo_date: \"2024-01-01\"\ndone: true\n"
+		if completedDaysAgo > 0 {
+			content += "completed_at: " + now.AddDate(0, 0, -completedDaysAgo).Format(time.RFC3339) + "\n"
+		}
+		return content + "---\n"
+	}
+
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":                  "",
+		"/vault/Archive/.keep":                   "",
+		"/vault/Tasks/Completed/Errand.md":       task(10),
+		"/vault/Tasks/Work/.cerebgo.yaml":        "settings:\n  retention:\n    empty_task: 90\n",
+		"/vault/Tasks/Work/Draft.md":             task(0),
+		"/vault/Tasks/Work/Completed/Report.md":  task(60),
+		"/vault/Tasks/Home/.cerebgo.yaml":        "settings:\n  retention:\n    empty_task: 3\n",
+		"/vault/Tasks/Home/Completed/Dishes.md":  task(5),
+		"/vault/Tasks/Home/Completed/Laundry.md": task(1),
+		"/vault/Tasks/Someday/Idea.md":           task(0),
+		"/vault/Tasks/Errands/.cerebgo.yaml":     "settings:\n  retention:\n    empty_task: 3\n",
+		"/vault/Tasks/Errands/Post letter.md":    task(0),
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if _, err := tasks.Agenda(fsys, cfg, tasks.AgendaOptions{View: tasks.ViewCompleted, Days: 7}, now); err != nil {
+		t.Fatalf("Agenda() error = %v", err)
+	}
+	if _, err := tasks.MigrateAll(fsys, cfg, true); err != nil {
+		t.Fatalf("MigrateAll() error = %v", err)
+	}
+	if exists, _ := afero.DirExists(fsys, "/vault/Tasks/Errands/Completed"); exists {
+		t.Fatal("reading the tasks created /vault/Tasks/Errands/Completed")
+	}
+
+	if err := tasks.ProcessAllTasks(fsys, now, cfg); err != nil {
+		t.Fatalf("ProcessAllTasks() error = %v", err)
+	}
+
+	for path, want := range map[string]bool{
+		"/vault/Tasks/Errands/Completed/Post letter.md": true,
+		"/vault/Tasks/Completed/Errand.md":              true,
+		"/vault/Tasks/Work/Draft.md":                    false,
+		"/vault/Tasks/Work/Completed/Draft.md":          true,
+		"/vault/Tasks/Work/Completed/Report.md":         true,
+		"/vault/Tasks/Home/Completed/Dishes.md":         false,
+		"/vault/Tasks/Home/Completed/Laundry.md":        true,
+		"/vault/Tasks/Someday/Idea.md":                  true,
+	} {
+		if exists, _ := afero.Exists(fsys, path); exists != want {
+			t.Errorf("%s exists = %v, want %v", path, exists, want)
+		}
+	}
+}
+
+// TestCompleteTask verifies that a task is completed and moved to the completed folder
+// of its own task area.
+func TestCompleteTask(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	active := "---\ncreated_at: 2026-10-01T09:00:00Z\nupdated_at: 2026-10-01T09:00:00Z\ndo_date: \"2026-10-20\"\n---\n"
+
+	tests := []struct {
+		name    string
+		title   string
+		want    string // the completed task file
+		wantErr bool
+	}{
+		{name: "task folder", title: "Buy milk", want: "/vault/Tasks/Completed/Buy milk.md"},
+		{name: "task area", title: "Report", want: "/vault/Tasks/Work/Completed/Report.md"},
+		{name: "in several areas", title: "Plan", wantErr: true},
+		{name: "missing task", title: "Nothing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Journals/.keep":           "",
+				"/vault/Archive/.keep":            "",
+				"/vault/Tasks/Completed/.keep":    "",
+				"/vault/Tasks/Buy milk.md":        active,
+				"/vault/Tasks/Plan.md":            active,
+				"/vault/Tasks/Work/.cerebgo.yaml": "settings:\n  retention:\n    empty_task: 90\n",
+				"/vault/Tasks/Work/Report.md":     active,
+				"/vault/Tasks/Work/Plan.md":       active,
+			})
+
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+				t.Fatal(err)
+			}
+			if err := config.Interpolate(v); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := config.New(v, fsys, "/vault")
+			if err != nil {
+				t.Fatalf("config.New() error = %v", err)
+			}
+
+			got, err := tasks.CompleteTask(fsys, cfg, tt.title, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompleteTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CompleteTask() = %q, want %q", got, tt.want)
+			}
+
+			task, err := tasks.ReadTaskFile(fsys, got)
+			if err != nil || !task.IsValid() {
+				t.Fatalf("ReadTaskFile(%s) = %v, %v", got, task, err)
+			}
+			if !tasks.IsCompleted(task.Value()) || task.Value().CompletedAt.Value() != now {
+				t.Errorf("completed task = %+v, want done at %v", task.Value(), now)
+			}
+		})
+	}
+}
+
+// TestDeactivateModifier_KeepNewer verifies that a task file older than the completed
+// file it meets goes to the trash instead of being removed.
+func TestDeactivateModifier_KeepNewer(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Tasks/Buy milk.md":           "older",
+		"/vault/Tasks/Completed/Buy milk.md": "newer",
+	})
+	older := time.Now().Add(-time.Hour)
+	if err := fsys.Chtimes("/vault/Tasks/Buy milk.md", older, older); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		DataPath: "/vault",
+		Paths:    config.Paths{Tasks: "/vault/Tasks", CompletedTasks: "/vault/Tasks/Completed"},
+		Tasks:    config.Tasks{Collision: files.MoveKeepNewer},
+	}
+	task := models.Task{Title: "Buy milk", Filename: "Buy milk.md"}
+	if _, err := tasks.DeactivateModifier(fsys, cfg)(task, now); err != nil {
+		t.Fatalf("DeactivateModifier() error = %v", err)
+	}
+
+	testutil.AssertFsFileContent(t, fsys, "/vault/Tasks/Completed/Buy milk.md", "newer")
+	testutil.AssertFsFileContent(t, fsys, "/vault/.trash/20261019T090000Z/Tasks/Buy milk.md", "older")
+	if exists, _ := afero.Exists(fsys, "/vault/Tasks/Buy milk.md"); exists {
+		t.Error("older task file still exists")
+	}
+}
+
+// TestAddTask verifies that a new task is written to the task folder, and that titles
+// taken by a task of any area are refused.
+func TestAddTask(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	existing := "---\ncreated_at: 2026-10-01T09:00:00Z\nupdated_at: 2026-10-01T09:00:00Z\ndo_date: \"2026-10-20\"\n---\n"
+
+	tests := []struct {
+		name    string
+		title   string
+		want    string
+		wantErr bool
+	}{
+		{name: "new task", title: "Call Dana", want: "/vault/Tasks/Call Dana.md"},
+		{name: "unsafe characters are escaped", title: "Read: a/b", want: "/vault/Tasks/Read%3A a%2Fb.md"},
+		{name: "active task", title: "buy milk", wantErr: true},
+		{name: "completed task", title: "Old Chore", wantErr: true},
+		{name: "task of an area", title: "Report", wantErr: true},
+		{name: "empty title", title: "  ", wantErr: true},
+		{name: "line break", title: "Two\nlines", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Journals/.keep":               "",
+				"/vault/Archive/.keep":                "",
+				"/vault/Tasks/Buy milk.md":            existing,
+				"/vault/Tasks/Completed/Old Chore.md": existing,
+				"/vault/Tasks/Work/.cerebgo.yaml":     "settings:\n  retention:\n    empty_task: 90\n",
+				"/vault/Tasks/Work/Report.md":         existing,
+			})
+
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+				t.Fatal(err)
+			}
+			if err := config.Interpolate(v); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := config.New(v, fsys, "/vault")
+			if err != nil {
+				t.Fatalf("config.New() error = %v", err)
+			}
+
+			task := models.Task{
+				Title:          tt.title,
+				Content:        ptr.Some("Ask about the trip"),
+				IsHighPriority: true,
+				DoDate:         "2026-10-20",
+				DueDate:        ptr.Some("2026-11-01"),
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			got, err := tasks.AddTask(fsys, cfg, task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("AddTask() = %q, want %q", got, tt.want)
+			}
+
+			read, err := tasks.ReadTaskFile(fsys, got)
+			if err != nil || !read.IsValid() {
+				t.Fatalf("ReadTaskFile(%s) = %v, %v", got, read, err)
+			}
+			if gotTask := read.Value(); gotTask.Title != tt.title || gotTask.DoDate != task.DoDate ||
+				gotTask.DueDate != task.DueDate || !gotTask.IsHighPriority || gotTask.Content != task.Content {
+				t.Errorf("written task = %+v, want %+v", gotTask, task)
+			}
+		})
+	}
+}
+
+// TestProcessAllTasks_DoDates verifies that do dates in the past move to today, that
+// later do dates are kept, and that a second run has nothing left to change.
+func TestProcessAllTasks_DoDates(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	task := func(doDate string) string {
+		return "---\ncreated_at: 2026-10-01T09:00:00Z\nupdated_at: 2026-10-01T09:00:00Z\ndo_date: \"" + doDate + "\"\n---\n"
+	}
+
+	base := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Tasks/Overdue.md":      task("2026-10-12"),
+		"/vault/Tasks/Today.md":        task("2026-10-19"),
+		"/vault/Tasks/Later.md":        task("2026-11-01"),
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, base, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if err := tasks.ProcessAllTasks(base, now, cfg); err != nil {
+		t.Fatalf("ProcessAllTasks() error = %v", err)
+	}
+	for path, want := range map[string]string{
+		"/vault/Tasks/Overdue.md": "2026-10-19",
+		"/vault/Tasks/Today.md":   "2026-10-19",
+		"/vault/Tasks/Later.md":   "2026-11-01",
+	} {
+		got, err := tasks.ReadTaskFile(base, path)
+		if err != nil || !got.IsValid() {
+			t.Fatalf("ReadTaskFile(%s) = %v, %v", path, got, err)
+		}
+		if got.Value().DoDate != want {
+			t.Errorf("%s do date = %s, want %s", path, got.Value().DoDate, want)
+		}
+	}
+
+	fsys := files.NewChangeFs(base)
+	if err := tasks.ProcessAllTasks(fsys, now.Add(time.Hour), cfg); err != nil {
+		t.Fatalf("second ProcessAllTasks() error = %v", err)
+	}
+	if changes := fsys.Changes(); len(changes) > 0 {
+		t.Errorf("second run changed %v, want no changes", changes)
+	}
+}
+
+// TestProcessAllTasks_FrontmatterFormats verifies that tasks with TOML and JSON
+// frontmatter keep their format and schema version across runs, so a second run reads
+// the schema_version written by the first and has nothing left to change.
+func TestProcessAllTasks_FrontmatterFormats(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	base := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Tasks/TOML task.md": "+++\ncreated_at = \"2026-10-01T09:00:00Z\"\n" +
+			"updated_at = \"2026-10-01T09:00:00Z\"\ndo_date = \"2026-10-12\"\n+++\n",
+		"/vault/Tasks/JSON task.md": "{\n  \"created_at\": \"2026-10-01T09:00:00Z\",\n" +
+			"  \"updated_at\": \"2026-10-01T09:00:00Z\",\n  \"do_date\": \"2026-10-12\"\n}\n",
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, base, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if err := tasks.ProcessAllTasks(base, now, cfg); err != nil {
+		t.Fatalf("ProcessAllTasks() error = %v", err)
+	}
+	for path, want := range map[string]string{
+		"/vault/Tasks/TOML task.md": "schema_version = 1\n",
+		"/vault/Tasks/JSON task.md": "\"schema_version\": 1",
+	} {
+		content, err := afero.ReadFile(base, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s = %q, want it to contain %q", path, content, want)
+		}
+	}
+
+	fsys := files.NewChangeFs(base)
+	if err := tasks.ProcessAllTasks(fsys, now.Add(time.Hour), cfg); err != nil {
+		t.Fatalf("second ProcessAllTasks() error = %v", err)
+	}
+	if changes := fsys.Changes(); len(changes) > 0 {
+		t.Errorf("second run changed %v, want no changes", changes)
+	}
+}
+
+// TestProcessAllTasks_UnescapedNames verifies that task files named before titles were
+// escaped, with characters such as "%" and "?" in their names, are rewritten and moved
+// under their own names.
+func TestProcessAllTasks_UnescapedNames(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	task := func(extra string) string {
+		return "---\ncreated_at: 2026-10-01T09:00:00Z\nupdated_at: 2026-10-01T09:00:00Z\ndo_date: \"2026-10-12\"\n" + extra + "---\n"
+	}
+
+	base := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":        "",
+		"/vault/Archive/.keep":         "",
+		"/vault/Tasks/Completed/.keep": "",
+		"/vault/Tasks/50% off.md":      task(""),
+		"/vault/Tasks/What now?.md":    task("done: true\n"),
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, base, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if err := tasks.ProcessAllTasks(base, now, cfg); err != nil {
+		t.Fatalf("ProcessAllTasks() error = %v", err)
+	}
+
+	for path, wantDoDate := range map[string]string{
+		"/vault/Tasks/50% off.md":             "2026-10-19",
+		"/vault/Tasks/Completed/What now?.md": "2026-10-12",
+	} {
+		got, err := tasks.ReadTaskFile(base, path)
+		if err != nil || !got.IsValid() {
+			t.Fatalf("ReadTaskFile(%s) = %v, %v", path, got, err)
+		}
+		if got.Value().DoDate != wantDoDate {
+			t.Errorf("%s do date = %s, want %s", path, got.Value().DoDate, wantDoDate)
+		}
+	}
+	if content, _ := afero.ReadFile(base, "/vault/Tasks/50% off.md"); strings.Contains(string(content), "title:") {
+		t.Errorf("/vault/Tasks/50%% off.md = %q, want no title field, as the file name holds the title", content)
+	}
+	for _, path := range []string{"/vault/Tasks/50%25 off.md", "/vault/Tasks/What now?.md", "/vault/Tasks/Completed/What now%3F.md"} {
+		if exists, _ := afero.Exists(base, path); exists {
+			t.Errorf("%s exists, want the task kept under its own name", path)
+		}
+	}
+
+	if err := tasks.ProcessAllTasks(base, now.Add(time.Hour), cfg); err != nil {
+		t.Fatalf("second ProcessAllTasks() error = %v", err)
+	}
+	if entries, _ := afero.ReadDir(base, "/vault/Tasks/Completed"); len(entries) != 2 {
+		t.Errorf("/vault/Tasks/Completed holds %d files after the second run, want 2", len(entries))
+	}
+}
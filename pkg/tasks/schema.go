@@ -0,0 +1,314 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/spf13/afero"
+)
+
+// SchemaVersion is the task frontmatter schema written by this version of cerebgo.
+//
+// Versions:
+//   - 0: files without a schema_version field, in either of the two historical layouts:
+//     "priority: high" with a required updated_at and RFC3339 due dates, or
+//     is_high_priority with a required do_date
+//   - 1: is_high_priority, do_date and YYYY-MM-DD due dates, with schema_version
+const SchemaVersion = 1
+
+// schemaVersionField is the frontmatter field holding the schema version of a task file.
+const schemaVersionField = "schema_version"
+
+// taskFrontmatter is the frontmatter of a task file in the current schema.
+// Unknown fields are kept in Extra and written back unchanged.
+type taskFrontmatter struct {
+	SchemaVersion  int                    `fm:"schema_version"`
+	CreatedAt      time.Time              `fm:"created_at,required,timestamp"`
+	DoDate         string                 `fm:"do_date,required"`
+	UpdatedAt      ptr.Option[time.Time]  `fm:"updated_at,timestamp"`
+	CompletedAt    ptr.Option[time.Time]  `fm:"completed_at,timestamp"`
+	DueDate        ptr.Option[string]     `fm:"due_date"`
+	Done           bool                   `fm:"done"`
+	IsProject      bool                   `fm:"is_project"`
+	IsHighPriority bool                   `fm:"is_high_priority"`
+	Extra          map[string]interface{} `fm:",extra"`
+}
+
+// taskMigration upgrades task frontmatter from one schema version to the next.
+// It returns a description of every change it made.
+type taskMigration func(fm mdparser.Frontmatter, content string) []string
+
+// taskMigrations holds the migration from version i to version i+1 at index i.
+var taskMigrations = []taskMigration{
+	migrateUnversioned,
+}
+
+// TaskSchemaVersion returns the schema version of a task document.
+//
+// Parameters:
+//   - fm: frontmatter of the task document
+//
+// Returns:
+//   - int: the schema version, 0 for files without a schema_version field
+//   - error: if the version is not a number or is newer than SchemaVersion
+func TaskSchemaVersion(fm mdparser.Frontmatter) (int, error) {
+	value, ok := fm[schemaVersionField]
+	if !ok {
+		return 0, nil
+	}
+
+	version, ok := mdparser.ToInt(value)
+	if !ok {
+		return 0, fmt.Errorf("invalid %s %v: must be a whole number", schemaVersionField, value)
+	}
+	if version < 0 || version > SchemaVersion {
+		return 0, fmt.Errorf("unsupported %s %d: this version of cerebgo supports up to %d", schemaVersionField, version, SchemaVersion)
+	}
+	return int(version), nil
+}
+
+// UpgradeTaskDocument migrates a task document to the current schema.
+// The document is not modified; a migrated copy is returned.
+//
+// Parameters:
+//   - doc: task document in any supported schema version
+//
+// Returns:
+//   - mdparser.MarkdownDocument: the document in the current schema
+//   - []string: descriptions of the changes made, empty if the document was current
+//   - error: if the schema version is invalid
+func UpgradeTaskDocument(doc mdparser.MarkdownDocument) (mdparser.MarkdownDocument, []string, error) {
+	version, err := TaskSchemaVersion(doc.Frontmatter)
+	if err != nil {
+		return mdparser.MarkdownDocument{}, nil, err
+	}
+
+	fm := make(mdparser.Frontmatter, len(doc.Frontmatter)+1)
+	for key, value := range doc.Frontmatter {
+		fm[key] = value
+	}
+
+	changes := make([]string, 0)
+	for v := version; v < SchemaVersion; v++ {
+		changes = append(changes, taskMigrations[v](fm, doc.Content)...)
+	}
+	if version < SchemaVersion {
+		fm[schemaVersionField] = SchemaVersion
+		changes = append(changes, fmt.Sprintf("set %s to %d", schemaVersionField, SchemaVersion))
+	}
+
+	return mdparser.MarkdownDocument{
+		Title:       doc.Title,
+		Frontmatter: fm,
+		Content:     doc.Content,
+	}, changes, nil
+}
+
+// DocumentToTask converts a markdown document into a Task model. Documents in older
+// schema versions are upgraded in memory first, see UpgradeTaskDocument.
+//
+// Required frontmatter fields:
+// - created_at: timestamp of task creation
+// - do_date: string representing when the task should be done
+//
+// Optional frontmatter fields:
+// - updated_at: timestamp of last update (defaults to created_at)
+// - completed_at: timestamp of task completion
+// - due_date: string deadline for the task
+// - done: boolean indicating completion status
+// - is_project: boolean marking task as a project
+// - is_high_priority: boolean for priority level
+//
+// Any other frontmatter field is kept in Task.Extra.
+//
+// Returns a *mdparser.DecodeError listing every missing or invalid field, or an error
+// if the schema version is unsupported.
+func DocumentToTask(doc mdparser.MarkdownDocument) (models.Task, error) {
+	if doc.Title == "" {
+		return models.Task{}, fmt.Errorf("task title is required")
+	}
+
+	current, _, err := UpgradeTaskDocument(doc)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	var fm taskFrontmatter
+	if err := mdparser.Decode(current.Frontmatter, &fm); err != nil {
+		return models.Task{}, err
+	}
+
+	task := models.Task{
+		Title:          doc.Title,
+		Content:        ptr.None[string](),
+		IsProject:      fm.IsProject,
+		IsHighPriority: fm.IsHighPriority,
+		Done:           fm.Done,
+		CompletedAt:    fm.CompletedAt,
+		DueDate:        fm.DueDate,
+		DoDate:         fm.DoDate,
+		CreatedAt:      fm.CreatedAt,
+		UpdatedAt:      fm.CreatedAt,
+		Extra:          fm.Extra,
+	}
+	if fm.UpdatedAt.IsValid() {
+		task.UpdatedAt = fm.UpdatedAt.Value()
+	}
+	if doc.Content != "" {
+		task.Content = ptr.Some(doc.Content)
+	}
+
+	return task, nil
+}
+
+// MigrateTaskFile rewrites a task file in the current schema.
+//
+// Parameters:
+//   - fsys: filesystem holding the file
+//   - filePath: path of the task file
+//   - dryRun: report the changes without writing the file
+//
+// Returns:
+//   - []string: descriptions of the changes, empty if the file was already current
+//   - error: reading, migration or writing errors with context
+func MigrateTaskFile(fsys afero.Fs, filePath string, dryRun bool) ([]string, error) {
+	doc, err := mdparser.ParseMarkdownDoc(fsys, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown from %s: %w", filePath, err)
+	}
+
+	current, changes, err := UpgradeTaskDocument(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate %s: %w", filePath, err)
+	}
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+
+	if err := mdparser.WriteMarkdownDoc(fsys, current.Frontmatter, current.Content, filePath); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return changes, nil
+}
+
+// MigrateTaskDirectory migrates every markdown file in a directory.
+//
+// Parameters:
+//   - fsys: filesystem holding the directory
+//   - dir: directory containing task files
+//   - dryRun: report the changes without writing files
+//
+// Returns:
+//   - map[string][]string: changes per migrated file path, only for files that changed
+//   - error: reading the directory, or the combined errors of files that failed
+func MigrateTaskDirectory(fsys afero.Fs, dir string, dryRun bool) (map[string][]string, error) {
+	entries, err := afero.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	migrated := make(map[string][]string)
+	failures := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		changes, err := MigrateTaskFile(fsys, path, dryRun)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if len(changes) > 0 {
+			migrated[path] = changes
+		}
+	}
+
+	if len(failures) > 0 {
+		return migrated, fmt.Errorf("failed to migrate %d files:\n  %s", len(failures), strings.Join(failures, "\n  "))
+	}
+	return migrated, nil
+}
+
+// MigrateAll migrates every task file of the task folder and its task areas, active and
+// completed, which are the folders a run processes.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//   - dryRun: report the changes without writing files
+//
+// Returns:
+//   - map[string][]string: changes per migrated file path, only for files that changed
+//   - error: finding the task areas, or the combined errors of the folders that failed
+func MigrateAll(fsys afero.Fs, cfg *config.Config, dryRun bool) (map[string][]string, error) {
+	areas, err := taskAreas(fsys, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := make(map[string][]string)
+	var errs []error
+	for _, area := range areas {
+		for _, dir := range area.folders() {
+			changes, err := MigrateTaskDirectory(fsys, dir, dryRun)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for path, fileChanges := range changes {
+				migrated[path] = fileChanges
+			}
+		}
+	}
+	return migrated, errors.Join(errs...)
+}
+
+// migrateUnversioned upgrades files without a schema_version to version 1.
+//
+// The older layout written by the first parser used "priority: high", RFC3339 due dates,
+// had no do_date and derived is_project from the presence of content. The later layout
+// only differs from version 1 by the missing schema_version.
+func migrateUnversioned(fm mdparser.Frontmatter, content string) []string {
+	changes := make([]string, 0)
+	_, hasDoDate := fm["do_date"]
+
+	if priority, ok := fm["priority"]; ok {
+		if _, exists := fm["is_high_priority"]; !exists {
+			fm["is_high_priority"] = priority == "high"
+			changes = append(changes, fmt.Sprintf("replaced priority: %v with is_high_priority: %v", priority, priority == "high"))
+		} else {
+			changes = append(changes, fmt.Sprintf("removed priority: %v", priority))
+		}
+		delete(fm, "priority")
+	}
+
+	if dueDate, ok := mdparser.GetTime(fm, "due_date"); ok {
+		fm["due_date"] = dueDate.Format("2006-01-02")
+		changes = append(changes, fmt.Sprintf("converted due_date to %s", fm["due_date"]))
+	}
+
+	if !hasDoDate {
+		if dueDate, ok := mdparser.GetString(fm, "due_date"); ok {
+			fm["do_date"] = dueDate
+			changes = append(changes, fmt.Sprintf("set do_date to %s from due_date", dueDate))
+		} else if createdAt, ok := mdparser.GetTime(fm, "created_at"); ok {
+			fm["do_date"] = createdAt.Format("2006-01-02")
+			changes = append(changes, fmt.Sprintf("set do_date to %s from created_at", fm["do_date"]))
+		}
+
+		if _, exists := fm["is_project"]; !exists {
+			fm["is_project"] = content != ""
+			changes = append(changes, fmt.Sprintf("set is_project to %v from content", content != ""))
+		}
+	}
+
+	return changes
+}
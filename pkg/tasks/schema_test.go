@@ -0,0 +1,353 @@
+package tasks_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/avivSarig/cerebgo/pkg/mdparser"
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+	"github.com/avivSarig/cerebgo/pkg/tasks"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/spf13/viper"
+)
+
+// TestDocumentToTask_Schemas verifies that documents in every supported schema version
+// decode to the same model.
+func TestDocumentToTask_Schemas(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	baseTimeStr := baseTime.Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		doc     mdparser.MarkdownDocument
+		want    models.Task
+		wantErr bool
+	}{
+		{
+			name: "legacy priority layout with all fields",
+			doc: mdparser.MarkdownDocument{
+				Title: "Test Task",
+				Frontmatter: mdparser.Frontmatter{
+					"created_at": baseTimeStr,
+					"updated_at": baseTimeStr,
+					"due_date":   "2024-01-05T00:00:00Z",
+					"priority":   "high",
+				},
+				Content: "Test content",
+			},
+			want: models.Task{
+				Title:          "Test Task",
+				Content:        ptr.Some("Test content"),
+				IsProject:      true,
+				IsHighPriority: true,
+				CreatedAt:      baseTime,
+				UpdatedAt:      baseTime,
+				DoDate:         "2024-01-05",
+				DueDate:        ptr.Some("2024-01-05"),
+				CompletedAt:    ptr.None[time.Time](),
+			},
+		},
+		{
+			name: "legacy minimal layout",
+			doc: mdparser.MarkdownDocument{
+				Title: "Test Task",
+				Frontmatter: mdparser.Frontmatter{
+					"created_at": baseTimeStr,
+					"updated_at": baseTimeStr,
+				},
+			},
+			want: models.Task{
+				Title:       "Test Task",
+				Content:     ptr.None[string](),
+				CreatedAt:   baseTime,
+				UpdatedAt:   baseTime,
+				DoDate:      "2024-01-01",
+				DueDate:     ptr.None[string](),
+				CompletedAt: ptr.None[time.Time](),
+			},
+		},
+		{
+			name: "legacy unknown priority is not high",
+			doc: mdparser.MarkdownDocument{
+				Title: "Test Task",
+				Frontmatter: mdparser.Frontmatter{
+					"created_at": baseTimeStr,
+					"priority":   "SUPER HIGH",
+				},
+			},
+			want: models.Task{
+				Title:       "Test Task",
+				Content:     ptr.None[string](),
+				CreatedAt:   baseTime,
+				UpdatedAt:   baseTime,
+				DoDate:      "2024-01-01",
+				DueDate:     ptr.None[string](),
+				CompletedAt: ptr.None[time.Time](),
+			},
+		},
+		{
+			name: "unversioned do_date layout",
+			doc: mdparser.MarkdownDocument{
+				Title: "Test Task",
+				Frontmatter: mdparser.Frontmatter{
+					"created_at":       baseTimeStr,
+					"do_date":          "2024-01-03",
+					"is_high_priority": true,
+				},
+				Content: "Notes",
+			},
+			want: models.Task{
+				Title:          "Test Task",
+				Content:        ptr.Some("Notes"),
+				IsHighPriority: true,
+				CreatedAt:      baseTime,
+				UpdatedAt:      baseTime,
+				DoDate:         "2024-01-03",
+				DueDate:        ptr.None[string](),
+				CompletedAt:    ptr.None[time.Time](),
+			},
+		},
+		{
+			name: "current schema",
+			doc: mdparser.MarkdownDocument{
+				Title: "Test Task",
+				Frontmatter: mdparser.Frontmatter{
+					"schema_version": 1,
+					"created_at":     baseTimeStr,
+					"do_date":        "2024-01-03",
+					"due_date":       "2024-01-04",
+					"done":           true,
+				},
+			},
+			want: models.Task{
+				Title:       "Test Task",
+				Content:     ptr.None[string](),
+				Done:        true,
+				CreatedAt:   baseTime,
+				UpdatedAt:   baseTime,
+				DoDate:      "2024-01-03",
+				DueDate:     ptr.Some("2024-01-04"),
+				CompletedAt: ptr.None[time.Time](),
+			},
+		},
+		{
+			name: "current schema requires do_date",
+			doc: mdparser.MarkdownDocument{
+				Title:       "Test Task",
+				Frontmatter: mdparser.Frontmatter{"schema_version": 1, "created_at": baseTimeStr},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing created_at",
+			doc: mdparser.MarkdownDocument{
+				Title:       "Test Task",
+				Frontmatter: mdparser.Frontmatter{"updated_at": baseTimeStr},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing title",
+			doc: mdparser.MarkdownDocument{
+				Frontmatter: mdparser.Frontmatter{"created_at": baseTimeStr, "do_date": "2024-01-01"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "created_at not RFC3339",
+			doc: mdparser.MarkdownDocument{
+				Title:       "Test Task",
+				Frontmatter: mdparser.Frontmatter{"created_at": "2024-01-01", "do_date": "2024-01-01"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newer schema version",
+			doc: mdparser.MarkdownDocument{
+				Title:       "Test Task",
+				Frontmatter: mdparser.Frontmatter{"schema_version": 99, "created_at": baseTimeStr, "do_date": "2024-01-01"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tasks.DocumentToTask(tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DocumentToTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				testutil.AssertTaskEqual(t, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDocumentToTask_FieldErrors verifies that every invalid field is reported by name.
+func TestDocumentToTask_FieldErrors(t *testing.T) {
+	doc := mdparser.MarkdownDocument{
+		Title: "Test Task",
+		Frontmatter: mdparser.Frontmatter{
+			"schema_version": 1,
+			"created_at":     "2024-01-01T00:00:00Z",
+			"completed_at":   "yesterday",
+			"done":           "maybe",
+		},
+	}
+
+	_, err := tasks.DocumentToTask(doc)
+	var decodeErr *mdparser.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("DocumentToTask() error = %v, want a *mdparser.DecodeError", err)
+	}
+
+	paths := make([]string, 0, len(decodeErr.Errors))
+	for _, fieldErr := range decodeErr.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	sort.Strings(paths)
+	if want := []string{"completed_at", "do_date", "done"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("invalid fields = %v, want %v", paths, want)
+	}
+}
+
+// TestUpgradeTaskDocument verifies the reported changes and that the input is left untouched.
+func TestUpgradeTaskDocument(t *testing.T) {
+	doc := mdparser.MarkdownDocument{
+		Title: "Legacy",
+		Frontmatter: mdparser.Frontmatter{
+			"created_at": "2024-01-01T00:00:00Z",
+			"priority":   "high",
+		},
+		Content: "Body",
+	}
+
+	upgraded, changes, err := tasks.UpgradeTaskDocument(doc)
+	if err != nil {
+		t.Fatalf("UpgradeTaskDocument() error = %v", err)
+	}
+
+	wantChanges := []string{
+		"replaced priority: high with is_high_priority: true",
+		"set do_date to 2024-01-01 from created_at",
+		"set is_project to true from content",
+		"set schema_version to 1",
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("changes = %q, want %q", changes, wantChanges)
+	}
+
+	wantFM := mdparser.Frontmatter{
+		"created_at":       "2024-01-01T00:00:00Z",
+		"do_date":          "2024-01-01",
+		"is_high_priority": true,
+		"is_project":       true,
+		"schema_version":   tasks.SchemaVersion,
+	}
+	if !reflect.DeepEqual(upgraded.Frontmatter, map[string]interface{}(wantFM)) {
+		t.Errorf("Frontmatter = %v, want %v", upgraded.Frontmatter, wantFM)
+	}
+	if _, ok := doc.Frontmatter["priority"]; !ok {
+		t.Error("UpgradeTaskDocument() modified its input")
+	}
+
+	_, changes, err = tasks.UpgradeTaskDocument(upgraded)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("upgrading a current document: changes = %q, error = %v", changes, err)
+	}
+}
+
+// TestMigrateTaskDirectory verifies that old files are rewritten in place and current
+// files are left alone.
+func TestMigrateTaskDirectory(t *testing.T) {
+	legacy := "---\n# imported\ncreated_at: \"2024-01-01T00:00:00Z\"\nupdated_at: \"2024-01-02T00:00:00Z\"\npriority: normal\n---\n\nBody\n"
+	current := "---\nschema_version: 1\ncreated_at: \"2024-01-01T00:00:00Z\"\ndo_date: 2024-01-01\n---\n"
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/tasks/Legacy.md":  legacy,
+		"/tasks/Current.md": current,
+		"/tasks/Broken.md":  "---\nschema_version: x\n---\n",
+		"/tasks/notes.txt":  "x",
+	})
+
+	dryRun, err := tasks.MigrateTaskDirectory(fsys, "/tasks", true)
+	if err == nil || !strings.Contains(err.Error(), "Broken.md") {
+		t.Errorf("MigrateTaskDirectory() error = %v, want failure for Broken.md", err)
+	}
+	if len(dryRun) != 1 {
+		t.Fatalf("dry run changes = %v, want only Legacy.md", dryRun)
+	}
+	testutil.AssertFsFileContent(t, fsys, "/tasks/Legacy.md", legacy)
+
+	migrated, _ := tasks.MigrateTaskDirectory(fsys, "/tasks", false)
+	if len(migrated[filepath.Join("/tasks", "Legacy.md")]) == 0 {
+		t.Fatalf("migrated = %v, want changes for Legacy.md", migrated)
+	}
+
+	testutil.AssertFsFileContent(t, fsys, "/tasks/Legacy.md",
+		"---\n# imported\ncreated_at: \"2024-01-01T00:00:00Z\"\nupdated_at: \"2024-01-02T00:00:00Z\"\n"+
+			"do_date: \"2024-01-01\"\nis_high_priority: false\nis_project: true\nschema_version: 1\n---\n\nBody\n")
+	testutil.AssertFsFileContent(t, fsys, "/tasks/Current.md", current)
+
+	again, _ := tasks.MigrateTaskDirectory(fsys, "/tasks", false)
+	if len(again) != 0 {
+		t.Errorf("second migration changed %v", again)
+	}
+}
+
+// TestMigrateAll verifies that task areas are migrated along with the task folder, in
+// both their active and completed folders.
+func TestMigrateAll(t *testing.T) {
+	legacy := "---\ncreated_at: \"2024-01-01T00:00:00Z\"\ndo_date: \"2024-01-01\"\n---\n"
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/Journals/.keep":                 "",
+		"/vault/Archive/.keep":                  "",
+		"/vault/Tasks/Root.md":                  legacy,
+		"/vault/Tasks/Completed/Done.md":        legacy,
+		"/vault/Tasks/Work/.cerebgo.yaml":       "settings:\n  retention:\n    empty_task: 90\n",
+		"/vault/Tasks/Work/Report.md":           legacy,
+		"/vault/Tasks/Work/Completed/Slides.md": legacy,
+		"/vault/Tasks/Notes/Loose.md":           legacy,
+	})
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(processConfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Interpolate(v); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.New(v, fsys, "/vault")
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	migrated, err := tasks.MigrateAll(fsys, cfg, false)
+	if err != nil {
+		t.Fatalf("MigrateAll() error = %v", err)
+	}
+
+	got := make([]string, 0, len(migrated))
+	for path := range migrated {
+		got = append(got, path)
+	}
+	sort.Strings(got)
+	want := []string{
+		"/vault/Tasks/Completed/Done.md",
+		"/vault/Tasks/Root.md",
+		"/vault/Tasks/Work/Completed/Slides.md",
+		"/vault/Tasks/Work/Report.md",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MigrateAll() migrated %v, want %v", got, want)
+	}
+	testutil.AssertFsFileContent(t, fsys, "/vault/Tasks/Notes/Loose.md", legacy)
+}
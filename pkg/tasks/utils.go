@@ -1,6 +1,10 @@
 package tasks
 
 import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/avivSarig/cerebgo/internal/models"
@@ -17,29 +21,35 @@ func IsCompleted(t models.Task) bool {
 	return t.Done && t.CompletedAt.IsValid()
 }
 
+// IsEmptyTask checks if a task is the empty task returned by modifiers that delete,
+// move or archive the task file
+//
+// Parameters:
+//   - task: Task to check
+//
+// Returns:
+//   - bool: true if all task fields hold zero values
+func IsEmptyTask(t models.Task) bool {
+	return reflect.ValueOf(t).IsZero()
+}
+
 // IsValidDoDate checks if the task's DoDate is valid - has valid format and is not in the past
 //
 // Parameters:
 //   - task: Task to check
+//   - now: the current time, in the time zone of the user
 //
 // Returns:
 //   - bool: true if task's DoDate is valid
 func IsValidDoDate(t models.Task, now time.Time) bool {
-	doDateStr, err := time.Parse("2006-01-02", t.DoDate)
-	if err != nil {
-		return false
-	}
-
-	nowDate := now.Truncate(24 * time.Hour)
-	doDate := doDateStr.Truncate(24 * time.Hour)
-
-	return !nowDate.After(doDate)
+	return notPast(t.DoDate, now)
 }
 
 // IsValidDueDate checks if the task's DueDate is valid - has valid format and is not in the past
 //
 // Parameters:
 //   - task: Task to check
+//   - now: the current time, in the time zone of the user
 //
 // Returns:
 //   - bool: true if task's DoDate is valid
@@ -47,14 +57,66 @@ func IsValidDueDate(t models.Task, now time.Time) bool {
 	if !t.DueDate.IsValid() {
 		return false
 	}
+	return notPast(t.DueDate.Value(), now)
+}
 
-	dueDateStr, err := time.Parse("2006-01-02", t.DueDate.Value())
-	if err != nil {
+// dateLayout is the layout of the do_date and due_date fields.
+const dateLayout = "2006-01-02"
+
+// notPast reports whether date is a valid date no earlier than the calendar day of now
+// in its own time zone. Dates in dateLayout order like their strings.
+func notPast(date string, now time.Time) bool {
+	if _, err := time.Parse(dateLayout, date); err != nil {
 		return false
 	}
+	return date >= now.Format(dateLayout)
+}
+
+// ParseDate parses a date given on the command line into the layout of the do_date and
+// due_date fields. Besides YYYY-MM-DD it accepts "today", "tomorrow", a weekday name for
+// the next such day after today, and "+3d" or "+2w" for a number of days or weeks from
+// today.
+//
+// Parameters:
+//   - value: the date to parse, case-insensitive
+//   - now: the current time, in the time zone of the user
+//
+// Returns:
+//   - string: the date as YYYY-MM-DD
+//   - error: if value is not one of the accepted forms
+func ParseDate(value string, now time.Time) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch value {
+	case "today":
+		return today.Format(dateLayout), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1).Format(dateLayout), nil
+	}
 
-	nowDate := now.Truncate(24 * time.Hour)
-	dueDate := dueDateStr.Truncate(24 * time.Hour)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			ahead := (int(day)-int(today.Weekday())+6)%7 + 1
+			return today.AddDate(0, 0, ahead).Format(dateLayout), nil
+		}
+	}
 
-	return !nowDate.After(dueDate)
+	if strings.HasPrefix(value, "+") && len(value) > 2 {
+		n, err := strconv.Atoi(value[1 : len(value)-1])
+		switch {
+		case err != nil || n < 0:
+		case strings.HasSuffix(value, "d"):
+			return today.AddDate(0, 0, n).Format(dateLayout), nil
+		case strings.HasSuffix(value, "w"):
+			return today.AddDate(0, 0, 7*n).Format(dateLayout), nil
+		}
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD, today, tomorrow, a weekday or +Nd/+Nw", value)
+	}
+	return date.Format(dateLayout), nil
 }
@@ -89,6 +89,18 @@ func TestIsValidDoDate(t *testing.T) {
 			now:  fixedTime,
 			want: false,
 		},
+		{
+			name: "local today, already tomorrow in UTC",
+			task: models.Task{DoDate: "2024-01-01"},
+			now:  time.Date(2024, 1, 1, 22, 0, 0, 0, time.FixedZone("UTC-8", -8*3600)),
+			want: true,
+		},
+		{
+			name: "local yesterday, still today in UTC",
+			task: models.Task{DoDate: "2023-12-31"},
+			now:  time.Date(2024, 1, 1, 7, 0, 0, 0, time.FixedZone("UTC+9", 9*3600)),
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -144,6 +156,18 @@ func TestIsValidDueDate(t *testing.T) {
 			now:  fixedTime,
 			want: false,
 		},
+		{
+			name: "local today, already tomorrow in UTC",
+			task: models.Task{DueDate: ptr.Some("2024-01-01")},
+			now:  time.Date(2024, 1, 1, 22, 0, 0, 0, time.FixedZone("UTC-8", -8*3600)),
+			want: true,
+		},
+		{
+			name: "local yesterday, still today in UTC",
+			task: models.Task{DueDate: ptr.Some("2023-12-31")},
+			now:  time.Date(2024, 1, 1, 7, 0, 0, 0, time.FixedZone("UTC+9", 9*3600)),
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,3 +178,37 @@ func TestIsValidDueDate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "date", value: "2026-11-01", want: "2026-11-01"},
+		{name: "today", value: "today", want: "2026-10-19"},
+		{name: "tomorrow", value: "Tomorrow", want: "2026-10-20"},
+		{name: "weekday", value: "friday", want: "2026-10-23"},
+		{name: "same weekday is next week", value: "mon", want: "2026-10-26"},
+		{name: "days", value: "+3d", want: "2026-10-22"},
+		{name: "weeks", value: "+2w", want: "2026-11-02"},
+		{name: "invalid date", value: "2026-02-30", wantErr: true},
+		{name: "invalid offset", value: "+xd", wantErr: true},
+		{name: "unknown word", value: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tasks.ParseDate(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDate(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
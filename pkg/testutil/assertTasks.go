@@ -25,6 +25,9 @@ func AssertTaskEqual(t *testing.T, got, want models.Task) {
 		ValidateEqual("DoDate", got.DoDate, want.DoDate),
 		ValidateEqual("CreatedAt", got.CreatedAt, want.CreatedAt),
 		ValidateEqual("UpdatedAt", got.UpdatedAt, want.UpdatedAt),
+
+		// Unknown frontmatter fields
+		ValidateDeepEqual("Extra", got.Extra, want.Extra),
 	}
 
 	ReportResults(t, results)
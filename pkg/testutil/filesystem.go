@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/avivSarig/cerebgo/internal/models"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
 
@@ -26,6 +27,39 @@ func CreateTestDirectory(t *testing.T) string {
 	return dir
 }
 
+// CreateMemFs creates an in-memory filesystem holding the given files, keyed by path.
+// Parent directories are created as needed.
+func CreateMemFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fsys := afero.NewMemMapFs()
+	for path, content := range files {
+		if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := afero.WriteFile(fsys, path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	return fsys
+}
+
+// AssertFsFileContent checks that a file of a filesystem has the expected content.
+func AssertFsFileContent(t *testing.T, fsys afero.Fs, path string, expected string) {
+	t.Helper()
+
+	content, err := afero.ReadFile(fsys, path)
+	if err != nil {
+		t.Errorf("failed to read file at %s: %v", path, err)
+		return
+	}
+
+	if string(content) != expected {
+		t.Errorf("file content mismatch at %s\ngot: %s\nwant: %s",
+			path, content, expected)
+	}
+}
+
 // CreateTestFile creates a file with given content in the specified directory.
 func CreateTestFile(t *testing.T, dir string, filename string, content string) error {
 	t.Helper()
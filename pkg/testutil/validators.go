@@ -1,6 +1,10 @@
 package testutil
 
-import "github.com/avivSarig/cerebgo/pkg/ptr"
+import (
+	"reflect"
+
+	"github.com/avivSarig/cerebgo/pkg/ptr"
+)
 
 func ValidateOptional[T any](
 	field string,
@@ -37,3 +41,13 @@ func ValidateEqual[T comparable](
 	}
 	return CreateValidSuccess(field)
 }
+
+func ValidateDeepEqual(
+	field string,
+	got, want any,
+) ValidationResult {
+	if !reflect.DeepEqual(got, want) {
+		return CreateValidationError(field, got, want, "values not deeply equal")
+	}
+	return CreateValidSuccess(field)
+}
@@ -0,0 +1,321 @@
+// Package trash moves deleted vault files into a trash folder, from which they can be
+// listed, restored or purged once their retention has passed.
+//
+// Each deleted file is kept at "<trash>/<deletion time>/<original relative path>", so the
+// trash itself records where a file came from and when it was deleted.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/spf13/afero"
+)
+
+// DefaultDir is the trash folder used when none is configured.
+const DefaultDir = ".trash"
+
+// stampFormat is the layout of the deletion time folders.
+const stampFormat = "20060102T150405Z"
+
+// Trash describes where deleted files of a vault go.
+type Trash struct {
+	Fs        afero.Fs
+	Root      string // vault root; original paths are kept relative to it
+	Dir       string // trash folder
+	Permanent bool   // delete files instead of moving them to the trash
+}
+
+// Entry is a file in the trash.
+type Entry struct {
+	Name         string // "<deletion time>/<original relative path>", slash separated
+	Path         string // location of the file inside the trash
+	OriginalPath string // location the file was deleted from
+	DeletedAt    time.Time
+}
+
+// New returns the trash of a vault. A relative dir is resolved against the vault root.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - root: the vault root
+//   - dir: the trash folder, DefaultDir if empty
+//   - permanent: whether files are deleted permanently instead
+//
+// Returns:
+//   - Trash: the vault trash
+func New(fsys afero.Fs, root, dir string, permanent bool) Trash {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return Trash{Fs: fsys, Root: root, Dir: dir, Permanent: permanent}
+}
+
+// ForVault returns the trash of a configured vault, following its trash settings.
+//
+// Parameters:
+//   - fsys: filesystem holding the vault
+//   - cfg: the vault configuration
+//
+// Returns:
+//   - Trash: the vault trash
+func ForVault(fsys afero.Fs, cfg *config.Config) Trash {
+	return New(fsys, cfg.DataPath, cfg.Trash.Path, cfg.Trash.PermanentDelete)
+}
+
+// Delete removes a file, either permanently or by moving it to the trash.
+//
+// Parameters:
+//   - src: the file to delete
+//   - now: the deletion time
+//
+// Returns:
+//   - error: if the file does not exist or cannot be moved or removed
+func (t Trash) Delete(src files.FilePath, now time.Time) error {
+	if t.Permanent {
+		return files.DeleteFile(t.Fs, src)
+	}
+	_, err := t.Move(src, now)
+	return err
+}
+
+// Move moves a file into the trash, keeping its path relative to the vault root.
+// A file outside the vault root is kept under its base name.
+//
+// Parameters:
+//   - src: the file to move
+//   - now: the deletion time
+//
+// Returns:
+//   - Entry: the trashed file
+//   - error: if the file does not exist or cannot be moved
+func (t Trash) Move(src files.FilePath, now time.Time) (Entry, error) {
+	rel, err := filepath.Rel(t.Root, src.FullPath())
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = src.Name
+	}
+
+	stamp := now.UTC().Format(stampFormat)
+	dest := files.FilePath{
+		Dir:  filepath.Join(t.Dir, stamp, filepath.Dir(rel)),
+		Name: filepath.Base(rel),
+	}
+	if err := t.Fs.MkdirAll(dest.Dir, 0755); err != nil {
+		return Entry{}, fmt.Errorf("failed to create trash folder: %w", err)
+	}
+	dest, err = files.NextAvailableName(t.Fs, dest)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to name trashed file: %w", err)
+	}
+
+	if _, err := files.MoveFile(t.Fs, src, dest, files.MoveError); err != nil {
+		return Entry{}, fmt.Errorf("failed to move %s to the trash: %w", src.Name, err)
+	}
+	return t.entry(dest.FullPath())
+}
+
+// List returns the files in the trash, most recently deleted first.
+//
+// Returns:
+//   - []Entry: the trashed files
+//   - error: if the trash cannot be read
+func (t Trash) List() ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	err := afero.Walk(t.Fs, t.Dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if path == t.Dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		entry, err := t.entry(path)
+		if err != nil {
+			// Not a file put there by Move.
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Find returns the trashed file matching name. The name is either the entry name, the
+// original relative path or the file name, with or without its ".md" extension. When a
+// name matches several deletions of the same file, the most recent one is returned.
+//
+// Parameters:
+//   - name: the file to find
+//
+// Returns:
+//   - Entry: the matching file
+//   - error: if no file or files from several places match
+func (t Trash) Find(name string) (Entry, error) {
+	entries, err := t.List()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	matches := make([]Entry, 0)
+	for _, entry := range entries {
+		if entry.matches(t.Root, name) {
+			matches = append(matches, entry)
+		}
+	}
+
+	if len(matches) == 0 {
+		return Entry{}, fmt.Errorf("%q not found in the trash", name)
+	}
+	for _, match := range matches[1:] {
+		if match.OriginalPath != matches[0].OriginalPath {
+			names := make([]string, 0, len(matches))
+			for _, m := range matches {
+				names = append(names, m.Name)
+			}
+			return Entry{}, fmt.Errorf("%q matches several files: %s", name, strings.Join(names, ", "))
+		}
+	}
+	return matches[0], nil
+}
+
+// Restore moves a trashed file back to its original path.
+//
+// Parameters:
+//   - name: the file to restore, as accepted by Find
+//
+// Returns:
+//   - Entry: the restored file
+//   - error: if the file is not found or its original path is taken
+func (t Trash) Restore(name string) (Entry, error) {
+	entry, err := t.Find(name)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	dest := files.FilePath{Dir: filepath.Dir(entry.OriginalPath), Name: filepath.Base(entry.OriginalPath)}
+	if _, err := t.Fs.Stat(dest.FullPath()); err == nil {
+		return Entry{}, fmt.Errorf("cannot restore %s: %s already exists", entry.Name, dest.FullPath())
+	}
+	if err := t.Fs.MkdirAll(dest.Dir, 0755); err != nil {
+		return Entry{}, fmt.Errorf("failed to create %s: %w", dest.Dir, err)
+	}
+
+	src := files.FilePath{Dir: filepath.Dir(entry.Path), Name: filepath.Base(entry.Path)}
+	if _, err := files.MoveFile(t.Fs, src, dest, files.MoveError); err != nil {
+		return Entry{}, fmt.Errorf("failed to restore %s: %w", entry.Name, err)
+	}
+	t.removeEmptyDirs(src.Dir)
+	return entry, nil
+}
+
+// Purge permanently deletes the trashed files older than the retention.
+// A retention of zero or less keeps the files forever.
+//
+// Parameters:
+//   - now: the current time
+//   - retention: how long deleted files are kept
+//
+// Returns:
+//   - []Entry: the deleted files
+//   - error: if the trash cannot be read or a file cannot be removed
+func (t Trash) Purge(now time.Time, retention time.Duration) ([]Entry, error) {
+	if retention <= 0 {
+		return nil, nil
+	}
+
+	entries, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+
+	purged := make([]Entry, 0)
+	for _, entry := range entries {
+		if now.Sub(entry.DeletedAt) <= retention {
+			continue
+		}
+		if err := t.Fs.Remove(entry.Path); err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", entry.Name, err)
+		}
+		t.removeEmptyDirs(filepath.Dir(entry.Path))
+		purged = append(purged, entry)
+	}
+	return purged, nil
+}
+
+// entry describes the trashed file at path.
+func (t Trash) entry(path string) (Entry, error) {
+	rel, err := filepath.Rel(t.Dir, path)
+	if err != nil {
+		return Entry{}, err
+	}
+	stamp, original, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok {
+		return Entry{}, fmt.Errorf("%s is not in a deletion folder", rel)
+	}
+	deletedAt, err := time.Parse(stampFormat, stamp)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid deletion time %q: %w", stamp, err)
+	}
+
+	return Entry{
+		Name:         filepath.ToSlash(rel),
+		Path:         path,
+		OriginalPath: filepath.Join(t.Root, filepath.FromSlash(original)),
+		DeletedAt:    deletedAt,
+	}, nil
+}
+
+// matches reports whether name refers to the entry.
+func (e Entry) matches(root, name string) bool {
+	name = filepath.ToSlash(name)
+	rel, err := filepath.Rel(root, e.OriginalPath)
+	if err != nil {
+		rel = e.OriginalPath
+	}
+	rel = filepath.ToSlash(rel)
+	base := filepath.Base(e.OriginalPath)
+
+	switch name {
+	case e.Name, rel, strings.TrimSuffix(rel, ".md"), base, strings.TrimSuffix(base, ".md"):
+		return true
+	}
+	return false
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, up to the trash folder.
+func (t Trash) removeEmptyDirs(dir string) {
+	for dir != t.Dir && strings.HasPrefix(dir, t.Dir+string(filepath.Separator)) {
+		entries, err := afero.ReadDir(t.Fs, dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := t.Fs.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,233 @@
+package trash_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avivSarig/cerebgo/config"
+	"github.com/avivSarig/cerebgo/pkg/files"
+	"github.com/avivSarig/cerebgo/pkg/testutil"
+	"github.com/avivSarig/cerebgo/pkg/trash"
+	"github.com/spf13/afero"
+)
+
+var (
+	deletedAt = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	later     = deletedAt.Add(48 * time.Hour)
+)
+
+// TestDelete tests that deleted files are moved to the trash, or removed when
+// permanent deletion is set.
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		permanent bool
+		src       files.FilePath
+		wantPath  string
+	}{
+		{
+			name:     "file keeps its vault path",
+			src:      files.FilePath{Dir: "/vault/Tasks/Completed", Name: "Buy milk.md"},
+			wantPath: "/vault/.trash/20240301T093000Z/Tasks/Completed/Buy milk.md",
+		},
+		{
+			name:     "file outside the vault keeps its name",
+			src:      files.FilePath{Dir: "/elsewhere", Name: "Stray.md"},
+			wantPath: "/vault/.trash/20240301T093000Z/Stray.md",
+		},
+		{
+			name:      "permanent deletion",
+			permanent: true,
+			src:       files.FilePath{Dir: "/vault/Tasks/Completed", Name: "Buy milk.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Tasks/Completed/Buy milk.md": "milk",
+				"/elsewhere/Stray.md":                "stray",
+			})
+			bin := trash.New(fsys, "/vault", "", tt.permanent)
+
+			if err := bin.Delete(tt.src, deletedAt); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+
+			if exists, _ := afero.Exists(fsys, tt.src.FullPath()); exists {
+				t.Errorf("%s still exists", tt.src.FullPath())
+			}
+			entries, err := bin.List()
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if tt.permanent {
+				if len(entries) != 0 {
+					t.Errorf("List() = %+v, want an empty trash", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Path != tt.wantPath {
+				t.Fatalf("List() = %+v, want one entry at %s", entries, tt.wantPath)
+			}
+			if !entries[0].DeletedAt.Equal(deletedAt) {
+				t.Errorf("DeletedAt = %v, want %v", entries[0].DeletedAt, deletedAt)
+			}
+		})
+	}
+}
+
+// TestDelete_MissingFile tests that deleting a missing file fails.
+func TestDelete_MissingFile(t *testing.T) {
+	bin := trash.New(afero.NewMemMapFs(), "/vault", "", false)
+	if err := bin.Delete(files.FilePath{Dir: "/vault", Name: "Missing.md"}, deletedAt); err == nil {
+		t.Error("Delete() expected error for a missing file")
+	}
+}
+
+// TestForVault tests that the vault trash follows the trash settings.
+func TestForVault(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings config.Trash
+		want     trash.Trash
+	}{
+		{
+			name: "default folder",
+			want: trash.Trash{Root: "/vault", Dir: "/vault/" + trash.DefaultDir},
+		},
+		{
+			name:     "relative folder with permanent deletion",
+			settings: config.Trash{Path: "Bin", PermanentDelete: true},
+			want:     trash.Trash{Root: "/vault", Dir: "/vault/Bin", Permanent: true},
+		},
+		{
+			name:     "absolute folder",
+			settings: config.Trash{Path: "/elsewhere/bin"},
+			want:     trash.Trash{Root: "/vault", Dir: "/elsewhere/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trash.ForVault(nil, &config.Config{DataPath: "/vault", Trash: tt.settings})
+			if got != tt.want {
+				t.Errorf("ForVault() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRestore tests restoring trashed files by name.
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		restore  string
+		existing bool
+		wantPath string
+		wantErr  string
+	}{
+		{name: "by title", restore: "Buy milk", wantPath: "/vault/Tasks/Completed/Buy milk.md"},
+		{name: "by original path", restore: "Tasks/Completed/Buy milk.md", wantPath: "/vault/Tasks/Completed/Buy milk.md"},
+		{name: "by entry name", restore: "20240301T093000Z/Lists/Groceries.md", wantPath: "/vault/Lists/Groceries.md"},
+		{name: "ambiguous name", restore: "Notes", wantErr: "matches several files"},
+		{name: "unknown name", restore: "Nothing", wantErr: "not found"},
+		{name: "original path taken", restore: "Buy milk", existing: true, wantErr: "already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/Tasks/Completed/Buy milk.md": "milk",
+				"/vault/Lists/Groceries.md":          "eggs",
+				"/vault/Tasks/Notes.md":              "task notes",
+				"/vault/Lists/Notes.md":              "list notes",
+			})
+			bin := trash.New(fsys, "/vault", ".trash", false)
+			for _, path := range []string{"/vault/Tasks/Completed/Buy milk.md", "/vault/Lists/Groceries.md", "/vault/Tasks/Notes.md", "/vault/Lists/Notes.md"} {
+				dir, name := filepath.Split(path)
+				if _, err := bin.Move(files.FilePath{Dir: dir, Name: name}, deletedAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.existing {
+				if err := afero.WriteFile(fsys, "/vault/Tasks/Completed/Buy milk.md", []byte("new milk"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entry, err := bin.Restore(tt.restore)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Restore() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if entry.OriginalPath != tt.wantPath {
+				t.Errorf("OriginalPath = %s, want %s", entry.OriginalPath, tt.wantPath)
+			}
+			if exists, _ := afero.Exists(fsys, tt.wantPath); !exists {
+				t.Errorf("%s was not restored", tt.wantPath)
+			}
+			if exists, _ := afero.Exists(fsys, entry.Path); exists {
+				t.Errorf("%s is still in the trash", entry.Path)
+			}
+		})
+	}
+}
+
+// TestRestore_MostRecent tests that a file deleted twice is restored from its last deletion.
+func TestRestore_MostRecent(t *testing.T) {
+	fsys := testutil.CreateMemFs(t, map[string]string{
+		"/vault/.trash/20240301T093000Z/Tasks/Buy milk.md": "first",
+		"/vault/.trash/20240303T093000Z/Tasks/Buy milk.md": "second",
+	})
+
+	if _, err := trash.New(fsys, "/vault", "", false).Restore("Buy milk"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	testutil.AssertFsFileContent(t, fsys, "/vault/Tasks/Buy milk.md", "second")
+	if exists, _ := afero.DirExists(fsys, "/vault/.trash/20240303T093000Z"); exists {
+		t.Error("empty deletion folder was left behind")
+	}
+}
+
+// TestPurge tests that only files older than the retention are removed.
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		wantLeft  int
+	}{
+		{name: "expired files are purged", retention: 24 * time.Hour, wantLeft: 1},
+		{name: "files within retention are kept", retention: 72 * time.Hour, wantLeft: 2},
+		{name: "zero retention keeps everything", retention: 0, wantLeft: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testutil.CreateMemFs(t, map[string]string{
+				"/vault/.trash/20240301T093000Z/Tasks/Old.md": "old",
+				"/vault/.trash/20240303T080000Z/Tasks/New.md": "new",
+				"/vault/.trash/README.txt":                    "not a trashed file",
+			})
+			bin := trash.New(fsys, "/vault", "", false)
+
+			if _, err := bin.Purge(later, tt.retention); err != nil {
+				t.Fatalf("Purge() error = %v", err)
+			}
+			entries, err := bin.List()
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(entries) != tt.wantLeft {
+				t.Errorf("List() returned %d entries, want %d: %+v", len(entries), tt.wantLeft, entries)
+			}
+		})
+	}
+}